@@ -0,0 +1,36 @@
+package hl7
+
+// accountNumberType is the identifier type (HL7 table 0203) of the patient
+// account number in PID-18.
+const accountNumberType = "AN"
+
+// accountNumberPath is the patient account number field.
+var accountNumberPath = fieldPath{Segment: "PID", Field: 18}
+
+// parseAccountNumber parses the patient account number (PID-18, CX). The
+// identifier type defaults to AN. It returns nil for an empty field.
+func parseAccountNumber(field string) *PatientIdentifier {
+	ids := parsePatientIdentifiers(field)
+	if len(ids) == 0 {
+		return nil
+	}
+	account := ids[0]
+	if account.IdentifierType == "" {
+		account.IdentifierType = accountNumberType
+	}
+	return &account
+}
+
+// splitAccountNumber returns the first identifier of type AN, written to
+// PID-18, and the other identifiers, written to PID-3.
+func splitAccountNumber(identifiers []Identifier) (*Identifier, []Identifier) {
+	for i, id := range identifiers {
+		if id.Type == nil || len(id.Type.Coding) == 0 || id.Type.Coding[0].Code != accountNumberType {
+			continue
+		}
+		others := make([]Identifier, 0, len(identifiers)-1)
+		others = append(others, identifiers[:i]...)
+		return &id, append(others, identifiers[i+1:]...)
+	}
+	return nil, identifiers
+}
@@ -0,0 +1,61 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_AccountNumber(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5\r" +
+		"PID|1||123^^^HOSP^MR||Smith^John||19800101|M||||||||||ACC-987^^^BILLING"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+	is.Equal(patient.ID, "123") // the account number is not the MRN
+	is.Equal(len(patient.Identifier), 2)
+	account := patient.Identifier[1]
+	is.Equal(account.Value, "ACC-987")
+	is.Equal(account.System, "BILLING")
+	is.Equal(account.Type.Coding, []Coding{{System: identifierTypeSystem, Code: accountNumberType}})
+
+	// and back to PID-18, leaving PID-3 to the MRN
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[3], "123^^^HOSP^MR")
+	is.Equal(pidFields[18], "ACC-987^^^BILLING^AN")
+	is.NoErr(p.verifyHL7(hl7Message, patient))
+}
+
+func TestSplitAccountNumber(t *testing.T) {
+	is := is.New(t)
+
+	mrn := Identifier{Value: "123", Type: &CodeableConcept{Coding: []Coding{{System: identifierTypeSystem, Code: "MR"}}}}
+	account := Identifier{Value: "ACC-987", Type: &CodeableConcept{Coding: []Coding{{System: identifierTypeSystem, Code: "AN"}}}}
+
+	got, others := splitAccountNumber([]Identifier{account, mrn})
+	is.Equal(*got, account)
+	is.Equal(others, []Identifier{mrn})
+
+	got, others = splitAccountNumber([]Identifier{mrn})
+	is.Equal(got, nil)
+	is.Equal(others, []Identifier{mrn})
+}
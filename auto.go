@@ -0,0 +1,109 @@
+package hl7
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"unicode"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// conversionAuto is the inputType and outputType value detecting the type
+// of records without type metadata.
+const conversionAuto = "auto"
+
+// Metadata keys naming the input and output type of a record. They take
+// precedence over the configured types.
+const (
+	metadataInputType  = "hl7.inputType"
+	metadataOutputType = "hl7.outputType"
+)
+
+// validConversions lists the output types each input type converts to. The
+// first one is the output type of records without hl7.outputType metadata.
+var validConversions = map[string][]string{
+	"fhir":  {"hl7", "hl7v3", "fhir"},
+	"hl7":   {"fhir"},
+	"hl7v3": {"fhir"},
+}
+
+// validateConversion checks that inputType converts to outputType.
+func validateConversion(inputType, outputType string) error {
+	if slices.Contains(validConversions[inputType], outputType) {
+		return nil
+	}
+	return fmt.Errorf("invalid conversion from %s to %s", inputType, outputType)
+}
+
+// recordProcessor returns the processor converting record: p itself, or a
+// copy with the types named by the hl7.inputType and hl7.outputType metadata
+// of the record. Types configured as auto and not named in the metadata are
+// resolved too: the input type is detected from the payload and the output
+// type is the first valid one of the input type.
+func (p *Processor) recordProcessor(record opencdc.Record) (*Processor, error) {
+	inputType := cmp.Or(record.Metadata[metadataInputType], p.config.InputType)
+	outputType := cmp.Or(record.Metadata[metadataOutputType], p.config.OutputType)
+	if inputType == conversionAuto {
+		var err error
+		inputType, err = detectInputType(record.Payload.After.Bytes())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if outputType == conversionAuto && len(validConversions[inputType]) > 0 {
+		outputType = validConversions[inputType][0]
+	}
+	if inputType == p.config.InputType && outputType == p.config.OutputType {
+		return p, nil
+	}
+	if err := validateConversion(inputType, outputType); err != nil {
+		return nil, err
+	}
+
+	rp := *p
+	rp.config.InputType, rp.config.OutputType = inputType, outputType
+	return &rp, nil
+}
+
+// detectInputType detects the input type of a payload: a message starting
+// with MSH, optionally in an MLLP frame, is HL7 v2, XML is HL7 v3 and JSON is
+// FHIR, unless it wraps an HL7 v2 message in an "hl7" field. JSON holding
+// both a resourceType and an "hl7" field is ambiguous.
+func detectInputType(payload []byte) (string, error) {
+	trimmed := bytes.TrimSpace([]byte(unwrapMLLP(string(bytes.TrimSpace(payload)))))
+	switch {
+	case isMSHSegment(trimmed):
+		return "hl7", nil
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return "hl7v3", nil
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var fields struct {
+			ResourceType *string `json:"resourceType"`
+			HL7          *string `json:"hl7"`
+		}
+		// invalid JSON is reported by the FHIR parser
+		_ = json.Unmarshal(trimmed, &fields)
+		switch {
+		case fields.HL7 != nil && fields.ResourceType != nil:
+			return "", errors.New("ambiguous input: JSON holds both a FHIR resourceType and an hl7 message")
+		case fields.HL7 != nil:
+			return "hl7", nil
+		}
+		return "fhir", nil
+	}
+	return "", errors.New("unrecognized input: expected an HL7 v2 message starting with MSH, HL7 v3 XML or FHIR JSON")
+}
+
+// isMSHSegment reports whether payload starts with an MSH segment, i.e. MSH
+// followed by the field separator.
+func isMSHSegment(payload []byte) bool {
+	if len(payload) < 4 || !bytes.HasPrefix(payload, []byte("MSH")) {
+		return false
+	}
+	sep := rune(payload[3])
+	return !unicode.IsLetter(sep) && !unicode.IsDigit(sep) && !unicode.IsSpace(sep)
+}
@@ -0,0 +1,187 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_AutoConversion(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	p := NewProcessor()
+	err := p.Configure(ctx, map[string]string{
+		"inputType":  "auto",
+		"outputType": "auto",
+	})
+	is.NoErr(err)
+
+	hl7Input := "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5\rPID|1||123^^^^MR||Smith^John||19800101|M"
+	result := p.Process(ctx, []opencdc.Record{
+		{
+			Metadata: opencdc.Metadata{metadataInputType: "fhir", metadataOutputType: "hl7"},
+			Payload:  opencdc.Change{After: opencdc.RawData(`{"resourceType":"Patient","id":"456"}`)},
+		},
+		{
+			Metadata: opencdc.Metadata{metadataInputType: "hl7", metadataOutputType: "fhir"},
+			Payload:  opencdc.Change{After: opencdc.RawData(hl7Input)},
+		},
+		// no metadata, the input type is detected
+		{Payload: opencdc.Change{After: opencdc.RawData(`{"resourceType":"Patient","id":"789"}`)}},
+		{
+			Metadata: opencdc.Metadata{metadataInputType: "hl7", metadataOutputType: "hl7v3"},
+			Payload:  opencdc.Change{After: opencdc.RawData(hl7Input)},
+		},
+	})
+	is.Equal(len(result), 4)
+
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	hl7Message, ok := rec.Payload.After.(opencdc.StructuredData)["hl7"].(string)
+	is.True(ok)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[3], "456")
+
+	rec, ok = result[1].(sdk.SingleRecord)
+	is.True(ok)
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+	is.Equal(patient.ID, "123")
+
+	rec, ok = result[2].(sdk.SingleRecord)
+	is.True(ok)
+	_, ok = rec.Payload.After.(opencdc.StructuredData)
+	is.True(ok) // FHIR converted to HL7 v2
+
+	_, ok = result[3].(sdk.ErrorRecord)
+	is.True(ok) // HL7 v2 does not convert to HL7 v3
+}
+
+func TestValidateConversion(t *testing.T) {
+	is := is.New(t)
+
+	is.NoErr(validateConversion("fhir", "hl7v3"))
+	is.NoErr(validateConversion("hl7v3", "fhir"))
+	is.True(validateConversion("hl7", "hl7v3") != nil)
+	is.True(validateConversion("auto", "fhir") != nil)
+}
+
+func TestDetectInputType(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{"MSH|^~\\&|APP", "hl7"},
+		{"MSH#^~\\&#APP", "hl7"}, // custom field separator
+		{mllpStartBlock + "MSH|^~\\&|APP" + mllpEndBlock, "hl7"},
+		{" <Patient xmlns=\"urn:hl7-org:v3\"/>", "hl7v3"},
+		{`<?xml version="1.0"?><ClinicalDocument/>`, "hl7v3"},
+		{`{"resourceType":"Patient"}`, "fhir"},
+		{`{"id":"123"}`, "fhir"},
+		{`{"hl7":"MSH|^~\\&"}`, "hl7"},
+	}
+	for _, tt := range tests {
+		got, err := detectInputType([]byte(tt.payload))
+		is.NoErr(err)
+		is.Equal(got, tt.want) // input type of tt.payload
+	}
+
+	for _, payload := range []string{
+		`{"resourceType":"Patient","hl7":"MSH|^~\\&"}`, // ambiguous
+		"PID|1||123",
+		"MSHX",
+		"hello",
+	} {
+		_, err := detectInputType([]byte(payload))
+		is.True(err != nil) // payload is not detected
+	}
+}
+
+func TestProcess_MetadataOverridesConfig(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	p := NewProcessor()
+	err := p.Configure(ctx, map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	})
+	is.NoErr(err)
+
+	fhirInput := `{"resourceType":"Patient","id":"456","name":[{"family":["Doe"],"given":["Jane"]}]}`
+	result := p.Process(ctx, []opencdc.Record{
+		// configured conversion
+		{Payload: opencdc.Change{After: opencdc.RawData("MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5\rPID|1||123^^^^MR||Smith^John||19800101|M")}},
+		// both types overridden
+		{
+			Metadata: opencdc.Metadata{metadataInputType: "fhir", metadataOutputType: "hl7v3"},
+			Payload:  opencdc.Change{After: opencdc.RawData(fhirInput)},
+		},
+		// the input type is still hl7, which does not convert to hl7v3
+		{
+			Metadata: opencdc.Metadata{metadataOutputType: "hl7v3"},
+			Payload:  opencdc.Change{After: opencdc.RawData("MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5\rPID|1||123^^^^MR||Smith^John||19800101|M")},
+		},
+	})
+
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+	is.Equal(patient.ID, "123")
+
+	rec, ok = result[1].(sdk.SingleRecord)
+	is.True(ok)
+	is.True(strings.HasPrefix(string(rec.Payload.After.Bytes()), "<"))
+	is.True(strings.Contains(string(rec.Payload.After.Bytes()), "Doe"))
+
+	errRec, ok := result[2].(sdk.ErrorRecord)
+	is.True(ok)
+	is.True(strings.Contains(errRec.Error.Error(), "invalid conversion from hl7 to hl7v3"))
+}
+
+func TestProcess_DetectInputType(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	p := NewProcessor()
+	err := p.Configure(ctx, map[string]string{
+		"inputType":  "auto",
+		"outputType": "auto",
+	})
+	is.NoErr(err)
+
+	result := p.Process(ctx, []opencdc.Record{
+		{Payload: opencdc.Change{After: opencdc.RawData("MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5\rPID|1||123^^^^MR||Smith^John||19800101|M")}},
+		{Payload: opencdc.Change{After: opencdc.RawData(`<Patient xmlns="urn:hl7-org:v3"><id>pat-1</id><name><given>Alex</given><family>Doe</family></name></Patient>`)}},
+		{Payload: opencdc.Change{After: opencdc.RawData(`{"resourceType":"Patient","id":"456"}`)}},
+		{Payload: opencdc.Change{After: opencdc.RawData("not a patient")}},
+	})
+
+	// HL7 v2 to FHIR
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+	is.Equal(patient.ID, "123")
+
+	// HL7 v3 to FHIR
+	rec, ok = result[1].(sdk.SingleRecord)
+	is.True(ok)
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+	is.Equal(patient.ID, "pat-1")
+
+	// FHIR to HL7 v2
+	rec, ok = result[2].(sdk.SingleRecord)
+	is.True(ok)
+	_, ok = rec.Payload.After.(opencdc.StructuredData)["hl7"].(string)
+	is.True(ok)
+
+	errRec, ok := result[3].(sdk.ErrorRecord)
+	is.True(ok)
+	is.True(strings.Contains(errRec.Error.Error(), "unrecognized input"))
+}
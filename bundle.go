@@ -0,0 +1,202 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FHIRBundle represents a FHIR Bundle resource.
+type FHIRBundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+// BundleEntry is a single entry of a FHIR Bundle.
+type BundleEntry struct {
+	FullURL  string      `json:"fullUrl,omitempty"`
+	Resource interface{} `json:"resource"`
+}
+
+// FHIRBinary represents a FHIR Binary resource. Data is base64 encoded when
+// marshaled to JSON.
+type FHIRBinary struct {
+	ResourceType string `json:"resourceType"`
+	ContentType  string `json:"contentType"`
+	Data         []byte `json:"data"`
+}
+
+// sourceContentTypes maps input types to the MIME type of the source message
+// stored in a Binary resource.
+var sourceContentTypes = map[string]string{
+	"hl7":   "x-application/hl7-v2+er7",
+	"hl7v3": "application/hl7-v3+xml",
+}
+
+// Reference represents a FHIR Reference.
+type Reference struct {
+	Reference  string      `json:"reference,omitempty"`
+	Identifier *Identifier `json:"identifier,omitempty"`
+	Display    string      `json:"display,omitempty"`
+}
+
+// fhirOutput returns the patient, or a collection Bundle holding the patient
+// and the resources configured to accompany it. resources are converted from
+// other segments of the message, e.g. Coverage resources, and source is the
+// HL7 message the patient was converted from.
+func (p *Processor) fhirOutput(patient FHIRPatient, resources []interface{}, source string) interface{} {
+	var entries []BundleEntry
+	if p.config.NK1AsRelatedPerson {
+		for _, contact := range patient.Contact {
+			entries = append(entries, BundleEntry{Resource: newRelatedPerson(patient.ID, contact)})
+		}
+	}
+	for _, resource := range resources {
+		entries = append(entries, BundleEntry{Resource: resource})
+	}
+	if p.config.IncludeSourceBinary && source != "" {
+		entries = append(entries, BundleEntry{Resource: FHIRBinary{
+			ResourceType: "Binary",
+			ContentType:  sourceContentTypes[p.config.InputType],
+			Data:         []byte(source),
+		}})
+	}
+	if len(entries) == 0 {
+		return patient
+	}
+
+	// resources in a bundle always carry their type
+	patient.ResourceType = "Patient"
+	return FHIRBundle{
+		ResourceType: "Bundle",
+		Type:         "collection",
+		Entry:        append([]BundleEntry{{Resource: patient}}, entries...),
+	}
+}
+
+// convertResources converts the segments of msg that become resources of
+// their own, as configured, to accompany the patient in a Bundle.
+func (p *Processor) convertResources(msg HL7Message) ([]interface{}, error) {
+	var resources []interface{}
+	if isLabResult(msg) {
+		for _, obr := range msg.OBR {
+			report, err := convertObservationRequest(msg.PID.ID, obr, p.timeLocation())
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, report)
+		}
+	}
+	if p.config.TXAAsComposition && msg.TXA != nil {
+		composition, err := convertToComposition(msg, p.timeLocation())
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, composition)
+	}
+	if p.config.DG1AsCondition {
+		for _, dg1 := range msg.DG1 {
+			condition, err := convertDiagnosis(msg.PID.ID, dg1, p.timeLocation())
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, condition)
+		}
+	}
+	if p.config.IN1AsCoverage {
+		for _, in1 := range msg.IN1 {
+			coverage, err := convertInsurance(msg.PID.ID, in1, p.timeLocation(), p.now())
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, coverage)
+		}
+	}
+	return resources, nil
+}
+
+// convertBundleToHL7 converts the Patient entries of a FHIR Bundle to an HL7
+// v2 batch (BHS, the messages in entry order, BTS). The Observations of a
+// patient, i.e. those with a subject referencing Patient/<id>, follow its PID
+// as OBX segments. Organization entries resolve the managingOrganization
+// references of the patients. Other resources are skipped.
+func (p *Processor) convertBundleToHL7(ctx context.Context, raw []byte) (string, error) {
+	var bundle struct {
+		Entry []struct {
+			FullURL  string          `json:"fullUrl"`
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(raw, &bundle); err != nil {
+		return "", fmt.Errorf("failed to parse FHIR bundle: %w", err)
+	}
+
+	// patients are the Patient entries by entry index
+	var patients []FHIRPatient
+	var patientEntries []int
+	observations := make(map[string][]FHIRObservation)
+	// organizations are the Organization entries by reference and full URL
+	organizations := make(map[string]FHIROrganization)
+	for i, entry := range bundle.Entry {
+		var resource struct {
+			ResourceType string `json:"resourceType"`
+		}
+		if err := json.Unmarshal(entry.Resource, &resource); err != nil {
+			return "", fmt.Errorf("bundle entry %d: failed to parse resource: %w", i, err)
+		}
+		switch resource.ResourceType {
+		case "Patient":
+			var patient FHIRPatient
+			if err := p.unmarshalFHIR(ctx, entry.Resource, &patient); err != nil {
+				return "", fmt.Errorf("bundle entry %d: failed to parse resource: %w", i, err)
+			}
+			patients = append(patients, patient)
+			patientEntries = append(patientEntries, i)
+		case "Observation":
+			var observation FHIRObservation
+			if err := json.Unmarshal(entry.Resource, &observation); err != nil {
+				return "", fmt.Errorf("bundle entry %d: failed to parse resource: %w", i, err)
+			}
+			subject := observation.Subject.Reference
+			observations[subject] = append(observations[subject], observation)
+		case "Organization":
+			var org FHIROrganization
+			if err := json.Unmarshal(entry.Resource, &org); err != nil {
+				return "", fmt.Errorf("bundle entry %d: failed to parse resource: %w", i, err)
+			}
+			organizations["Organization/"+org.ID] = org
+			if entry.FullURL != "" {
+				organizations[entry.FullURL] = org
+			}
+		}
+	}
+
+	bhs := []string{"BHS", "^~\\&", "FHIR_CONVERTER", "FACILITY", "HL7_PARSER", "FACILITY", p.messageTimestamp()}
+	batch := []string{strings.Join(bhs, "|")}
+	for j, patient := range patients {
+		if ref := patient.ManagingOrganization; ref != nil && ref.Display == "" {
+			if org, ok := organizations[ref.Reference]; ok {
+				resolved := *ref
+				resolved.Display = org.Name
+				patient.ManagingOrganization = &resolved
+			}
+		}
+		msg, err := p.convertFHIRToHL7(patient)
+		if err != nil {
+			return "", fmt.Errorf("bundle entry %d: %w", patientEntries[j], err)
+		}
+		obx, err := p.formatObservations(observations["Patient/"+patient.ID])
+		if err != nil {
+			return "", fmt.Errorf("bundle entry %d: %w", patientEntries[j], err)
+		}
+		batch = append(batch, strings.Join(append([]string{msg}, obx...), p.segmentTerminator()))
+	}
+	if len(batch) == 1 {
+		return "", fmt.Errorf("bundle holds no Patient resource")
+	}
+	batch = append(batch, "BTS|"+strconv.Itoa(len(batch)-1))
+	return strings.Join(batch, p.segmentTerminator()), nil
+}
@@ -0,0 +1,168 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_IncludeSourceBinary(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":           "hl7",
+		"outputType":          "fhir",
+		"includeSourceBinary": "true",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var bundle struct {
+		ResourceType string `json:"resourceType"`
+		Entry        []struct {
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &bundle)
+	is.NoErr(err)
+	is.Equal(bundle.ResourceType, "Bundle")
+	is.Equal(len(bundle.Entry), 2)
+
+	var patient FHIRPatient
+	err = json.Unmarshal(bundle.Entry[0].Resource, &patient)
+	is.NoErr(err)
+	is.Equal(patient.ResourceType, "Patient")
+	is.Equal(patient.ID, "123")
+
+	var binary FHIRBinary
+	err = json.Unmarshal(bundle.Entry[1].Resource, &binary)
+	is.NoErr(err)
+	is.Equal(binary.ResourceType, "Binary")
+	is.Equal(binary.ContentType, "x-application/hl7-v2+er7")
+	is.Equal(string(binary.Data), input) // the original message, byte for byte
+}
+
+func TestProcessor_Process_NK1AsRelatedPerson(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":          "hl7",
+		"outputType":         "fhir",
+		"nk1AsRelatedPerson": "true",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\rPID|1||123||Smith^John||1990-01-01|male\rNK1|1|Smith^Jane|SPO^Spouse||||||||||||F\rNK1|2|Smith^Tom|FTH^Father||||||||||||M"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var bundle struct {
+		Entry []struct {
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &bundle)
+	is.NoErr(err)
+	is.Equal(len(bundle.Entry), 3)
+
+	var patient FHIRPatient
+	err = json.Unmarshal(bundle.Entry[0].Resource, &patient)
+	is.NoErr(err)
+	is.Equal(len(patient.Contact), 2) // contacts are kept on the patient
+
+	var person FHIRRelatedPerson
+	err = json.Unmarshal(bundle.Entry[2].Resource, &person)
+	is.NoErr(err)
+	is.Equal(person.ResourceType, "RelatedPerson")
+	is.Equal(person.Patient.Reference, "Patient/123")
+	is.Equal(person.Name[0].Given[0], "Tom")
+	is.Equal(person.Relationship[0].Coding[0].Code, "FTH")
+	is.Equal(person.Gender, "male")
+}
+
+func TestProcessor_Process_BundleToHL7(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+	})
+	is.NoErr(err)
+
+	input := `{"resourceType":"Bundle","type":"collection","entry":[
+		{"resource":{"resourceType":"Patient","id":"p3","name":[{"family":["Carter"],"given":["Cy"]}]}},
+		{"resource":{"resourceType":"Coverage","status":"active"}},
+		{"resource":{"resourceType":"Patient","id":"p1","name":[{"family":["Adams"],"given":["Al"]}]}},
+		{"resource":{"resourceType":"Patient","id":"p2","name":[{"family":["Baker"],"given":["Bo"]}]}}
+	]}`
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	segments := splitHL7Message(rec.Payload.After.(opencdc.StructuredData)["hl7"].(string))
+	is.Equal(len(segments), 8)
+	is.Equal(splitHL7Field(segments[0])[0], "BHS")
+	var ids []string
+	for _, segment := range segments {
+		if fields := splitHL7Field(segment); fields[0] == "PID" {
+			ids = append(ids, fields[3])
+		}
+	}
+	is.Equal(ids, []string{"p3", "p1", "p2"}) // entry order is preserved
+	is.Equal(segments[7], "BTS|3")
+}
+
+func TestProcessor_Process_BundleObservationsToHL7(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+	})
+	is.NoErr(err)
+
+	input := `{"resourceType":"Bundle","type":"collection","entry":[
+		{"resource":{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/p1"},
+			"code":{"coding":[{"system":"http://loinc.org","code":"85354-9","display":"Blood pressure panel"}]},
+			"effectiveDateTime":"2023-08-15T09:00:00",
+			"component":[
+				{"code":{"coding":[{"system":"http://loinc.org","code":"8480-6","display":"Systolic blood pressure"}]},
+					"valueQuantity":{"value":142,"unit":"mm[Hg]","system":"http://unitsofmeasure.org","code":"mm[Hg]"},
+					"interpretation":[{"coding":[{"code":"H"}]}]},
+				{"code":{"coding":[{"system":"http://loinc.org","code":"8462-4","display":"Diastolic blood pressure"}]},
+					"valueQuantity":{"value":88.5,"unit":"mm[Hg]","system":"http://unitsofmeasure.org","code":"mm[Hg]"}}
+			]}},
+		{"resource":{"resourceType":"Patient","id":"p1","name":[{"family":["Adams"],"given":["Al"]}]}},
+		{"resource":{"resourceType":"Observation","status":"preliminary","subject":{"reference":"Patient/p1"},
+			"code":{"text":"Comment"},"valueString":"Taken after exercise"}},
+		{"resource":{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/other"},
+			"code":{"text":"Unrelated"},"valueString":"x"}}
+	]}`
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	segments := splitHL7Message(rec.Payload.After.(opencdc.StructuredData)["hl7"].(string))
+	is.Equal(len(segments), 7) // BHS, MSH, PID, 3 OBX, BTS
+	is.Equal(segments[3], "OBX|1|NM|8480-6^Systolic blood pressure^LN|1|142|mm[Hg]^mm[Hg]^UCUM||H|||F|||20230815090000")
+	is.Equal(segments[4], "OBX|2|NM|8462-4^Diastolic blood pressure^LN|1|88.5|mm[Hg]^mm[Hg]^UCUM|||||F|||20230815090000")
+	is.Equal(segments[5], "OBX|3|ST|^Comment||Taken after exercise||||||P|||")
+	is.Equal(segments[6], "BTS|1")
+}
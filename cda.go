@@ -0,0 +1,80 @@
+package hl7
+
+import "encoding/xml"
+
+// HL7v3 formats of the HL7V3Format option.
+const (
+	hl7V3FormatBare = "bare"
+	hl7V3FormatCDA  = "cda"
+)
+
+// CDADocument is the part of an HL7 CDA ClinicalDocument holding the patient
+// (ClinicalDocument/recordTarget/patientRole).
+type CDADocument struct {
+	XMLName      xml.Name `xml:"ClinicalDocument"`
+	RecordTarget struct {
+		PatientRole CDAPatientRole `xml:"patientRole"`
+	} `xml:"recordTarget"`
+}
+
+// CDAPatientRole is a CDA patientRole. Unlike in the bare Patient format, the
+// identifiers, addresses and telecoms belong to the role and codes are
+// attributes.
+type CDAPatientRole struct {
+	ID      []CDAInstanceIdentifier `xml:"id"`
+	Address []HL7V3Address          `xml:"addr"`
+	Telecom []HL7V3Telecom          `xml:"telecom"`
+	Patient struct {
+		Name   []HL7V3Name `xml:"name"`
+		Gender struct {
+			Code       string `xml:"code,attr"`
+			CodeSystem string `xml:"codeSystem,attr"`
+			NullFlavor string `xml:"nullFlavor,attr"`
+		} `xml:"administrativeGenderCode"`
+		BirthTime struct {
+			Value string `xml:"value,attr"`
+		} `xml:"birthTime"`
+	} `xml:"patient"`
+}
+
+// CDAInstanceIdentifier is an HL7v3 instance identifier (II): the OID of the
+// assigning authority (root) and the identifier within it (extension).
+type CDAInstanceIdentifier struct {
+	Root      string `xml:"root,attr"`
+	Extension string `xml:"extension,attr"`
+}
+
+// unmarshalHL7V3 parses an HL7v3 patient in the given format. A CDA
+// document is mapped onto the bare Patient structure, the first patientRole
+// id with an extension being the patient ID (the MRN).
+func unmarshalHL7V3(data []byte, format string) (HL7V3Patient, error) {
+	if format != hl7V3FormatCDA {
+		var v3Patient HL7V3Patient
+		err := xml.Unmarshal(data, &v3Patient)
+		return v3Patient, err
+	}
+
+	var doc CDADocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return HL7V3Patient{}, err
+	}
+	role := doc.RecordTarget.PatientRole
+	v3Patient := HL7V3Patient{
+		Name: role.Patient.Name,
+		Gender: HL7V3Gender{
+			Code:       role.Patient.Gender.Code,
+			CodeSystem: role.Patient.Gender.CodeSystem,
+			NullFlavor: role.Patient.Gender.NullFlavor,
+		},
+		Address: role.Address,
+		Telecom: role.Telecom,
+	}
+	v3Patient.BirthTime.Value = role.Patient.BirthTime.Value
+	for _, id := range role.ID {
+		if id.Extension != "" {
+			v3Patient.ID = id.Extension
+			break
+		}
+	}
+	return v3Patient, nil
+}
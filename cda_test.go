@@ -0,0 +1,81 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_CDA(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	p := NewProcessor()
+	err := p.Configure(ctx, map[string]string{
+		"inputType":   "hl7v3",
+		"outputType":  "fhir",
+		"hl7v3Format": "cda",
+	})
+	is.NoErr(err)
+
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<ClinicalDocument xmlns="urn:hl7-org:v3">
+  <typeId root="2.16.840.1.113883.1.3" extension="POCD_HD000040"/>
+  <id root="2.16.840.1.113883.19.5" extension="doc-1"/>
+  <code code="34133-9" codeSystem="2.16.840.1.113883.6.1" displayName="Summary of episode note"/>
+  <effectiveTime value="20230815120000"/>
+  <recordTarget>
+    <patientRole>
+      <id root="2.16.840.1.113883.19.5.99999.2" extension="MRN-998991"/>
+      <addr use="HP">
+        <streetAddressLine>1357 Amber Drive</streetAddressLine>
+        <city>Beaverton</city>
+        <state>OR</state>
+        <postalCode>97867</postalCode>
+      </addr>
+      <telecom value="tel:+1-555-555-2003" use="HP"/>
+      <telecom value="mailto:isabella@example.com"/>
+      <patient>
+        <name>
+          <given>Isabella</given>
+          <family>Jones</family>
+        </name>
+        <administrativeGenderCode code="F" codeSystem="2.16.840.1.113883.5.1"/>
+        <birthTime value="19750501"/>
+      </patient>
+    </patientRole>
+  </recordTarget>
+</ClinicalDocument>`
+	result := p.Process(ctx, []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok) // should be a single record
+
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+	is.Equal(patient.ID, "MRN-998991")
+	is.Equal(patient.Name, []HumanName{{Family: []string{"Jones"}, Given: []string{"Isabella"}}})
+	is.Equal(patient.Gender, "female")
+	is.Equal(patient.BirthDate, "1975-05-01")
+	is.Equal(patient.Address, []Address{{Use: "home", Line: []string{"1357 Amber Drive"}, City: "Beaverton", State: "OR", PostalCode: "97867"}})
+	is.Equal(patient.Telecom, []ContactPoint{
+		{System: "phone", Value: "+1-555-555-2003", Use: "home"},
+		{System: "email", Value: "isabella@example.com"},
+	})
+
+	// the bare format does not accept CDA documents
+	err = p.Configure(ctx, map[string]string{
+		"inputType":  "hl7v3",
+		"outputType": "fhir",
+	})
+	is.NoErr(err)
+	result = p.Process(ctx, []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	_, ok = result[0].(sdk.ErrorRecord)
+	is.True(ok)
+}
@@ -0,0 +1,100 @@
+package hl7
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Character sets of the Charset option.
+const (
+	charsetUTF8        = "utf-8"
+	charsetLatin1      = "iso-8859-1"
+	charsetWindows1252 = "windows-1252"
+)
+
+// hl7Charsets maps the character sets declared in MSH-18 (HL7 table 0211 and
+// common non-standard names) to the Charset option values. Names are
+// matched case-insensitively.
+var hl7Charsets = map[string]string{
+	"ASCII":         charsetUTF8, // a subset of UTF-8
+	"UNICODE":       charsetUTF8,
+	"UNICODE UTF-8": charsetUTF8,
+	"UTF-8":         charsetUTF8,
+	"8859/1":        charsetLatin1,
+	"ISO-8859-1":    charsetLatin1,
+	"WINDOWS-1252":  charsetWindows1252,
+	"CP1252":        charsetWindows1252,
+}
+
+// validateOutputCharset checks that the OutputCharset option, when set, is
+// one of the character set names of hl7Charsets.
+func validateOutputCharset(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := hl7Charsets[strings.ToUpper(name)]; !ok {
+		return fmt.Errorf("invalid output charset %q: not a supported HL7 table 0211 character set", name)
+	}
+	return nil
+}
+
+// windows1252 holds the characters of the Windows-1252 bytes 0x80 to 0x9F,
+// which are C1 control characters in Latin-1. Unassigned bytes keep their
+// Latin-1 meaning.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// characterSetPath is the character set field of the message header.
+var characterSetPath = fieldPath{Segment: "MSH", Field: 18}
+
+// declaredCharset returns the Charset option value of the character set
+// declared in MSH-18 of a raw HL7 v2 message. The header is read before the
+// message is transcoded and parsed. It returns false when MSH-18 is empty or names an unsupported
+// character set.
+func declaredCharset(raw []byte) (string, bool) {
+	message := normalizeDelimiters(string(raw))
+	if !strings.HasPrefix(message, "MSH|") {
+		return "", false
+	}
+	header, _ := nextSegment(message)
+	declared := characterSetPath.value(splitFields(header, nil))
+	charset, ok := hl7Charsets[strings.ToUpper(strings.TrimSpace(declared))]
+	return charset, ok
+}
+
+// decodeCharset transcodes raw text in the given character set to UTF-8.
+func decodeCharset(raw []byte, charset string) string {
+	switch charset {
+	case charsetLatin1, charsetWindows1252:
+	default:
+		return string(raw)
+	}
+
+	var b strings.Builder
+	b.Grow(len(raw))
+	for _, c := range raw {
+		switch {
+		case c < utf8.RuneSelf:
+			b.WriteByte(c)
+		case charset == charsetWindows1252 && c < 0xA0:
+			b.WriteRune(windows1252[c-0x80])
+		default:
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// hl7Source returns a raw HL7 v2 message as UTF-8 text, transcoding it from
+// the character set declared in MSH-18 or, when not declared, the configured
+// Charset.
+func (p *Processor) hl7Source(raw []byte) string {
+	charset, ok := declaredCharset(raw)
+	if !ok {
+		charset = p.config.Charset
+	}
+	return decodeCharset(raw, charset)
+}
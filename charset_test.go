@@ -0,0 +1,88 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_Charset(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	// Müller^José in Latin-1, ü is 0xFC and é 0xE9
+	latin1 := func(msh18 string) []byte {
+		return []byte("MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5||||||" + msh18 + "\r" +
+			"PID|1||123^^^^MR||M\xfcller^Jos\xe9||19800101|M")
+	}
+	convert := func(cfg map[string]string, input []byte) FHIRPatient {
+		p := NewProcessor()
+		is.NoErr(p.Configure(ctx, cfg))
+		result := p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+		rec, ok := result[0].(sdk.SingleRecord)
+		is.True(ok)
+		var patient FHIRPatient
+		is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+		return patient
+	}
+
+	// configured charset
+	patient := convert(map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+		"charset":    "iso-8859-1",
+	}, latin1(""))
+	is.Equal(patient.Name[0].Family, []string{"Müller"})
+	is.Equal(patient.Name[0].Given, []string{"José"})
+
+	// MSH-18 takes precedence over the default utf-8
+	patient = convert(map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	}, latin1("8859/1"))
+	is.Equal(patient.Name[0].Family, []string{"Müller"})
+
+	// MSH-18 takes precedence over the configured charset
+	utf8Input := []byte("MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5||||||UNICODE UTF-8\r" +
+		"PID|1||123^^^^MR||Müller^José||19800101|M")
+	patient = convert(map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+		"charset":    "iso-8859-1",
+	}, utf8Input)
+	is.Equal(patient.Name[0].Family, []string{"Müller"})
+	is.Equal(patient.Name[0].Given, []string{"José"})
+
+	// JSON wrapped messages are UTF-8 whatever the configured charset
+	wrapped, err := json.Marshal(map[string]string{
+		"hl7": "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5\rPID|1||123^^^^MR||Müller^José||19800101|M",
+	})
+	is.NoErr(err)
+	patient = convert(map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+		"charset":    "iso-8859-1",
+	}, wrapped)
+	is.Equal(patient.Name[0].Family, []string{"Müller"})
+	is.Equal(patient.Name[0].Given, []string{"José"})
+}
+
+func TestDecodeCharset(t *testing.T) {
+	is := is.New(t)
+
+	// 0x8A is Š in Windows-1252 and a control character in Latin-1
+	raw := []byte("\x8aimon \x80 5 \xe0")
+	is.Equal(decodeCharset(raw, charsetWindows1252), "Šimon € 5 à")
+	is.Equal(decodeCharset(raw, charsetLatin1), "\u008aimon \u0080 5 à")
+	is.Equal(decodeCharset([]byte("Zürich"), charsetUTF8), "Zürich")
+
+	charset, ok := declaredCharset([]byte("MSH|^~\\&||||||||||||||||unicode utf-8~8859/1\rPID|1"))
+	is.True(ok)
+	is.Equal(charset, charsetUTF8)
+	_, ok = declaredCharset([]byte("MSH|^~\\&|APP\rPID|1"))
+	is.True(!ok)
+}
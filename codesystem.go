@@ -0,0 +1,58 @@
+package hl7
+
+import "strings"
+
+// codeSystems maps HL7 v2 coding system names (table 0396) to FHIR code
+// system URIs. I9, the older name of ICD-9-CM, is read but not written.
+var codeSystems = map[string]string{
+	"LN":      "http://loinc.org",
+	"SCT":     "http://snomed.info/sct",
+	"UCUM":    unitsOfMeasureSystem,
+	"I10":     "http://hl7.org/fhir/sid/icd-10",
+	"I10C":    "http://hl7.org/fhir/sid/icd-10-cm",
+	"I9":      "http://hl7.org/fhir/sid/icd-9-cm",
+	"I9C":     "http://hl7.org/fhir/sid/icd-9-cm",
+	"C4":      "http://www.ama-assn.org/go/cpt",
+	"CVX":     "http://hl7.org/fhir/sid/cvx",
+	"NDC":     "http://hl7.org/fhir/sid/ndc",
+	"RXNORM":  "http://www.nlm.nih.gov/research/umls/rxnorm",
+	"ISO3166": "urn:iso:std:iso:3166",
+}
+
+// hl7CodingSystems maps FHIR code system URIs to HL7 v2 coding system names,
+// the inverse of codeSystems.
+var hl7CodingSystems = func() map[string]string {
+	names := make(map[string]string, len(codeSystems))
+	for name, uri := range codeSystems {
+		if name != "I9" {
+			names[uri] = name
+		}
+	}
+	return names
+}()
+
+// fhirCodeSystem returns the FHIR code system URI of an HL7 v2 coding system
+// name. Values already holding a URI are kept; other names are dropped, as
+// FHIR requires a URI.
+func fhirCodeSystem(name string) string {
+	if uri, ok := codeSystems[name]; ok {
+		return uri
+	}
+	if strings.Contains(name, ":") {
+		return name
+	}
+	return ""
+}
+
+// hl7CodingSystem returns the HL7 v2 coding system name of a FHIR code system
+// URI. HL7 v2 names are kept; other URIs are dropped, as they do not fit the
+// coding system component.
+func hl7CodingSystem(uri string) string {
+	if name, ok := hl7CodingSystems[uri]; ok {
+		return name
+	}
+	if _, ok := codeSystems[uri]; ok {
+		return uri
+	}
+	return ""
+}
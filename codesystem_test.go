@@ -0,0 +1,31 @@
+package hl7
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestCodeSystems(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(fhirCodeSystem("LN"), "http://loinc.org")
+	is.Equal(fhirCodeSystem("UCUM"), "http://unitsofmeasure.org")
+	is.Equal(fhirCodeSystem("urn:oid:2.16.840.1.113883.6.1"), "urn:oid:2.16.840.1.113883.6.1")
+	is.Equal(fhirCodeSystem("99LOCAL"), "") // not a URI
+	is.Equal(fhirCodeSystem(""), "")
+
+	is.Equal(hl7CodingSystem("http://snomed.info/sct"), "SCT")
+	is.Equal(hl7CodingSystem("LN"), "LN")
+	is.Equal(hl7CodingSystem("http://example.org/codes"), "") // no HL7 v2 name
+
+	is.Equal(fhirCodeSystem("I9"), "http://hl7.org/fhir/sid/icd-9-cm")
+	is.Equal(hl7CodingSystem("http://hl7.org/fhir/sid/icd-9-cm"), "I9C") // not the older I9
+	is.Equal(hl7CodingSystem("I9"), "I9")
+
+	for name, uri := range codeSystems {
+		if name != "I9" {
+			is.Equal(hl7CodingSystem(uri), name) // every other name round-trips
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package hl7
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FHIRComposition represents a FHIR Composition resource.
+type FHIRComposition struct {
+	ResourceType string               `json:"resourceType"`
+	Identifier   *Identifier          `json:"identifier,omitempty"`
+	Status       string               `json:"status"`
+	Type         CodeableConcept      `json:"type"`
+	Subject      Reference            `json:"subject"`
+	Date         string               `json:"date,omitempty"`
+	Author       []Reference          `json:"author"`
+	Title        string               `json:"title"`
+	Section      []CompositionSection `json:"section,omitempty"`
+}
+
+// CompositionSection is a section of a FHIR Composition.
+type CompositionSection struct {
+	Title string           `json:"title,omitempty"`
+	Code  *CodeableConcept `json:"code,omitempty"`
+	Text  *Narrative       `json:"text,omitempty"`
+}
+
+// Narrative represents a FHIR Narrative.
+type Narrative struct {
+	Status string `json:"status"`
+	Div    string `json:"div"`
+}
+
+// DocumentHeader is a TXA segment.
+type DocumentHeader struct {
+	// DocumentType is the document type (TXA-2, table 0270), e.g. DS for a
+	// discharge summary.
+	DocumentType CodedElement
+	// ActivityDateTime is the raw activity date/time (TXA-4).
+	ActivityDateTime string
+	// Originator is the author of the document (TXA-9).
+	Originator string
+	// UniqueDocumentNumber identifies the document (TXA-12).
+	UniqueDocumentNumber string
+	// CompletionStatus is the document completion status (TXA-17, table
+	// 0271).
+	CompletionStatus string
+}
+
+// ObservationResult is an OBX segment.
+type ObservationResult struct {
+	SetID int
+	// ValueType is the data type of the values (OBX-2), e.g. TX.
+	ValueType  string
+	Identifier CodedElement
+	// Values are the raw repetitions of the observation value (OBX-5), to be
+	// parsed according to ValueType.
+	Values         []string
+	Units          CodedElement
+	ReferenceRange string
+	// AbnormalFlag is the first abnormal flag (OBX-8, table 0078), e.g. H.
+	AbnormalFlag string
+	// Status is the observation result status (OBX-11, table 0085).
+	Status string
+	// DateTime is the raw date/time of the observation (OBX-14).
+	DateTime string
+}
+
+// documentTypeSystem is the code system of HL7 v2 document types (table
+// 0270).
+const documentTypeSystem = "http://terminology.hl7.org/CodeSystem/v2-0270"
+
+// compositionStatuses maps HL7 v2 document completion statuses (table 0271)
+// to FHIR Composition statuses. Documents in other states are preliminary.
+var compositionStatuses = map[string]string{
+	"AU": "final",
+	"LA": "final",
+	"CA": "entered-in-error",
+}
+
+// parseDocumentHeader parses the fields of a TXA segment.
+func parseDocumentHeader(fields []string) DocumentHeader {
+	txa := func(n int) string { return fieldPath{Segment: "TXA", Field: n}.field(fields) }
+	originator, _, _ := nextToken(txa(9), '~')
+	return DocumentHeader{
+		DocumentType:         parseCodedElement(txa(2)),
+		ActivityDateTime:     txa(4),
+		Originator:           personName(originator),
+		UniqueDocumentNumber: unescapeHL7(component(txa(12), '^', 1)),
+		CompletionStatus:     txa(17),
+	}
+}
+
+// parseObservationResult parses the fields of an OBX segment.
+func parseObservationResult(fields []string) ObservationResult {
+	obx := func(n int) string { return fieldPath{Segment: "OBX", Field: n}.field(fields) }
+	setID, _ := strconv.Atoi(obx(1))
+	flag, _, _ := nextToken(obx(8), '~')
+	result := ObservationResult{
+		SetID:          setID,
+		ValueType:      obx(2),
+		Identifier:     parseCodedElement(obx(3)),
+		Units:          parseCodedElement(obx(6)),
+		ReferenceRange: unescapeHL7(obx(7)),
+		AbnormalFlag:   flag,
+		Status:         obx(11),
+		DateTime:       obx(14),
+	}
+	for rest, more := obx(5), true; more; {
+		var value string
+		value, rest, more = nextToken(rest, '~')
+		result.Values = append(result.Values, value)
+	}
+	return result
+}
+
+// convertToComposition converts the document of an MDM message, i.e. its TXA
+// segment and the OBX segments holding the document content, to a Composition
+// resource of the patient. Every OBX segment becomes a section. Times without
+// UTC offset are in location.
+func convertToComposition(msg HL7Message, location *time.Location) (FHIRComposition, error) {
+	txa := *msg.TXA
+	status, ok := compositionStatuses[txa.CompletionStatus]
+	if !ok {
+		status = "preliminary"
+	}
+	composition := FHIRComposition{
+		ResourceType: "Composition",
+		Status:       status,
+		Type:         CodeableConcept{Text: txa.DocumentType.Text},
+		Subject:      Reference{Reference: "Patient/" + msg.PID.ID},
+		Author:       []Reference{{Display: txa.Originator}},
+		Title:        txa.DocumentType.Text,
+	}
+	if txa.UniqueDocumentNumber != "" {
+		composition.Identifier = &Identifier{Value: txa.UniqueDocumentNumber}
+	}
+	if code := txa.DocumentType.Code; code != "" {
+		composition.Type.Coding = []Coding{{System: documentTypeSystem, Code: code, Display: txa.DocumentType.Text}}
+	}
+	if composition.Title == "" {
+		composition.Title = "Document"
+	}
+	if txa.ActivityDateTime != "" {
+		date, err := hl7ToFHIRTimestamp(txa.ActivityDateTime, location)
+		if err != nil {
+			return FHIRComposition{}, fmt.Errorf("invalid document activity date/time: %w", err)
+		}
+		composition.Date = date
+	}
+
+	for _, obx := range msg.OBX {
+		section := CompositionSection{
+			Title: obx.Identifier.Text,
+			Text:  newNarrative(obx.Values),
+		}
+		if obx.Identifier.Code != "" {
+			code := codeableConcept(obx.Identifier)
+			code.Text = ""
+			section.Code = &code
+		}
+		composition.Section = append(composition.Section, section)
+	}
+	return composition, nil
+}
+
+// newNarrative returns a generated narrative showing HL7 escaped lines as
+// XHTML, one line per row.
+func newNarrative(lines []string) *Narrative {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = html.EscapeString(unescapeHL7(line))
+	}
+	return &Narrative{
+		Status: "generated",
+		Div:    `<div xmlns="http://www.w3.org/1999/xhtml">` + strings.Join(escaped, "<br/>") + "</div>",
+	}
+}
+
+// personName returns the name of the person in an XCN value, "given family",
+// or the ID number if the name is missing.
+func personName(xcn string) string {
+	name := strings.TrimSpace(unescapeHL7(component(xcn, '^', 3)) + " " + unescapeHL7(component(xcn, '^', 2)))
+	if name == "" {
+		name = unescapeHL7(component(xcn, '^', 1))
+	}
+	return name
+}
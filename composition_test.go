@@ -0,0 +1,60 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_TXAAsComposition(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":        "hl7",
+		"outputType":       "fhir",
+		"txaAsComposition": "true",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|DOC_APP|FACILITY|HL7_PARSER|FACILITY|20230815120000||MDM^T02|123|P|2.5|\r" +
+		"EVN|T02|20230815120000\r" +
+		"PID|1||123||Smith^John||1990-01-01|male\r" +
+		"PV1|1|I\r" +
+		"TXA|1|DS^Discharge summary|TX|202308151130|||||1234^House^Gregory|||DOC-77|||||AU\r" +
+		"OBX|1|TX|11535-2^Hospital discharge Dx^LN||Pneumonia~Resolved on antibiotics & rest||||||F\r" +
+		"OBX|2|TX|18776-5^Plan of care^LN||Follow up in 2 weeks||||||F"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var bundle struct {
+		Entry []struct {
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &bundle)
+	is.NoErr(err)
+	is.Equal(len(bundle.Entry), 2)
+
+	var composition FHIRComposition
+	is.NoErr(json.Unmarshal(bundle.Entry[1].Resource, &composition))
+	is.Equal(composition.ResourceType, "Composition")
+	is.Equal(composition.Status, "final")
+	is.Equal(composition.Identifier.Value, "DOC-77")
+	is.Equal(composition.Type.Coding[0].Code, "DS")
+	is.Equal(composition.Title, "Discharge summary")
+	is.Equal(composition.Subject.Reference, "Patient/123")
+	is.Equal(composition.Date, "2023-08-15T11:30:00Z")
+	is.Equal(composition.Author[0].Display, "Gregory House")
+	is.Equal(len(composition.Section), 2)
+	is.Equal(composition.Section[0].Title, "Hospital discharge Dx")
+	is.Equal(composition.Section[0].Code.Coding[0].Code, "11535-2")
+	is.Equal(composition.Section[0].Text.Div, `<div xmlns="http://www.w3.org/1999/xhtml">Pneumonia<br/>Resolved on antibiotics &amp; rest</div>`)
+	is.Equal(composition.Section[1].Title, "Plan of care")
+}
@@ -0,0 +1,36 @@
+package hl7
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+)
+
+// metadataContentEncoding is the metadata key set to "gzip" on records whose
+// payload was compressed by CompressOutput.
+const metadataContentEncoding = "hl7.contentEncoding"
+
+// compressPayload gzips the raw payload of a converted record and flags it in
+// the record metadata. Structured payloads (HL7 v2 output) are left as is.
+func compressPayload(record *opencdc.Record) error {
+	raw, ok := record.Payload.After.(opencdc.RawData)
+	if !ok {
+		return nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return fmt.Errorf("failed to compress output: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress output: %w", err)
+	}
+	record.Payload.After = opencdc.RawData(buf.Bytes())
+	if record.Metadata == nil {
+		record.Metadata = opencdc.Metadata{}
+	}
+	record.Metadata[metadataContentEncoding] = "gzip"
+	return nil
+}
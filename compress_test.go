@@ -0,0 +1,69 @@
+package hl7
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_CompressOutput(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":           "hl7",
+		"outputType":          "fhir",
+		"includeSourceBinary": "true",
+		"compressOutput":      "true",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5\rPID|1||123^^^^MR||Smith^John||19800101|M"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	is.Equal(rec.Metadata[metadataContentEncoding], "gzip")
+
+	payload := rec.Payload.After.Bytes()
+	is.True(bytes.HasPrefix(payload, []byte{0x1f, 0x8b})) // gzip magic bytes
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	is.NoErr(err)
+	decompressed, err := io.ReadAll(zr)
+	is.NoErr(err)
+
+	var bundle FHIRBundle
+	is.NoErr(json.Unmarshal(decompressed, &bundle))
+	is.Equal(bundle.ResourceType, "Bundle")
+	is.Equal(len(bundle.Entry), 2)
+}
+
+func TestProcess_CompressOutputHL7(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":      "fhir",
+		"outputType":     "hl7",
+		"compressOutput": "true",
+	})
+	is.NoErr(err)
+
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(`{"resourceType":"Patient","id":"123"}`)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	// HL7 v2 output stays structured
+	_, ok = rec.Payload.After.(opencdc.StructuredData)
+	is.True(ok)
+	_, ok = rec.Metadata[metadataContentEncoding]
+	is.True(!ok)
+}
@@ -0,0 +1,122 @@
+package hl7
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FHIRCondition represents a FHIR Condition resource.
+type FHIRCondition struct {
+	ResourceType string            `json:"resourceType"`
+	Category     []CodeableConcept `json:"category,omitempty"`
+	Code         CodeableConcept   `json:"code"`
+	Subject      Reference         `json:"subject"`
+	RecordedDate string            `json:"recordedDate,omitempty"`
+}
+
+// Diagnosis is a DG1 segment.
+type Diagnosis struct {
+	SetID int
+	// Code is the diagnosis code (DG1-3) with its coding system, e.g. I10.
+	Code CodedElement
+	// DateTime is the raw diagnosis date/time (DG1-5).
+	DateTime string
+}
+
+// icdSystemPrefix is the common prefix of the FHIR code system URIs of the
+// ICD revisions in codeSystems.
+const icdSystemPrefix = "http://hl7.org/fhir/sid/icd-"
+
+// conditionCategorySystem is the code system of Condition categories.
+const conditionCategorySystem = "http://terminology.hl7.org/CodeSystem/condition-category"
+
+// parseDiagnosis parses the fields of a DG1 segment.
+func parseDiagnosis(fields []string) Diagnosis {
+	dg1 := func(n int) string { return fieldPath{Segment: "DG1", Field: n}.field(fields) }
+	setID, _ := strconv.Atoi(dg1(1))
+	return Diagnosis{
+		SetID:    setID,
+		Code:     parseCodedElement(dg1(3)),
+		DateTime: dg1(5),
+	}
+}
+
+// convertDiagnosis converts a DG1 segment to a Condition resource of the
+// patient with the given ID. Times without UTC offset are in location.
+func convertDiagnosis(patientID string, dg1 Diagnosis, location *time.Location) (FHIRCondition, error) {
+	condition := FHIRCondition{
+		ResourceType: "Condition",
+		Category: []CodeableConcept{{
+			Coding: []Coding{{System: conditionCategorySystem, Code: "encounter-diagnosis"}},
+		}},
+		Code:    CodeableConcept{Text: dg1.Code.Text},
+		Subject: Reference{Reference: "Patient/" + patientID},
+	}
+	if dg1.Code.Code != "" {
+		condition.Code.Coding = []Coding{{
+			System:  icdSystem(dg1.Code),
+			Code:    dg1.Code.Code,
+			Display: dg1.Code.Text,
+		}}
+	}
+	if dg1.DateTime != "" {
+		recorded, err := hl7ToFHIRTimestamp(dg1.DateTime, location)
+		if err != nil {
+			return FHIRCondition{}, fmt.Errorf("diagnosis %d: invalid diagnosis date/time: %w", dg1.SetID, err)
+		}
+		condition.RecordedDate = recorded
+	}
+	return condition, nil
+}
+
+// icdSystem returns the FHIR code system of a diagnosis code. The system
+// named in the coded element wins if it is an ICD revision; without one,
+// ICD-9 and ICD-10 are told apart by the code format. Codes matching neither,
+// and the V codes both revisions share, are left without a system.
+func icdSystem(code CodedElement) string {
+	if code.System != "" {
+		if system := codeSystems[code.System]; strings.HasPrefix(system, icdSystemPrefix) {
+			return system
+		}
+		return ""
+	}
+
+	c := code.Code
+	category, _, _ := nextToken(c, '.')
+	switch {
+	case len(category) == 3 && isDigits(category):
+		// 250.00
+		return codeSystems["I9C"]
+	case len(category) == 4 && category[0] == 'E' && isDigits(category[1:]):
+		// E849.0
+		return codeSystems["I9C"]
+	case len(category) == 3 && category[0] >= 'A' && category[0] <= 'Z' && category[0] != 'V' &&
+		isDigits(category[1:2]) && isAlphanumeric(category[2:]):
+		// E11.9, C7A.0
+		return codeSystems["I10"]
+	}
+	return ""
+}
+
+// isDigits reports whether s is not empty and consists of ASCII digits only.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isAlphanumeric reports whether s consists of ASCII digits and upper case
+// letters only.
+func isAlphanumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'A' || s[i] > 'Z') {
+			return false
+		}
+	}
+	return true
+}
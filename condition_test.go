@@ -0,0 +1,82 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_DG1AsCondition(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":      "hl7",
+		"outputType":     "fhir",
+		"dg1AsCondition": "true",
+		"parseMode":      "strict",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\r" +
+		"PID|1||123||Smith^John||1990-01-01|male\r" +
+		"DG1|1|I10|E11.9^Type 2 diabetes mellitus without complications^I10||20230815\r" +
+		"DG1|2|I9|250.00^Diabetes mellitus without complication^I9"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var bundle struct {
+		Entry []struct {
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &bundle)
+	is.NoErr(err)
+	is.Equal(len(bundle.Entry), 3)
+
+	var icd10, icd9 FHIRCondition
+	is.NoErr(json.Unmarshal(bundle.Entry[1].Resource, &icd10))
+	is.NoErr(json.Unmarshal(bundle.Entry[2].Resource, &icd9))
+
+	is.Equal(icd10.ResourceType, "Condition")
+	is.Equal(icd10.Subject.Reference, "Patient/123")
+	is.Equal(icd10.Code.Coding[0].System, "http://hl7.org/fhir/sid/icd-10")
+	is.Equal(icd10.Code.Coding[0].Code, "E11.9")
+	is.Equal(icd10.RecordedDate, "2023-08-15")
+
+	is.Equal(icd9.Code.Coding[0].System, "http://hl7.org/fhir/sid/icd-9-cm")
+	is.Equal(icd9.Code.Coding[0].Code, "250.00")
+	is.Equal(icd9.Code.Text, "Diabetes mellitus without complication")
+}
+
+func TestICDSystem(t *testing.T) {
+	testCases := []struct {
+		code CodedElement
+		want string
+	}{
+		{CodedElement{Code: "E11.9", System: "I10"}, "http://hl7.org/fhir/sid/icd-10"},
+		{CodedElement{Code: "E11.9", System: "I10C"}, "http://hl7.org/fhir/sid/icd-10-cm"},
+		{CodedElement{Code: "250.00", System: "I9"}, "http://hl7.org/fhir/sid/icd-9-cm"},
+		{CodedElement{Code: "250.00", System: "I9C"}, "http://hl7.org/fhir/sid/icd-9-cm"},
+		{CodedElement{Code: "44054006", System: "SCT"}, ""},
+		// without a coding system the revision is detected from the code
+		{CodedElement{Code: "J45.909"}, "http://hl7.org/fhir/sid/icd-10"},
+		{CodedElement{Code: "C7A.0"}, "http://hl7.org/fhir/sid/icd-10"},
+		{CodedElement{Code: "401.9"}, "http://hl7.org/fhir/sid/icd-9-cm"},
+		{CodedElement{Code: "E849.0"}, "http://hl7.org/fhir/sid/icd-9-cm"},
+		{CodedElement{Code: "V22.0"}, ""}, // V codes exist in both revisions
+		{CodedElement{Code: "ABC"}, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.code.Code+"^"+tc.code.System, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(icdSystem(tc.code), tc.want)
+		})
+	}
+}
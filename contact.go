@@ -0,0 +1,150 @@
+package hl7
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PatientContact represents a FHIR Patient.contact.
+type PatientContact struct {
+	Relationship []CodeableConcept `json:"relationship,omitempty"`
+	Name         *HumanName        `json:"name,omitempty"`
+	Address      *Address          `json:"address,omitempty"`
+	Gender       string            `json:"gender,omitempty"`
+}
+
+// FHIRRelatedPerson represents a FHIR RelatedPerson resource.
+type FHIRRelatedPerson struct {
+	ResourceType string            `json:"resourceType"`
+	Patient      Reference         `json:"patient"`
+	Relationship []CodeableConcept `json:"relationship,omitempty"`
+	Name         []HumanName       `json:"name,omitempty"`
+	Gender       string            `json:"gender,omitempty"`
+	Address      []Address         `json:"address,omitempty"`
+}
+
+// NextOfKin is an NK1 segment.
+type NextOfKin struct {
+	LastName     string
+	FirstName    string
+	Relationship CodedElement
+	// Address is the first address (NK1-4), nil when there is none.
+	Address *PatientAddress
+	// Gender is the administrative sex (NK1-15).
+	Gender string
+}
+
+// relationshipSystem is the code system of HL7 v2 relationships (table 0063).
+const relationshipSystem = "http://terminology.hl7.org/CodeSystem/v2-0063"
+
+// nk1FieldCount is the number of fields emitted in NK1 segments.
+const nk1FieldCount = 15
+
+// nk1AddressPath is the address field of NK1 segments.
+var nk1AddressPath = fieldPath{Segment: "NK1", Field: 4}
+
+// nk1AddressMappings locates the address components of NK1-4, which are laid
+// out like the default PID-11 components.
+var nk1AddressMappings = func() map[string]fieldPath {
+	mappings := make(map[string]fieldPath, len(addressNames))
+	for _, name := range addressNames {
+		mappings[name] = fieldPath{
+			Segment:   nk1AddressPath.Segment,
+			Field:     nk1AddressPath.Field,
+			Component: defaultFieldMappings[name].Component,
+		}
+	}
+	return mappings
+}()
+
+// parseNextOfKin parses the fields of an NK1 segment.
+func parseNextOfKin(fields []string) NextOfKin {
+	nk1 := func(n int) string { return fieldPath{Segment: "NK1", Field: n}.field(fields) }
+	name, _, _ := nextToken(nk1(2), '~')
+	kin := NextOfKin{
+		LastName:     unescapeHL7(component(name, '^', 1)),
+		FirstName:    unescapeHL7(component(name, '^', 2)),
+		Relationship: parseCodedElement(nk1(3)),
+		Gender:       component(nk1(15), '~', 1),
+	}
+	for _, addr := range parsePatientAddresses(nk1AddressPath.field(fields), nk1AddressPath, nk1AddressMappings) {
+		if addr != (PatientAddress{}) {
+			kin.Address = &addr
+			break
+		}
+	}
+	return kin
+}
+
+// convertNextOfKin converts an NK1 segment to a FHIR patient contact.
+func (p *Processor) convertNextOfKin(nk1 NextOfKin) PatientContact {
+	var contact PatientContact
+	if nk1.LastName != "" || nk1.FirstName != "" {
+		contact.Name = &HumanName{Family: []string{nk1.LastName}, Given: []string{nk1.FirstName}}
+	}
+	if r := nk1.Relationship; r.Code != "" {
+		contact.Relationship = []CodeableConcept{{
+			Coding: []Coding{{System: relationshipSystem, Code: r.Code, Display: r.Text}},
+		}}
+	}
+	if nk1.Address != nil {
+		address := p.convertAddress(*nk1.Address)
+		contact.Address = &address
+	}
+	if nk1.Gender != "" {
+		contact.Gender = p.hl7ToFHIRGender(nk1.Gender)
+	}
+	return contact
+}
+
+// isEmptyContact reports whether a patient contact has neither a name,
+// relationship, address nor gender.
+func isEmptyContact(contact PatientContact) bool {
+	return contact.Name == nil && len(contact.Relationship) == 0 && contact.Address == nil && contact.Gender == ""
+}
+
+// newRelatedPerson converts a contact of the patient with the given ID to a
+// standalone RelatedPerson resource.
+func newRelatedPerson(patientID string, contact PatientContact) FHIRRelatedPerson {
+	person := FHIRRelatedPerson{
+		ResourceType: "RelatedPerson",
+		Patient:      Reference{Reference: "Patient/" + patientID},
+		Relationship: contact.Relationship,
+		Gender:       contact.Gender,
+	}
+	if contact.Name != nil {
+		person.Name = []HumanName{*contact.Name}
+	}
+	if contact.Address != nil {
+		person.Address = []Address{*contact.Address}
+	}
+	return person
+}
+
+// formatNextOfKin formats a FHIR patient contact as the setID-th NK1
+// segment.
+func (p *Processor) formatNextOfKin(setID int, contact PatientContact) string {
+	nk1 := newSegment("NK1", nk1FieldCount)
+	nk1[1] = strconv.Itoa(setID)
+	if n := contact.Name; n != nil {
+		var family, given string
+		if len(n.Family) > 0 {
+			family = n.Family[0]
+		}
+		if len(n.Given) > 0 {
+			given = n.Given[0]
+		}
+		nk1[2] = strings.TrimRight(joinComponents(family, given), "^")
+	}
+	if len(contact.Relationship) > 0 && len(contact.Relationship[0].Coding) > 0 {
+		coding := contact.Relationship[0].Coding[0]
+		nk1[3] = formatCodedElement(CodedElement{Code: coding.Code, Text: coding.Display})
+	}
+	if addr := contact.Address; addr != nil {
+		normalized := *addr
+		normalized.Country = normalizeCountry(addr.Country, p.config.CountryFormat)
+		nk1[4] = formatXAD(normalized)
+	}
+	nk1[15] = fhirToHL7Gender(contact.Gender)
+	return strings.Join(nk1, "|")
+}
@@ -0,0 +1,95 @@
+package hl7
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestConvertHL7ToFHIR_ContactGender(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male\nNK1|1|Smith^Jane|SPO^Spouse||||||||||||F"
+	msg, err := parseHL7Message(hl7String, parseOptions{strict: true})
+	is.NoErr(err)
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(len(patient.Contact), 1)
+	contact := patient.Contact[0]
+	is.Equal(contact.Gender, "female")
+	is.Equal(contact.Name.Family[0], "Smith")
+	is.Equal(contact.Relationship[0].Coding[0].Code, "SPO")
+
+	// and back to NK1-15
+	contact.Gender = "male"
+	patient.Contact = []PatientContact{contact}
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	segments := splitHL7Message(hl7Message)
+	is.Equal(len(segments), 3)
+	nk1Fields := splitHL7Field(segments[2])
+	is.Equal(nk1Fields[0], "NK1")
+	is.Equal(nk1Fields[2], "Smith^Jane")
+	is.Equal(nk1Fields[3], "SPO^Spouse")
+	is.Equal(nk1Fields[15], "M")
+}
+
+func TestConvertHL7ToFHIR_ContactAddress(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\n" +
+		"PID|1||123||Smith^John||1990-01-01|male\n" +
+		"NK1|1|Smith^Jane|SPO^Spouse|42 Elm St^Springfield^IL^62701^USA^^H"
+	msg, err := parseHL7Message(hl7String, parseOptions{strict: true})
+	is.NoErr(err)
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.Contact[0].Address, &Address{
+		Use:        "home",
+		Line:       []string{"42 Elm St"},
+		City:       "Springfield",
+		State:      "IL",
+		PostalCode: "62701",
+		Country:    "USA",
+	})
+
+	// and back to NK1-4
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	nk1Fields := splitHL7Field(splitHL7Message(hl7Message)[2])
+	is.Equal(nk1Fields[4], "42 Elm St^Springfield^IL^62701^USA^^H")
+
+	// contacts without an address leave NK1-4 empty
+	patient.Contact[0].Address = nil
+	hl7Message, err = p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	nk1Fields = splitHL7Field(splitHL7Message(hl7Message)[2])
+	is.Equal(nk1Fields[4], "")
+}
+
+func TestConvert_EmptyContact(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\rPID|1||123||Smith^John||19900101|M\rNK1|1\rNK1|2|Smith^Jane|SPO^Spouse"
+	msg, err := parseHL7Message(hl7String, parseOptions{strict: true})
+	is.NoErr(err)
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(len(patient.Contact), 1) // the empty NK1 is skipped
+	is.Equal(patient.Contact[0].Name.Given[0], "Jane")
+
+	// and no empty NK1 for an empty contact, the others are numbered on
+	patient.Contact = append([]PatientContact{{}}, patient.Contact...)
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	segments := splitHL7Message(hl7Message)
+	is.Equal(len(segments), 3)
+	nk1Fields := splitHL7Field(segments[2])
+	is.Equal(nk1Fields[1], "1")
+	is.Equal(nk1Fields[2], "Smith^Jane")
+}
@@ -0,0 +1,43 @@
+package hl7
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+)
+
+// Convert converts input from one format to another outside of a Conduit
+// pipeline, using the default configuration. from and to are the input and
+// output types accepted by the processor: "fhir", "hl7" or "hl7v3". HL7 v2
+// input and output are plain messages, without the {"hl7": ...} wrapper used
+// in records.
+func Convert(input []byte, from, to string) ([]byte, error) {
+	ctx := context.Background()
+	p := &Processor{}
+	err := p.Configure(ctx, map[string]string{
+		"inputType":  from,
+		"outputType": to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	processed := p.processRecord(ctx, 0, opencdc.Record{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	})
+	switch rec := processed.(type) {
+	case sdk.ErrorRecord:
+		return nil, rec.Error
+	case sdk.SingleRecord:
+		if data, ok := rec.Payload.After.(opencdc.StructuredData); ok {
+			if msg, ok := data["hl7"].(string); ok {
+				return []byte(msg), nil
+			}
+		}
+		return rec.Payload.After.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unexpected processed record %T", processed)
+	}
+}
@@ -0,0 +1,78 @@
+package hl7
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+const convertTestFHIR = `{"id":"123","name":[{"family":["Smith"],"given":["John"]}],"birthDate":"1990-01-01","gender":"male"}`
+
+func TestConvert_FHIRToHL7(t *testing.T) {
+	is := is.New(t)
+
+	out, err := Convert([]byte(convertTestFHIR), "fhir", "hl7")
+	is.NoErr(err)
+	segments := splitHL7Message(string(out))
+	is.Equal(len(segments), 2)
+	is.True(strings.HasPrefix(segments[0], "MSH|"))
+	pidFields := splitHL7Field(segments[1])
+	is.Equal(pidFields[3], "123")
+	is.Equal(pidFields[5], "Smith^John")
+}
+
+func TestConvert_FHIRToHL7V3(t *testing.T) {
+	is := is.New(t)
+
+	out, err := Convert([]byte(convertTestFHIR), "fhir", "hl7v3")
+	is.NoErr(err)
+	var v3Patient HL7V3Patient
+	is.NoErr(xml.Unmarshal(out, &v3Patient))
+	is.Equal(v3Patient.ID, "123")
+	is.Equal(v3Patient.Name[0].Family, "Smith")
+}
+
+func TestConvert_HL7ToFHIR(t *testing.T) {
+	is := is.New(t)
+
+	// both a plain message and the record wrapper are accepted
+	for _, input := range []string{
+		"MSH|^~\\&|APP|FAC|APP|FAC|20230815120000||ADT^A01|123|P|2.5\rPID|1||123||Smith^John||19900101|M",
+		`{"hl7":"MSH|^~\\&|APP|FAC|APP|FAC|20230815120000||ADT^A01|123|P|2.5\rPID|1||123||Smith^John||19900101|M"}`,
+	} {
+		out, err := Convert([]byte(input), "hl7", "fhir")
+		is.NoErr(err)
+		var patient FHIRPatient
+		is.NoErr(json.Unmarshal(out, &patient))
+		is.Equal(patient.ID, "123")
+		is.Equal(patient.Name[0].Family[0], "Smith")
+		is.Equal(patient.Gender, "male")
+	}
+}
+
+func TestConvert_HL7V3ToFHIR(t *testing.T) {
+	is := is.New(t)
+
+	input := `<Patient xmlns="urn:hl7-org:v3"><id>123</id><name><given>John</given><family>Smith</family></name>` +
+		`<administrativeGenderCode><code>F</code></administrativeGenderCode><birthTime><value>19900101000000</value></birthTime></Patient>`
+	out, err := Convert([]byte(input), "hl7v3", "fhir")
+	is.NoErr(err)
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(out, &patient))
+	is.Equal(patient.ID, "123")
+	is.Equal(patient.Gender, "female")
+	is.Equal(patient.BirthDate, "1990-01-01")
+}
+
+func TestConvert_Errors(t *testing.T) {
+	is := is.New(t)
+
+	_, err := Convert([]byte(convertTestFHIR), "fhir", "csv")
+	is.True(err != nil) // unknown output type
+
+	_, err = Convert([]byte("not a message"), "hl7", "fhir")
+	is.True(err != nil)
+}
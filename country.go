@@ -0,0 +1,54 @@
+package hl7
+
+import "strings"
+
+// Country formats of the CountryFormat option.
+const (
+	countryFormatAlpha2 = "alpha2"
+	countryFormatAlpha3 = "alpha3"
+	countryFormatName   = "name"
+)
+
+// country is an ISO 3166-1 country.
+type country struct {
+	alpha2 string
+	alpha3 string
+	name   string
+}
+
+// countryIndex maps the upper case codes and names of the countries, and the
+// lower case aliases, to the country.
+var countryIndex = func() map[string]country {
+	index := make(map[string]country, 4*len(iso3166Countries))
+	for _, c := range iso3166Countries {
+		index[c.alpha2] = c
+		index[c.alpha3] = c
+		index[strings.ToUpper(c.name)] = c
+	}
+	for alias, alpha2 := range iso3166CountryAliases {
+		index[strings.ToUpper(alias)] = index[alpha2]
+	}
+	return index
+}()
+
+// normalizeCountry returns the country v, given as alpha-2 code, alpha-3 code
+// or name, in the given format. Unknown countries, and all countries when no
+// format is set, are returned unchanged.
+func normalizeCountry(v, format string) string {
+	if format == "" {
+		return v
+	}
+	c, ok := countryIndex[strings.ToUpper(strings.TrimSpace(v))]
+	if !ok {
+		return v
+	}
+	switch format {
+	case countryFormatAlpha2:
+		return c.alpha2
+	case countryFormatAlpha3:
+		return c.alpha3
+	case countryFormatName:
+		return c.name
+	}
+	return v
+}
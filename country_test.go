@@ -0,0 +1,67 @@
+package hl7
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestNormalizeCountry(t *testing.T) {
+	testCases := []struct {
+		in, format, want string
+	}{
+		{"USA", countryFormatAlpha2, "US"},
+		{"US", countryFormatAlpha3, "USA"},
+		{"United States", countryFormatAlpha3, "USA"},
+		{"united states of america", countryFormatAlpha2, "US"},
+		{"USA", countryFormatName, "United States"},
+		{"deu", countryFormatName, "Germany"},
+		{"Atlantis", countryFormatAlpha3, "Atlantis"}, // unknown values are untouched
+		{"USA", "", "USA"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in+"->"+tc.format, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(normalizeCountry(tc.in, tc.format), tc.want)
+		})
+	}
+}
+
+func TestCountryFormat_BothDirections(t *testing.T) {
+	is := is.New(t)
+
+	toHL7 := NewProcessor().(*Processor)
+	err := toHL7.Configure(context.Background(), map[string]string{
+		"inputType":     "fhir",
+		"outputType":    "hl7",
+		"countryFormat": "alpha3",
+	})
+	is.NoErr(err)
+	hl7Message, err := toHL7.convertFHIRToHL7(FHIRPatient{
+		ID:      "123",
+		Address: []Address{{Line: []string{"1 Main St"}, City: "Springfield", State: "IL", PostalCode: "62701", Country: "US"}},
+	})
+	is.NoErr(err)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[11], "1 Main St^Springfield^IL^62701^USA")
+
+	toFHIR := NewProcessor().(*Processor)
+	err = toFHIR.Configure(context.Background(), map[string]string{
+		"inputType":     "hl7",
+		"outputType":    "fhir",
+		"countryFormat": "alpha2",
+	})
+	is.NoErr(err)
+	msg, err := parseHL7Message(hl7Message, toFHIR.parseOptions())
+	is.NoErr(err)
+	patient, err := toFHIR.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.Address[0].Country, "US")
+
+	err = toFHIR.Configure(context.Background(), map[string]string{
+		"inputType":     "hl7",
+		"outputType":    "fhir",
+		"countryFormat": "numeric",
+	})
+	is.True(err != nil) // unsupported format
+}
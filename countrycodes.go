@@ -0,0 +1,439 @@
+package hl7
+
+// The country tables follow ISO 3166-1 as published by the iso-codes
+// project.
+
+// iso3166Countries lists the ISO 3166-1 countries with their alpha-2 code,
+// alpha-3 code and short name.
+var iso3166Countries = []country{
+	{"AD", "AND", "Andorra"},
+	{"AE", "ARE", "United Arab Emirates"},
+	{"AF", "AFG", "Afghanistan"},
+	{"AG", "ATG", "Antigua and Barbuda"},
+	{"AI", "AIA", "Anguilla"},
+	{"AL", "ALB", "Albania"},
+	{"AM", "ARM", "Armenia"},
+	{"AO", "AGO", "Angola"},
+	{"AQ", "ATA", "Antarctica"},
+	{"AR", "ARG", "Argentina"},
+	{"AS", "ASM", "American Samoa"},
+	{"AT", "AUT", "Austria"},
+	{"AU", "AUS", "Australia"},
+	{"AW", "ABW", "Aruba"},
+	{"AX", "ALA", "Åland Islands"},
+	{"AZ", "AZE", "Azerbaijan"},
+	{"BA", "BIH", "Bosnia and Herzegovina"},
+	{"BB", "BRB", "Barbados"},
+	{"BD", "BGD", "Bangladesh"},
+	{"BE", "BEL", "Belgium"},
+	{"BF", "BFA", "Burkina Faso"},
+	{"BG", "BGR", "Bulgaria"},
+	{"BH", "BHR", "Bahrain"},
+	{"BI", "BDI", "Burundi"},
+	{"BJ", "BEN", "Benin"},
+	{"BL", "BLM", "Saint Barthélemy"},
+	{"BM", "BMU", "Bermuda"},
+	{"BN", "BRN", "Brunei Darussalam"},
+	{"BO", "BOL", "Bolivia"},
+	{"BQ", "BES", "Bonaire, Sint Eustatius and Saba"},
+	{"BR", "BRA", "Brazil"},
+	{"BS", "BHS", "Bahamas"},
+	{"BT", "BTN", "Bhutan"},
+	{"BV", "BVT", "Bouvet Island"},
+	{"BW", "BWA", "Botswana"},
+	{"BY", "BLR", "Belarus"},
+	{"BZ", "BLZ", "Belize"},
+	{"CA", "CAN", "Canada"},
+	{"CC", "CCK", "Cocos (Keeling) Islands"},
+	{"CD", "COD", "Congo, The Democratic Republic of the"},
+	{"CF", "CAF", "Central African Republic"},
+	{"CG", "COG", "Congo"},
+	{"CH", "CHE", "Switzerland"},
+	{"CI", "CIV", "Côte d'Ivoire"},
+	{"CK", "COK", "Cook Islands"},
+	{"CL", "CHL", "Chile"},
+	{"CM", "CMR", "Cameroon"},
+	{"CN", "CHN", "China"},
+	{"CO", "COL", "Colombia"},
+	{"CR", "CRI", "Costa Rica"},
+	{"CU", "CUB", "Cuba"},
+	{"CV", "CPV", "Cabo Verde"},
+	{"CW", "CUW", "Curaçao"},
+	{"CX", "CXR", "Christmas Island"},
+	{"CY", "CYP", "Cyprus"},
+	{"CZ", "CZE", "Czechia"},
+	{"DE", "DEU", "Germany"},
+	{"DJ", "DJI", "Djibouti"},
+	{"DK", "DNK", "Denmark"},
+	{"DM", "DMA", "Dominica"},
+	{"DO", "DOM", "Dominican Republic"},
+	{"DZ", "DZA", "Algeria"},
+	{"EC", "ECU", "Ecuador"},
+	{"EE", "EST", "Estonia"},
+	{"EG", "EGY", "Egypt"},
+	{"EH", "ESH", "Western Sahara"},
+	{"ER", "ERI", "Eritrea"},
+	{"ES", "ESP", "Spain"},
+	{"ET", "ETH", "Ethiopia"},
+	{"FI", "FIN", "Finland"},
+	{"FJ", "FJI", "Fiji"},
+	{"FK", "FLK", "Falkland Islands (Malvinas)"},
+	{"FM", "FSM", "Micronesia, Federated States of"},
+	{"FO", "FRO", "Faroe Islands"},
+	{"FR", "FRA", "France"},
+	{"GA", "GAB", "Gabon"},
+	{"GB", "GBR", "United Kingdom"},
+	{"GD", "GRD", "Grenada"},
+	{"GE", "GEO", "Georgia"},
+	{"GF", "GUF", "French Guiana"},
+	{"GG", "GGY", "Guernsey"},
+	{"GH", "GHA", "Ghana"},
+	{"GI", "GIB", "Gibraltar"},
+	{"GL", "GRL", "Greenland"},
+	{"GM", "GMB", "Gambia"},
+	{"GN", "GIN", "Guinea"},
+	{"GP", "GLP", "Guadeloupe"},
+	{"GQ", "GNQ", "Equatorial Guinea"},
+	{"GR", "GRC", "Greece"},
+	{"GS", "SGS", "South Georgia and the South Sandwich Islands"},
+	{"GT", "GTM", "Guatemala"},
+	{"GU", "GUM", "Guam"},
+	{"GW", "GNB", "Guinea-Bissau"},
+	{"GY", "GUY", "Guyana"},
+	{"HK", "HKG", "Hong Kong"},
+	{"HM", "HMD", "Heard Island and McDonald Islands"},
+	{"HN", "HND", "Honduras"},
+	{"HR", "HRV", "Croatia"},
+	{"HT", "HTI", "Haiti"},
+	{"HU", "HUN", "Hungary"},
+	{"ID", "IDN", "Indonesia"},
+	{"IE", "IRL", "Ireland"},
+	{"IL", "ISR", "Israel"},
+	{"IM", "IMN", "Isle of Man"},
+	{"IN", "IND", "India"},
+	{"IO", "IOT", "British Indian Ocean Territory"},
+	{"IQ", "IRQ", "Iraq"},
+	{"IR", "IRN", "Iran"},
+	{"IS", "ISL", "Iceland"},
+	{"IT", "ITA", "Italy"},
+	{"JE", "JEY", "Jersey"},
+	{"JM", "JAM", "Jamaica"},
+	{"JO", "JOR", "Jordan"},
+	{"JP", "JPN", "Japan"},
+	{"KE", "KEN", "Kenya"},
+	{"KG", "KGZ", "Kyrgyzstan"},
+	{"KH", "KHM", "Cambodia"},
+	{"KI", "KIR", "Kiribati"},
+	{"KM", "COM", "Comoros"},
+	{"KN", "KNA", "Saint Kitts and Nevis"},
+	{"KP", "PRK", "North Korea"},
+	{"KR", "KOR", "South Korea"},
+	{"KW", "KWT", "Kuwait"},
+	{"KY", "CYM", "Cayman Islands"},
+	{"KZ", "KAZ", "Kazakhstan"},
+	{"LA", "LAO", "Laos"},
+	{"LB", "LBN", "Lebanon"},
+	{"LC", "LCA", "Saint Lucia"},
+	{"LI", "LIE", "Liechtenstein"},
+	{"LK", "LKA", "Sri Lanka"},
+	{"LR", "LBR", "Liberia"},
+	{"LS", "LSO", "Lesotho"},
+	{"LT", "LTU", "Lithuania"},
+	{"LU", "LUX", "Luxembourg"},
+	{"LV", "LVA", "Latvia"},
+	{"LY", "LBY", "Libya"},
+	{"MA", "MAR", "Morocco"},
+	{"MC", "MCO", "Monaco"},
+	{"MD", "MDA", "Moldova"},
+	{"ME", "MNE", "Montenegro"},
+	{"MF", "MAF", "Saint Martin (French part)"},
+	{"MG", "MDG", "Madagascar"},
+	{"MH", "MHL", "Marshall Islands"},
+	{"MK", "MKD", "North Macedonia"},
+	{"ML", "MLI", "Mali"},
+	{"MM", "MMR", "Myanmar"},
+	{"MN", "MNG", "Mongolia"},
+	{"MO", "MAC", "Macao"},
+	{"MP", "MNP", "Northern Mariana Islands"},
+	{"MQ", "MTQ", "Martinique"},
+	{"MR", "MRT", "Mauritania"},
+	{"MS", "MSR", "Montserrat"},
+	{"MT", "MLT", "Malta"},
+	{"MU", "MUS", "Mauritius"},
+	{"MV", "MDV", "Maldives"},
+	{"MW", "MWI", "Malawi"},
+	{"MX", "MEX", "Mexico"},
+	{"MY", "MYS", "Malaysia"},
+	{"MZ", "MOZ", "Mozambique"},
+	{"NA", "NAM", "Namibia"},
+	{"NC", "NCL", "New Caledonia"},
+	{"NE", "NER", "Niger"},
+	{"NF", "NFK", "Norfolk Island"},
+	{"NG", "NGA", "Nigeria"},
+	{"NI", "NIC", "Nicaragua"},
+	{"NL", "NLD", "Netherlands"},
+	{"NO", "NOR", "Norway"},
+	{"NP", "NPL", "Nepal"},
+	{"NR", "NRU", "Nauru"},
+	{"NU", "NIU", "Niue"},
+	{"NZ", "NZL", "New Zealand"},
+	{"OM", "OMN", "Oman"},
+	{"PA", "PAN", "Panama"},
+	{"PE", "PER", "Peru"},
+	{"PF", "PYF", "French Polynesia"},
+	{"PG", "PNG", "Papua New Guinea"},
+	{"PH", "PHL", "Philippines"},
+	{"PK", "PAK", "Pakistan"},
+	{"PL", "POL", "Poland"},
+	{"PM", "SPM", "Saint Pierre and Miquelon"},
+	{"PN", "PCN", "Pitcairn"},
+	{"PR", "PRI", "Puerto Rico"},
+	{"PS", "PSE", "Palestine, State of"},
+	{"PT", "PRT", "Portugal"},
+	{"PW", "PLW", "Palau"},
+	{"PY", "PRY", "Paraguay"},
+	{"QA", "QAT", "Qatar"},
+	{"RE", "REU", "Réunion"},
+	{"RO", "ROU", "Romania"},
+	{"RS", "SRB", "Serbia"},
+	{"RU", "RUS", "Russian Federation"},
+	{"RW", "RWA", "Rwanda"},
+	{"SA", "SAU", "Saudi Arabia"},
+	{"SB", "SLB", "Solomon Islands"},
+	{"SC", "SYC", "Seychelles"},
+	{"SD", "SDN", "Sudan"},
+	{"SE", "SWE", "Sweden"},
+	{"SG", "SGP", "Singapore"},
+	{"SH", "SHN", "Saint Helena, Ascension and Tristan da Cunha"},
+	{"SI", "SVN", "Slovenia"},
+	{"SJ", "SJM", "Svalbard and Jan Mayen"},
+	{"SK", "SVK", "Slovakia"},
+	{"SL", "SLE", "Sierra Leone"},
+	{"SM", "SMR", "San Marino"},
+	{"SN", "SEN", "Senegal"},
+	{"SO", "SOM", "Somalia"},
+	{"SR", "SUR", "Suriname"},
+	{"SS", "SSD", "South Sudan"},
+	{"ST", "STP", "Sao Tome and Principe"},
+	{"SV", "SLV", "El Salvador"},
+	{"SX", "SXM", "Sint Maarten (Dutch part)"},
+	{"SY", "SYR", "Syria"},
+	{"SZ", "SWZ", "Eswatini"},
+	{"TC", "TCA", "Turks and Caicos Islands"},
+	{"TD", "TCD", "Chad"},
+	{"TF", "ATF", "French Southern Territories"},
+	{"TG", "TGO", "Togo"},
+	{"TH", "THA", "Thailand"},
+	{"TJ", "TJK", "Tajikistan"},
+	{"TK", "TKL", "Tokelau"},
+	{"TL", "TLS", "Timor-Leste"},
+	{"TM", "TKM", "Turkmenistan"},
+	{"TN", "TUN", "Tunisia"},
+	{"TO", "TON", "Tonga"},
+	{"TR", "TUR", "Türkiye"},
+	{"TT", "TTO", "Trinidad and Tobago"},
+	{"TV", "TUV", "Tuvalu"},
+	{"TW", "TWN", "Taiwan"},
+	{"TZ", "TZA", "Tanzania"},
+	{"UA", "UKR", "Ukraine"},
+	{"UG", "UGA", "Uganda"},
+	{"UM", "UMI", "United States Minor Outlying Islands"},
+	{"US", "USA", "United States"},
+	{"UY", "URY", "Uruguay"},
+	{"UZ", "UZB", "Uzbekistan"},
+	{"VA", "VAT", "Holy See (Vatican City State)"},
+	{"VC", "VCT", "Saint Vincent and the Grenadines"},
+	{"VE", "VEN", "Venezuela"},
+	{"VG", "VGB", "Virgin Islands, British"},
+	{"VI", "VIR", "Virgin Islands, U.S."},
+	{"VN", "VNM", "Vietnam"},
+	{"VU", "VUT", "Vanuatu"},
+	{"WF", "WLF", "Wallis and Futuna"},
+	{"WS", "WSM", "Samoa"},
+	{"YE", "YEM", "Yemen"},
+	{"YT", "MYT", "Mayotte"},
+	{"ZA", "ZAF", "South Africa"},
+	{"ZM", "ZMB", "Zambia"},
+	{"ZW", "ZWE", "Zimbabwe"},
+}
+
+// iso3166CountryAliases maps the lower case formal and official names of
+// countries to their alpha-2 code.
+var iso3166CountryAliases = map[string]string{
+	"principality of andorra":                              "AD",
+	"islamic republic of afghanistan":                      "AF",
+	"republic of albania":                                  "AL",
+	"republic of armenia":                                  "AM",
+	"republic of angola":                                   "AO",
+	"argentine republic":                                   "AR",
+	"republic of austria":                                  "AT",
+	"republic of azerbaijan":                               "AZ",
+	"republic of bosnia and herzegovina":                   "BA",
+	"people's republic of bangladesh":                      "BD",
+	"kingdom of belgium":                                   "BE",
+	"republic of bulgaria":                                 "BG",
+	"kingdom of bahrain":                                   "BH",
+	"republic of burundi":                                  "BI",
+	"republic of benin":                                    "BJ",
+	"bolivia, plurinational state of":                      "BO",
+	"plurinational state of bolivia":                       "BO",
+	"federative republic of brazil":                        "BR",
+	"commonwealth of the bahamas":                          "BS",
+	"kingdom of bhutan":                                    "BT",
+	"republic of botswana":                                 "BW",
+	"republic of belarus":                                  "BY",
+	"republic of the congo":                                "CG",
+	"swiss confederation":                                  "CH",
+	"republic of côte d'ivoire":                            "CI",
+	"republic of chile":                                    "CL",
+	"republic of cameroon":                                 "CM",
+	"people's republic of china":                           "CN",
+	"republic of colombia":                                 "CO",
+	"republic of costa rica":                               "CR",
+	"republic of cuba":                                     "CU",
+	"republic of cabo verde":                               "CV",
+	"republic of cyprus":                                   "CY",
+	"czech republic":                                       "CZ",
+	"federal republic of germany":                          "DE",
+	"republic of djibouti":                                 "DJ",
+	"kingdom of denmark":                                   "DK",
+	"commonwealth of dominica":                             "DM",
+	"people's democratic republic of algeria":              "DZ",
+	"republic of ecuador":                                  "EC",
+	"republic of estonia":                                  "EE",
+	"arab republic of egypt":                               "EG",
+	"the state of eritrea":                                 "ER",
+	"kingdom of spain":                                     "ES",
+	"federal democratic republic of ethiopia":              "ET",
+	"republic of finland":                                  "FI",
+	"republic of fiji":                                     "FJ",
+	"federated states of micronesia":                       "FM",
+	"french republic":                                      "FR",
+	"gabonese republic":                                    "GA",
+	"united kingdom of great britain and northern ireland": "GB",
+	"republic of ghana":                                    "GH",
+	"republic of the gambia":                               "GM",
+	"republic of guinea":                                   "GN",
+	"republic of equatorial guinea":                        "GQ",
+	"hellenic republic":                                    "GR",
+	"republic of guatemala":                                "GT",
+	"republic of guinea-bissau":                            "GW",
+	"republic of guyana":                                   "GY",
+	"hong kong special administrative region of china":     "HK",
+	"republic of honduras":                                 "HN",
+	"republic of croatia":                                  "HR",
+	"republic of haiti":                                    "HT",
+	"republic of indonesia":                                "ID",
+	"state of israel":                                      "IL",
+	"republic of india":                                    "IN",
+	"republic of iraq":                                     "IQ",
+	"iran, islamic republic of":                            "IR",
+	"islamic republic of iran":                             "IR",
+	"republic of iceland":                                  "IS",
+	"italian republic":                                     "IT",
+	"hashemite kingdom of jordan":                          "JO",
+	"republic of kenya":                                    "KE",
+	"kyrgyz republic":                                      "KG",
+	"kingdom of cambodia":                                  "KH",
+	"republic of kiribati":                                 "KI",
+	"union of the comoros":                                 "KM",
+	"korea, democratic people's republic of":               "KP",
+	"democratic people's republic of korea":                "KP",
+	"korea, republic of":                                   "KR",
+	"state of kuwait":                                      "KW",
+	"republic of kazakhstan":                               "KZ",
+	"lao people's democratic republic":                     "LA",
+	"lebanese republic":                                    "LB",
+	"principality of liechtenstein":                        "LI",
+	"democratic socialist republic of sri lanka":           "LK",
+	"republic of liberia":                                  "LR",
+	"kingdom of lesotho":                                   "LS",
+	"republic of lithuania":                                "LT",
+	"grand duchy of luxembourg":                            "LU",
+	"republic of latvia":                                   "LV",
+	"kingdom of morocco":                                   "MA",
+	"principality of monaco":                               "MC",
+	"moldova, republic of":                                 "MD",
+	"republic of moldova":                                  "MD",
+	"republic of madagascar":                               "MG",
+	"republic of the marshall islands":                     "MH",
+	"republic of north macedonia":                          "MK",
+	"republic of mali":                                     "ML",
+	"republic of myanmar":                                  "MM",
+	"macao special administrative region of china":         "MO",
+	"commonwealth of the northern mariana islands":         "MP",
+	"islamic republic of mauritania":                       "MR",
+	"republic of malta":                                    "MT",
+	"republic of mauritius":                                "MU",
+	"republic of maldives":                                 "MV",
+	"republic of malawi":                                   "MW",
+	"united mexican states":                                "MX",
+	"republic of mozambique":                               "MZ",
+	"republic of namibia":                                  "NA",
+	"republic of the niger":                                "NE",
+	"federal republic of nigeria":                          "NG",
+	"republic of nicaragua":                                "NI",
+	"kingdom of the netherlands":                           "NL",
+	"kingdom of norway":                                    "NO",
+	"federal democratic republic of nepal":                 "NP",
+	"republic of nauru":                                    "NR",
+	"sultanate of oman":                                    "OM",
+	"republic of panama":                                   "PA",
+	"republic of peru":                                     "PE",
+	"independent state of papua new guinea":                "PG",
+	"republic of the philippines":                          "PH",
+	"islamic republic of pakistan":                         "PK",
+	"republic of poland":                                   "PL",
+	"the state of palestine":                               "PS",
+	"portuguese republic":                                  "PT",
+	"republic of palau":                                    "PW",
+	"republic of paraguay":                                 "PY",
+	"state of qatar":                                       "QA",
+	"republic of serbia":                                   "RS",
+	"rwandese republic":                                    "RW",
+	"kingdom of saudi arabia":                              "SA",
+	"republic of seychelles":                               "SC",
+	"republic of the sudan":                                "SD",
+	"kingdom of sweden":                                    "SE",
+	"republic of singapore":                                "SG",
+	"republic of slovenia":                                 "SI",
+	"slovak republic":                                      "SK",
+	"republic of sierra leone":                             "SL",
+	"republic of san marino":                               "SM",
+	"republic of senegal":                                  "SN",
+	"federal republic of somalia":                          "SO",
+	"republic of suriname":                                 "SR",
+	"republic of south sudan":                              "SS",
+	"democratic republic of sao tome and principe":         "ST",
+	"republic of el salvador":                              "SV",
+	"syrian arab republic":                                 "SY",
+	"kingdom of eswatini":                                  "SZ",
+	"republic of chad":                                     "TD",
+	"togolese republic":                                    "TG",
+	"kingdom of thailand":                                  "TH",
+	"republic of tajikistan":                               "TJ",
+	"democratic republic of timor-leste":                   "TL",
+	"republic of tunisia":                                  "TN",
+	"kingdom of tonga":                                     "TO",
+	"republic of türkiye":                                  "TR",
+	"republic of trinidad and tobago":                      "TT",
+	"taiwan, province of china":                            "TW",
+	"tanzania, united republic of":                         "TZ",
+	"united republic of tanzania":                          "TZ",
+	"republic of uganda":                                   "UG",
+	"united states of america":                             "US",
+	"eastern republic of uruguay":                          "UY",
+	"republic of uzbekistan":                               "UZ",
+	"venezuela, bolivarian republic of":                    "VE",
+	"bolivarian republic of venezuela":                     "VE",
+	"british virgin islands":                               "VG",
+	"virgin islands of the united states":                  "VI",
+	"viet nam":                                             "VN",
+	"socialist republic of viet nam":                       "VN",
+	"republic of vanuatu":                                  "VU",
+	"independent state of samoa":                           "WS",
+	"republic of yemen":                                    "YE",
+	"republic of south africa":                             "ZA",
+	"republic of zambia":                                   "ZM",
+	"republic of zimbabwe":                                 "ZW",
+}
@@ -0,0 +1,112 @@
+package hl7
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FHIRCoverage represents a FHIR Coverage resource.
+type FHIRCoverage struct {
+	ResourceType string          `json:"resourceType"`
+	Status       string          `json:"status"`
+	Identifier   []Identifier    `json:"identifier,omitempty"`
+	Beneficiary  Reference       `json:"beneficiary"`
+	Period       *Period         `json:"period,omitempty"`
+	Payor        []Reference     `json:"payor,omitempty"`
+	Class        []CoverageClass `json:"class,omitempty"`
+	Order        int             `json:"order,omitempty"`
+}
+
+// CoverageClass represents a FHIR Coverage.class, e.g. the plan or group of
+// a coverage.
+type CoverageClass struct {
+	Type  CodeableConcept `json:"type"`
+	Value string          `json:"value"`
+	Name  string          `json:"name,omitempty"`
+}
+
+// Insurance is an IN1 segment.
+type Insurance struct {
+	// SetID is the position of the insurance in the coordination of
+	// benefits: 1 for the primary insurer, 2 for the secondary and so on.
+	SetID          int
+	Plan           CodedElement
+	CompanyID      string
+	CompanyName    string
+	GroupNumber    string
+	EffectiveDate  string
+	ExpirationDate string
+	PolicyNumber   string
+}
+
+// coverageClassSystem is the code system of Coverage.class types.
+const coverageClassSystem = "http://terminology.hl7.org/CodeSystem/coverage-class"
+
+// parseInsurance parses the fields of an IN1 segment. setID is used when
+// IN1-1 is missing or invalid.
+func parseInsurance(fields []string, setID int) Insurance {
+	in1 := func(n int) string { return fieldPath{Segment: "IN1", Field: n}.field(fields) }
+	if n, err := strconv.Atoi(in1(1)); err == nil && n > 0 {
+		setID = n
+	}
+	companyID, _, _ := nextToken(in1(3), '~')
+	companyName, _, _ := nextToken(in1(4), '~')
+	return Insurance{
+		SetID:          setID,
+		Plan:           parseCodedElement(in1(2)),
+		CompanyID:      unescapeHL7(component(companyID, '^', 1)),
+		CompanyName:    unescapeHL7(component(companyName, '^', 1)),
+		GroupNumber:    unescapeHL7(in1(8)),
+		EffectiveDate:  in1(12),
+		ExpirationDate: in1(13),
+		PolicyNumber:   unescapeHL7(in1(36)),
+	}
+}
+
+// convertInsurance converts an IN1 segment to a Coverage resource of the
+// patient with the given ID. Times without UTC offset are in location. A
+// coverage whose plan expired (IN1-13) before now is cancelled.
+func convertInsurance(patientID string, in1 Insurance, location *time.Location, now time.Time) (FHIRCoverage, error) {
+	coverage := FHIRCoverage{
+		ResourceType: "Coverage",
+		Status:       "active",
+		Beneficiary:  Reference{Reference: "Patient/" + patientID},
+		Order:        in1.SetID,
+	}
+	if in1.CompanyName != "" || in1.CompanyID != "" {
+		payor := Reference{Display: in1.CompanyName}
+		if in1.CompanyID != "" {
+			payor.Identifier = &Identifier{Value: in1.CompanyID}
+		}
+		coverage.Payor = []Reference{payor}
+	}
+	if in1.PolicyNumber != "" {
+		coverage.Identifier = []Identifier{{Value: in1.PolicyNumber}}
+	}
+	if in1.Plan.Code != "" {
+		coverage.Class = append(coverage.Class, newCoverageClass("plan", in1.Plan.Code, in1.Plan.Text))
+	}
+	if in1.GroupNumber != "" {
+		coverage.Class = append(coverage.Class, newCoverageClass("group", in1.GroupNumber, ""))
+	}
+
+	period, err := hl7ToFHIRPeriod(in1.EffectiveDate, in1.ExpirationDate, location)
+	if err != nil {
+		return FHIRCoverage{}, fmt.Errorf("insurance %d: %w", in1.SetID, err)
+	}
+	coverage.Period = period
+	if periodEnded(period, now) {
+		coverage.Status = "cancelled"
+	}
+	return coverage, nil
+}
+
+// newCoverageClass returns a Coverage.class of the given type.
+func newCoverageClass(classType, value, name string) CoverageClass {
+	return CoverageClass{
+		Type:  CodeableConcept{Coding: []Coding{{System: coverageClassSystem, Code: classType}}},
+		Value: value,
+		Name:  name,
+	}
+}
@@ -0,0 +1,99 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_IN1AsCoverage(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":     "hl7",
+		"outputType":    "fhir",
+		"in1AsCoverage": "true",
+		"parseMode":     "strict",
+	})
+	is.NoErr(err)
+	p.clock = func() time.Time { return time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC) }
+
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A04|123|P|2.5|\r" +
+		"PID|1||123||Smith^John||1990-01-01|male\r" +
+		"IN1|1|PPO1^Gold PPO|BCBS01|Blue Cross Blue Shield||||GRP100||||20230101|20231231|||||||||||||||||||||||POL-111\r" +
+		"IN1|2|HMO2^Basic HMO|AETNA7|Aetna||||||||||||||||||||||||||||||||POL-222"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var bundle struct {
+		Entry []struct {
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &bundle)
+	is.NoErr(err)
+	is.Equal(len(bundle.Entry), 3)
+
+	var primary, secondary FHIRCoverage
+	is.NoErr(json.Unmarshal(bundle.Entry[1].Resource, &primary))
+	is.NoErr(json.Unmarshal(bundle.Entry[2].Resource, &secondary))
+
+	is.Equal(primary.ResourceType, "Coverage")
+	is.Equal(primary.Status, "active")
+	is.Equal(primary.Order, 1)
+	is.Equal(primary.Beneficiary.Reference, "Patient/123")
+	is.Equal(primary.Payor[0].Display, "Blue Cross Blue Shield")
+	is.Equal(primary.Payor[0].Identifier.Value, "BCBS01")
+	is.Equal(primary.Identifier[0].Value, "POL-111")
+	is.Equal(*primary.Period, Period{Start: "2023-01-01", End: "2023-12-31"})
+	is.Equal(len(primary.Class), 2)
+	is.Equal(primary.Class[0].Type.Coding[0].Code, "plan")
+	is.Equal(primary.Class[0].Value, "PPO1")
+	is.Equal(primary.Class[0].Name, "Gold PPO")
+	is.Equal(primary.Class[1].Type.Coding[0].Code, "group")
+	is.Equal(primary.Class[1].Value, "GRP100")
+
+	is.Equal(secondary.Order, 2)
+	is.Equal(secondary.Payor[0].Display, "Aetna")
+	is.Equal(secondary.Identifier[0].Value, "POL-222")
+	is.Equal(secondary.Period, nil)
+
+	// the primary plan has expired since
+	p.clock = func() time.Time { return time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC) }
+	result = p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok = result[0].(sdk.SingleRecord)
+	is.True(ok)
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &bundle))
+	is.NoErr(json.Unmarshal(bundle.Entry[1].Resource, &primary))
+	is.NoErr(json.Unmarshal(bundle.Entry[2].Resource, &secondary))
+	is.Equal(primary.Status, "cancelled")
+	is.Equal(secondary.Status, "active") // no expiration date
+}
+
+func TestConvertInsurance_NoCompany(t *testing.T) {
+	is := is.New(t)
+
+	in1 := parseInsurance(splitFields("IN1|1|PLAN", nil), 1)
+	coverage, err := convertInsurance("123", in1, time.UTC, time.Now())
+	is.NoErr(err)
+	is.Equal(coverage.Payor, nil) // no empty payor
+	is.Equal(coverage.Status, "active")
+}
+
+func TestParseInsurance_SetIDFallback(t *testing.T) {
+	is := is.New(t)
+
+	in1 := parseInsurance(splitFields("IN1||PLAN|CO|Company", nil), 3)
+	is.Equal(in1.SetID, 3) // position is used without IN1-1
+	is.Equal(in1.CompanyName, "Company")
+}
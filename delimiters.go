@@ -0,0 +1,67 @@
+package hl7
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// standardEncodingCharacters are the encoding characters (MSH-2) the parser
+// expects: component, repetition, escape and subcomponent separator.
+const standardEncodingCharacters = `^~\&`
+
+// standardEscapes are the escape sequences of the standard delimiters, used
+// when a delimiter appears as data in a message using other delimiters.
+var standardEscapes = map[byte]string{
+	'|':  `\F\`,
+	'^':  `\S\`,
+	'~':  `\R\`,
+	'\\': `\E\`,
+	'&':  `\T\`,
+}
+
+// normalizeDelimiters rewrites a message declaring its own delimiters, i.e. a
+// field separator (MSH-1) other than | or encoding characters (MSH-2) other
+// than ^~\&, to the standard delimiters. Standard delimiters appearing as data
+// are escaped, so the message keeps its meaning. Delimiters are read as
+// runes, so multi-byte UTF-8 characters may be used as well. Messages already
+// using the standard delimiters, and input that is not an HL7 v2 message, are
+// returned unchanged.
+func normalizeDelimiters(message string) string {
+	if len(message) < 4 || !strings.HasPrefix(message, "MSH") {
+		return message
+	}
+	fieldSep, size := utf8.DecodeRuneInString(message[3:])
+	if fieldSep == utf8.RuneError {
+		return message
+	}
+	header := message[3+size:]
+	encoding, _, _ := strings.Cut(header, string(fieldSep))
+	if fieldSep == '|' && encoding == standardEncodingCharacters {
+		return message
+	}
+
+	delimiters := map[rune]byte{fieldSep: '|'}
+	for i, r := range []rune(encoding) {
+		if i >= len(standardEncodingCharacters) {
+			break
+		}
+		delimiters[r] = standardEncodingCharacters[i]
+	}
+
+	var b strings.Builder
+	b.Grow(len(message))
+	b.WriteString("MSH|" + standardEncodingCharacters)
+	for rest := header[len(encoding):]; rest != ""; {
+		r, size := utf8.DecodeRuneInString(rest)
+		if d, ok := delimiters[r]; ok && r != utf8.RuneError {
+			b.WriteByte(d)
+		} else if esc, ok := standardEscapes[rest[0]]; ok {
+			b.WriteString(esc)
+		} else {
+			// invalid UTF-8 is copied byte by byte
+			b.WriteString(rest[:size])
+		}
+		rest = rest[size:]
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package hl7
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestParseHL7Message_FieldSeparator(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH#^~\\&#LAB#FACILITY#HL7_PARSER#FACILITY#20230815120000##ADT^A01#123#P#2.5\r" +
+		"PID#1##123^^^HOSP^MR~456^^^HOSP^PI##Smith|Jones^John##19800101#M###1 Main St^Springfield"
+	msg, err := parseHL7Message(hl7String, parseOptions{strict: true})
+	is.NoErr(err)
+	is.Equal(msg.MSH.SendingApplication, "LAB")
+	is.Equal(msg.MSH.MessageType, "ADT^A01")
+	is.Equal(msg.PID.ID, "123")
+	is.Equal(len(msg.PID.Identifiers), 2)
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.Name[0].Family, []string{"Smith|Jones"}) // a | is data with a # separator
+	is.Equal(patient.Name[0].Given, []string{"John"})
+	is.Equal(patient.BirthDate, "1980-01-01")
+	is.Equal(patient.Address[0].City, "Springfield")
+
+	segments, err := p.Inspect(hl7String)
+	is.NoErr(err)
+	is.Equal(segments, []string{"MSH:12", "PID:11"})
+}
+
+func TestNormalizeDelimiters(t *testing.T) {
+	is := is.New(t)
+
+	standard := "MSH|^~\\&|LAB\rPID|1||123^^^^MR||Smith^John"
+	is.Equal(normalizeDelimiters(standard), standard)
+
+	custom := "MSH*$%!@*LAB\rPID*1**123$$$$MR%456**O^Brien$John@Jr*a|b!F!"
+	is.Equal(normalizeDelimiters(custom), "MSH|^~\\&|LAB\rPID|1||123^^^^MR~456||O\\S\\Brien^John&Jr|a\\F\\b\\F\\")
+
+	is.Equal(normalizeDelimiters("not hl7"), "not hl7")
+}
+
+func TestParseHL7Message_NonASCIIDelimiters(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	// § (2 bytes) separates components and ¦ (2 bytes) fields; ü is data
+	hl7String := "MSH¦§~\\&¦LAB¦FACILITY¦HL7_PARSER¦FACILITY¦20230815120000¦¦ADT§A01¦123¦P¦2.5\r" +
+		"PID¦1¦¦123§§§HOSP§MR¦¦Müller^Smith§Jürgen¦¦19800101¦M¦¦¦1 Main St§Zürich"
+	is.Equal(normalizeDelimiters(hl7String),
+		"MSH|^~\\&|LAB|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5\r"+
+			"PID|1||123^^^HOSP^MR||Müller\\S\\Smith^Jürgen||19800101|M|||1 Main St^Zürich")
+
+	msg, err := parseHL7Message(hl7String, parseOptions{strict: true})
+	is.NoErr(err)
+	is.Equal(msg.MSH.MessageType, "ADT^A01")
+	is.Equal(msg.PID.ID, "123")
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.Name[0].Family, []string{"Müller^Smith"}) // a ^ is data with a § separator
+	is.Equal(patient.Name[0].Given, []string{"Jürgen"})
+	is.Equal(patient.Address[0].City, "Zürich")
+
+	// invalid UTF-8 is kept as is
+	is.Equal(normalizeDelimiters("MSH¦§~\\&¦\xff§x"), "MSH|^~\\&|\xff^x")
+}
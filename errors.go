@@ -0,0 +1,179 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+)
+
+// Error classes reported in ConversionError.
+const (
+	errorClassParse      = "parse"
+	errorClassConversion = "conversion"
+	errorClassMarshal    = "marshal"
+)
+
+// Metadata keys attached to ConversionError when includeErrorMetadata is
+// enabled.
+const (
+	metadataErrorClass   = "hl7.error.class"
+	metadataErrorInput   = "hl7.error.input"
+	metadataErrorSegment = "hl7.error.segment"
+	metadataErrorField   = "hl7.error.field"
+)
+
+// FieldError reports a problem with a specific segment or field of an HL7 v2
+// message.
+type FieldError struct {
+	Segment string
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors aggregates the validation problems of an HL7 v2 message
+// when aggregateErrors is enabled.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d validation errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the aggregated errors, so errors.As finds the first
+// FieldError.
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ConversionError is returned in an ErrorRecord when a record can not be
+// converted. It carries the context needed to triage the failed record.
+type ConversionError struct {
+	Position  opencdc.Position
+	InputType string
+	// Segment and Field identify the part of the input that failed, when known.
+	Segment string
+	Field   string
+	// Class classifies the failure as a parse, conversion or marshal error.
+	Class string
+	// Metadata holds the error classification and the failed raw input. It is
+	// only populated when includeErrorMetadata is enabled.
+	Metadata map[string]string
+	Err      error
+}
+
+func (e *ConversionError) Error() string {
+	details := []string{
+		fmt.Sprintf("position: %s", e.Position),
+		fmt.Sprintf("inputType: %s", e.InputType),
+	}
+	if e.Field != "" {
+		details = append(details, fmt.Sprintf("field: %s", e.Field))
+	} else if e.Segment != "" {
+		details = append(details, fmt.Sprintf("segment: %s", e.Segment))
+	}
+	return fmt.Sprintf("%s error (%s): %v", e.Class, strings.Join(details, ", "), e.Err)
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// errorModeOperationOutcome is the ErrorMode returning errors in-band.
+const errorModeOperationOutcome = "operationOutcome"
+
+// operationOutcomeCodes maps error classes to FHIR issue type codes.
+var operationOutcomeCodes = map[string]string{
+	errorClassParse:      "structure",
+	errorClassConversion: "processing",
+	errorClassMarshal:    "exception",
+}
+
+// FHIROperationOutcome represents a FHIR OperationOutcome resource.
+type FHIROperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+// OperationOutcomeIssue is a single issue of a FHIR OperationOutcome.
+type OperationOutcomeIssue struct {
+	Severity    string `json:"severity"`
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+// errorRecord wraps err with the context of the record that failed. In the
+// operationOutcome error mode the error is returned in-band, as a record
+// holding a FHIR OperationOutcome.
+func (p *Processor) errorRecord(record opencdc.Record, class string, err error) sdk.ProcessedRecord {
+	convErr := &ConversionError{
+		Position:  record.Position,
+		InputType: p.config.InputType,
+		Class:     class,
+		Err:       err,
+	}
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		convErr.Segment = fieldErr.Segment
+		convErr.Field = fieldErr.Field
+	}
+
+	if p.config.IncludeErrorMetadata {
+		convErr.Metadata = map[string]string{
+			metadataErrorClass: class,
+		}
+		if record.Payload.After != nil {
+			convErr.Metadata[metadataErrorInput] = string(record.Payload.After.Bytes())
+		}
+		if convErr.Segment != "" {
+			convErr.Metadata[metadataErrorSegment] = convErr.Segment
+		}
+		if convErr.Field != "" {
+			convErr.Metadata[metadataErrorField] = convErr.Field
+		}
+	}
+
+	if p.config.ErrorMode == errorModeOperationOutcome {
+		return p.operationOutcomeRecord(record, convErr)
+	}
+	return sdk.ErrorRecord{Error: convErr}
+}
+
+// operationOutcomeRecord replaces the payload of record with an
+// OperationOutcome describing convErr. The error metadata, if any, is added
+// to the record metadata.
+func (p *Processor) operationOutcomeRecord(record opencdc.Record, convErr *ConversionError) sdk.ProcessedRecord {
+	outcome, err := p.marshalJSON(FHIROperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue: []OperationOutcomeIssue{{
+			Severity:    "error",
+			Code:        operationOutcomeCodes[convErr.Class],
+			Diagnostics: convErr.Error(),
+		}},
+	})
+	if err != nil {
+		return sdk.ErrorRecord{Error: fmt.Errorf("failed to marshal OperationOutcome: %w (%w)", err, convErr)}
+	}
+
+	if len(convErr.Metadata) > 0 && record.Metadata == nil {
+		record.Metadata = opencdc.Metadata{}
+	}
+	for k, v := range convErr.Metadata {
+		record.Metadata[k] = v
+	}
+	record.Payload.After = opencdc.RawData(outcome)
+	return sdk.SingleRecord(record)
+}
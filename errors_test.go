@@ -0,0 +1,175 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_ConversionError(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":            "hl7",
+		"outputType":           "fhir",
+		"includeErrorMetadata": "true",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||||Smith^John||1990-01-01|male"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Position: opencdc.Position("pos-42"),
+		Payload:  opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	is.Equal(len(result), 1)
+
+	errRecord, ok := result[0].(sdk.ErrorRecord)
+	is.True(ok)                                                  // should be an error record
+	is.True(strings.Contains(errRecord.Error.Error(), "pos-42")) // error should include the record position
+	is.True(strings.Contains(errRecord.Error.Error(), "PID-3"))  // error should include the failing field
+
+	var convErr *ConversionError
+	is.True(errors.As(errRecord.Error, &convErr))
+	is.Equal(convErr.Class, errorClassParse)
+	is.Equal(convErr.Segment, "PID")
+	is.Equal(convErr.Metadata[metadataErrorClass], errorClassParse)
+	is.Equal(convErr.Metadata[metadataErrorField], "PID-3")
+	is.Equal(convErr.Metadata[metadataErrorInput], input)
+}
+
+func TestProcessor_Process_ConversionErrorWithoutMetadata(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+	})
+	is.NoErr(err)
+
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Position: opencdc.Position("pos-7"),
+		Payload:  opencdc.Change{After: opencdc.RawData(`{"invalid": json`)},
+	}})
+	errRecord, ok := result[0].(sdk.ErrorRecord)
+	is.True(ok) // should be an error record
+	is.True(strings.Contains(errRecord.Error.Error(), "pos-7"))
+
+	var convErr *ConversionError
+	is.True(errors.As(errRecord.Error, &convErr))
+	is.Equal(convErr.Metadata, nil) // metadata is only attached when enabled
+}
+
+func TestProcessor_Process_EmptyPayload(t *testing.T) {
+	is := is.New(t)
+	for _, inputType := range []string{"fhir", "hl7", "hl7v3"} {
+		outputType := "fhir"
+		if inputType == "fhir" {
+			outputType = "hl7"
+		}
+		p := NewProcessor()
+		err := p.Configure(context.Background(), map[string]string{
+			"inputType":  inputType,
+			"outputType": outputType,
+		})
+		is.NoErr(err)
+
+		result := p.Process(context.Background(), []opencdc.Record{
+			{Position: opencdc.Position("no-payload")},
+			{Payload: opencdc.Change{After: opencdc.RawData("")}},
+			{Payload: opencdc.Change{After: opencdc.RawData(" \r\n")}},
+		})
+		is.Equal(len(result), 3)
+		for _, rec := range result {
+			errRecord, ok := rec.(sdk.ErrorRecord)
+			is.True(ok) // should be an error record
+			is.True(strings.Contains(errRecord.Error.Error(), "empty payload"))
+		}
+	}
+}
+
+func TestProcessor_Process_AggregateErrors(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":       "hl7",
+		"outputType":      "fhir",
+		"parseMode":       "strict",
+		"aggregateErrors": "true",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\rPID|1||||||42|M\rZZ1|custom"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	errRecord, ok := result[0].(sdk.ErrorRecord)
+	is.True(ok) // should be an error record
+
+	var validationErrs ValidationErrors
+	is.True(errors.As(errRecord.Error, &validationErrs))
+	fields := make([]string, len(validationErrs))
+	for i, fieldErr := range validationErrs {
+		fields[i] = fieldErr.Segment + " " + fieldErr.Field
+	}
+	is.Equal(fields, []string{"ZZ1 ", "PID PID-3", "PID PID-5", "PID PID-7"})
+
+	var convErr *ConversionError
+	is.True(errors.As(errRecord.Error, &convErr))
+	is.Equal(convErr.Segment, "ZZ1") // the first problem is reported as the failing segment
+
+	// without aggregation the first problem fails the message
+	err = p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+		"parseMode":  "strict",
+	})
+	is.NoErr(err)
+	result = p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	errRecord, ok = result[0].(sdk.ErrorRecord)
+	is.True(ok)
+	is.True(!errors.As(errRecord.Error, &validationErrs))
+	is.True(strings.Contains(errRecord.Error.Error(), "unknown segment ZZ1"))
+}
+
+func TestProcessor_Process_OperationOutcome(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+		"errorMode":  "operationOutcome",
+	})
+	is.NoErr(err)
+
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Position: opencdc.Position("pos-7"),
+		Payload:  opencdc.Change{After: opencdc.RawData(`{"invalid": json`)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok) // the error travels in-band
+
+	var outcome struct {
+		ResourceType string `json:"resourceType"`
+		Issue        []struct {
+			Severity    string `json:"severity"`
+			Code        string `json:"code"`
+			Diagnostics string `json:"diagnostics"`
+		} `json:"issue"`
+	}
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &outcome)
+	is.NoErr(err)
+	is.Equal(outcome.ResourceType, "OperationOutcome")
+	is.Equal(len(outcome.Issue), 1)
+	is.Equal(outcome.Issue[0].Severity, "error")
+	is.Equal(outcome.Issue[0].Code, "structure")
+	is.True(strings.Contains(outcome.Issue[0].Diagnostics, "failed to parse FHIR JSON"))
+	is.True(strings.Contains(outcome.Issue[0].Diagnostics, "pos-7"))
+}
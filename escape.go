@@ -0,0 +1,37 @@
+package hl7
+
+import "strings"
+
+// HL7 v2 escape sequences of the delimiters, for the default encoding
+// characters ^~\&.
+var (
+	hl7Escaper   = strings.NewReplacer(`\`, `\E\`, `|`, `\F\`, `^`, `\S\`, `&`, `\T\`, `~`, `\R\`)
+	hl7Unescaper = strings.NewReplacer(`\E\`, `\`, `\F\`, `|`, `\S\`, `^`, `\T\`, `&`, `\R\`, `~`)
+)
+
+// escapeHL7 escapes the delimiters in a value written to an HL7 v2 message.
+func escapeHL7(v string) string {
+	if !strings.ContainsAny(v, `\|^&~`) {
+		return v
+	}
+	return hl7Escaper.Replace(v)
+}
+
+// unescapeHL7 replaces the delimiter escape sequences in a value read from
+// an HL7 v2 message. Other escape sequences are kept as they are.
+func unescapeHL7(v string) string {
+	if strings.IndexByte(v, '\\') < 0 {
+		return v
+	}
+	return hl7Unescaper.Replace(v)
+}
+
+// joinComponents escapes the values and joins them as the components of a
+// field.
+func joinComponents(values ...string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = escapeHL7(v)
+	}
+	return strings.Join(escaped, "^")
+}
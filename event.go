@@ -0,0 +1,41 @@
+package hl7
+
+import "time"
+
+// Metadata keys holding the event of HL7 v2 messages with an EVN segment.
+const (
+	metadataEventType         = "hl7.event.type"
+	metadataEventRecordedTime = "hl7.event.recordedDateTime"
+)
+
+// Event is an EVN segment, describing the trigger event of an ADT message.
+type Event struct {
+	// TypeCode is the event type code (EVN-1), e.g. A01.
+	TypeCode string
+	// RecordedDateTime is the time the event was recorded (EVN-2).
+	RecordedDateTime string
+}
+
+// parseEvent parses the fields of an EVN segment.
+func parseEvent(fields []string) Event {
+	return Event{
+		TypeCode:         fieldPath{Segment: "EVN", Field: 1}.value(fields),
+		RecordedDateTime: fieldPath{Segment: "EVN", Field: 2, Component: 1}.value(fields),
+	}
+}
+
+// eventMetadata returns the metadata describing the event of a message: its
+// type and, when it is a valid HL7 timestamp, the recorded time as a FHIR
+// dateTime, in location when it has no UTC offset.
+func eventMetadata(evn Event, location *time.Location) map[string]string {
+	metadata := make(map[string]string)
+	if evn.TypeCode != "" {
+		metadata[metadataEventType] = evn.TypeCode
+	}
+	if evn.RecordedDateTime != "" {
+		if recorded, err := hl7ToFHIRTimestamp(evn.RecordedDateTime, location); err == nil {
+			metadata[metadataEventRecordedTime] = recorded
+		}
+	}
+	return metadata
+}
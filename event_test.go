@@ -0,0 +1,55 @@
+package hl7
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_Event(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	input := "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|MSG00001|P|2.5\r" +
+		"EVN|A01|20230815120000\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M"
+
+	msg, err := parseHL7Message(input, parseOptions{strict: true})
+	is.NoErr(err)
+	is.Equal(msg.EVN, &Event{TypeCode: "A01", RecordedDateTime: "20230815120000"})
+
+	p := NewProcessor()
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	}))
+	result := p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	is.Equal(rec.Metadata[metadataEventType], "A01")
+	is.Equal(rec.Metadata[metadataEventRecordedTime], "2023-08-15T12:00:00Z")
+
+	// messages without EVN carry no event metadata
+	input = "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|MSG00001|P|2.5\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M"
+	result = p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	rec, ok = result[0].(sdk.SingleRecord)
+	is.True(ok)
+	_, ok = rec.Metadata[metadataEventType]
+	is.True(!ok)
+
+	// a record with several messages carries the event of the first one
+	input = "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|MSG00001|P|2.5\r" +
+		"EVN|A01|20230815120000\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M\r" +
+		"MSH|^~\\&|APP|FAC|||20230816090000||ADT^A08|MSG00002|P|2.5\r" +
+		"EVN|A08|20230816090000\r" +
+		"PID|1||456^^^^MR||Roe^Jane||19850101|F"
+	result = p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	rec, ok = result[0].(sdk.SingleRecord)
+	is.True(ok)
+	is.Equal(rec.Metadata[metadataEventType], "A01")
+	is.Equal(rec.Metadata[metadataEventRecordedTime], "2023-08-15T12:00:00Z")
+}
@@ -0,0 +1,103 @@
+package hl7
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// fhirFields maps the FHIR fields fhirDefaults may set to the values they
+// point to in a patient. Address fields yield the field of every address.
+var fhirFields = map[string]func(*FHIRPatient) []*string{
+	"gender":    func(p *FHIRPatient) []*string { return []*string{&p.Gender} },
+	"birthDate": func(p *FHIRPatient) []*string { return []*string{&p.BirthDate} },
+	"address.use": func(p *FHIRPatient) []*string {
+		return addressFields(p, func(a *Address) *string { return &a.Use })
+	},
+	"address.city": func(p *FHIRPatient) []*string {
+		return addressFields(p, func(a *Address) *string { return &a.City })
+	},
+	"address.state": func(p *FHIRPatient) []*string {
+		return addressFields(p, func(a *Address) *string { return &a.State })
+	},
+	"address.postalCode": func(p *FHIRPatient) []*string {
+		return addressFields(p, func(a *Address) *string { return &a.PostalCode })
+	},
+	"address.country": func(p *FHIRPatient) []*string {
+		return addressFields(p, func(a *Address) *string { return &a.Country })
+	},
+}
+
+// fhirDefaultChecks validates the default values of the fhirDefaults fields
+// restricted to a FHIR value set or format.
+var fhirDefaultChecks = map[string]func(string) error{
+	"gender": func(v string) error {
+		if _, ok := fhirGenders[v]; !ok {
+			return fmt.Errorf("%q is not a FHIR gender (male, female, other, unknown)", v)
+		}
+		return nil
+	},
+	"birthDate": func(v string) error {
+		if _, err := parseFHIRTime(v); err != nil || strings.Contains(v, "T") {
+			return fmt.Errorf("%q is not a FHIR date", v)
+		}
+		return nil
+	},
+	"address.use": func(v string) error {
+		if hl7AddressType(Address{Use: v}) == "" {
+			return fmt.Errorf("%q is not a FHIR address use (home, work, temp, old, billing)", v)
+		}
+		return nil
+	},
+}
+
+// addressFields returns the field of every address of the patient.
+func addressFields(p *FHIRPatient, field func(*Address) *string) []*string {
+	values := make([]*string, len(p.Address))
+	for i := range p.Address {
+		values[i] = field(&p.Address[i])
+	}
+	return values
+}
+
+// parseFHIRDefaults parses the fhirDefaults JSON object mapping FHIR fields
+// to the value they take when empty. Values of fields with a value set or
+// format are validated.
+func parseFHIRDefaults(raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var defaults map[string]string
+	if err := json.Unmarshal([]byte(raw), &defaults); err != nil {
+		return nil, fmt.Errorf("failed to parse FHIR defaults: %w", err)
+	}
+	for name := range defaults {
+		if _, ok := fhirFields[name]; !ok {
+			supported := make([]string, 0, len(fhirFields))
+			for field := range fhirFields {
+				supported = append(supported, field)
+			}
+			slices.Sort(supported)
+			return nil, fmt.Errorf("unknown field %q in FHIR defaults, supported fields: %s", name, strings.Join(supported, ", "))
+		}
+		if check, ok := fhirDefaultChecks[name]; ok {
+			if err := check(defaults[name]); err != nil {
+				return nil, fmt.Errorf("FHIR defaults %q: %w", name, err)
+			}
+		}
+	}
+	return defaults, nil
+}
+
+// applyFHIRDefaults sets the empty fields of a converted patient that have a
+// configured default value.
+func (p *Processor) applyFHIRDefaults(patient *FHIRPatient) {
+	for name, value := range p.fhirDefaults {
+		for _, field := range fhirFields[name](patient) {
+			if *field == "" {
+				*field = value
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package hl7
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestConvertHL7ToFHIR_FHIRDefaults(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	is.NoErr(p.Configure(context.Background(), map[string]string{
+		"inputType":    "hl7",
+		"outputType":   "fhir",
+		"fhirDefaults": `{"address.country": "US", "gender": "unknown"}`,
+	}))
+
+	hl7String := "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5\r" +
+		"PID|1||123^^^^MR||Smith^John||19800101||||1 Main St^Springfield^IL^62701~2 Side St^Paris^^75001^FR"
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.Gender, "unknown")        // empty PID-8
+	is.Equal(patient.Address[0].Country, "US") // empty country
+	is.Equal(patient.Address[1].Country, "FR") // mapped value kept
+
+	// HL7v3 input
+	patient, err = p.convertHL7V3ToFHIR(HL7V3Patient{
+		ID:      "pat-1",
+		Name:    []HL7V3Name{{Given: "Jane", Family: "Doe"}},
+		Gender:  HL7V3Gender{Code: "F"},
+		Address: []HL7V3Address{{City: "Springfield"}},
+	})
+	is.NoErr(err)
+	is.Equal(patient.Gender, "female")
+	is.Equal(patient.Address[0].Country, "US") // no country
+
+	patient, err = p.convertHL7V3ToFHIR(HL7V3Patient{
+		ID:   "pat-2",
+		Name: []HL7V3Name{{Given: "Jane", Family: "Doe"}},
+	})
+	is.NoErr(err)
+	is.Equal(patient.Gender, "unknown") // no administrativeGenderCode
+
+	for _, invalid := range []string{
+		`{"name.family": "Unknown"}`, // unsupported field
+		`{"gender": "U"}`,            // HL7 v2 code, not a FHIR gender
+		`{"birthDate": "01/01/1980"}`,
+		`{"address.use": "H"}`,
+	} {
+		_, err = parseFHIRDefaults(invalid)
+		is.True(err != nil)
+	}
+	defaults, err := parseFHIRDefaults(`{"birthDate": "1980-01", "address.use": "home"}`)
+	is.NoErr(err)
+	is.Equal(defaults["birthDate"], "1980-01")
+}
@@ -0,0 +1,54 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	sdk "github.com/conduitio/conduit-processor-sdk"
+)
+
+// onInvalidFHIRTypeIgnore is the OnInvalidFHIRType mode dropping FHIR fields
+// of the wrong JSON type.
+const onInvalidFHIRTypeIgnore = "ignore"
+
+// unmarshalFHIR parses a FHIR resource. A field of the wrong JSON type, such
+// as a numeric gender, is reported as a FieldError naming the field, or
+// dropped with a warning in the ignore mode of OnInvalidFHIRType.
+func (p *Processor) unmarshalFHIR(ctx context.Context, data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field == "" {
+		return err
+	}
+
+	// the rest of the resource is decoded despite the mismatch
+	fieldErr := &FieldError{
+		Field:   typeErr.Field,
+		Message: fmt.Sprintf("invalid FHIR field %q: expected %s, got %s", typeErr.Field, jsonTypeName(typeErr), typeErr.Value),
+	}
+	if p.config.OnInvalidFHIRType == onInvalidFHIRTypeIgnore {
+		sdk.Logger(ctx).Warn().Str("field", typeErr.Field).Msg(fieldErr.Message + ", field dropped")
+		return nil
+	}
+	return fieldErr
+}
+
+// jsonTypeName returns the JSON type expected by the Go type of an
+// UnmarshalTypeError.
+func jsonTypeName(typeErr *json.UnmarshalTypeError) string {
+	switch typeErr.Type.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	default:
+		return "a number"
+	}
+}
@@ -0,0 +1,48 @@
+package hl7
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_InvalidFHIRType(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	input := `{"resourceType":"Patient","id":"123","name":[{"family":["Doe"],"given":["John"]}],"birthDate":"1980-01-01","gender":1}`
+
+	p := NewProcessor()
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+	}))
+	result := p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	errRecord, ok := result[0].(sdk.ErrorRecord)
+	is.True(ok)
+	var convErr *ConversionError
+	is.True(errors.As(errRecord.Error, &convErr))
+	is.Equal(convErr.Field, "gender")
+	is.Equal(convErr.Err.Error(), `failed to parse FHIR JSON: invalid FHIR field "gender": expected a string, got number`)
+
+	// nested fields are named by their path
+	var patient FHIRPatient
+	err := p.(*Processor).unmarshalFHIR(ctx, []byte(`{"name":[{"family":"Doe"}]}`), &patient)
+	is.Equal(err.Error(), `invalid FHIR field "name.0.family": expected an array, got string`)
+
+	// the ignore mode drops the field
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":         "fhir",
+		"outputType":        "hl7",
+		"onInvalidFHIRType": "ignore",
+	}))
+	result = p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	pidFields := splitHL7Field(splitHL7Message(rec.Payload.After.(opencdc.StructuredData)["hl7"].(string))[1])
+	is.Equal(pidFields[5], "Doe^John")
+	is.Equal(pidFields[8], "") // gender
+}
@@ -0,0 +1,132 @@
+package hl7
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Field length validation modes.
+const (
+	fieldLengthsNone     = "none"
+	fieldLengthsTruncate = "truncate"
+	fieldLengthsError    = "error"
+)
+
+// Maximum length of a repetition of the fields read by the parser, per HL7
+// version.
+var (
+	fieldMaxLengthsV23 = map[string]int{
+		"MSH-10": 20,
+		"PID-3":  20,
+		"PID-5":  48,
+		"PID-7":  26,
+		"PID-8":  1,
+		"PID-11": 106,
+		"PID-18": 20,
+		"NK1-2":  48,
+	}
+	fieldMaxLengthsV24 = map[string]int{
+		"MSH-10": 20,
+		"PID-3":  250,
+		"PID-5":  250,
+		"PID-7":  26,
+		"PID-8":  1,
+		"PID-11": 250,
+		"PID-18": 250,
+		"NK1-2":  250,
+	}
+)
+
+// fieldMaxLengths maps HL7 versions (MSH-12) to their maximum field lengths.
+var fieldMaxLengths = map[string]map[string]int{
+	"2.3":   fieldMaxLengthsV23,
+	"2.3.1": fieldMaxLengthsV23,
+	"2.4":   fieldMaxLengthsV24,
+	"2.5":   fieldMaxLengthsV24,
+	"2.5.1": fieldMaxLengthsV24,
+}
+
+// checkFieldLengths checks the fields of a segment against the maximum
+// lengths of the HL7 version. Depending on mode, over-long repetitions are
+// truncated in place, reported as warnings, or rejected with one error per
+// over-long field. An MSH segment of a version without known lengths yields
+// a warning, the fields of its message are not checked.
+func checkFieldLengths(fields []string, version, mode string) ([]ParseWarning, []*FieldError) {
+	maxLengths, ok := fieldMaxLengths[version]
+	if !ok {
+		if fields[0] != "MSH" {
+			return nil, nil
+		}
+		return []ParseWarning{{
+			Segment: "MSH",
+			Field:   "MSH-12",
+			Message: fmt.Sprintf("no maximum field lengths known for HL7 %q, field lengths not checked", version),
+		}}, nil
+	}
+
+	var warnings []ParseWarning
+	var errs []*FieldError
+	for i := 1; i < len(fields); i++ {
+		num := i
+		if fields[0] == "MSH" {
+			// MSH-1 is the field separator itself
+			num++
+		}
+		name := fmt.Sprintf("%s-%d", fields[0], num)
+		maxLength, ok := maxLengths[name]
+		if !ok {
+			continue
+		}
+
+		repetitions := strings.Split(fields[i], "~")
+		var truncated bool
+		for j, rep := range repetitions {
+			cut, over := truncateEscaped(rep, maxLength)
+			if !over {
+				continue
+			}
+			if mode == fieldLengthsError {
+				errs = append(errs, &FieldError{
+					Segment: fields[0],
+					Field:   name,
+					Message: fmt.Sprintf("%s exceeds the maximum length of %d for HL7 %s", name, maxLength, version),
+				})
+				break
+			}
+			repetitions[j] = cut
+			truncated = true
+		}
+		if truncated {
+			fields[i] = strings.Join(repetitions, "~")
+			warnings = append(warnings, ParseWarning{
+				Segment: fields[0],
+				Field:   name,
+				Message: fmt.Sprintf("truncated to the maximum length of %d for HL7 %s", maxLength, version),
+			})
+		}
+	}
+	return warnings, errs
+}
+
+// truncateEscaped cuts an escaped value to at most n characters of the
+// unescaped value, counting an escape sequence such as \S\ as the one
+// character it stands for, so that sequences are never cut. It reports
+// whether the value was longer.
+func truncateEscaped(v string, n int) (string, bool) {
+	var count int
+	for i := 0; i < len(v); count++ {
+		if count == n {
+			return v[:i], true
+		}
+		if v[i] == '\\' {
+			if end := strings.IndexByte(v[i+1:], '\\'); end >= 0 {
+				i += end + 2
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(v[i:])
+		i += size
+	}
+	return v, false
+}
@@ -0,0 +1,48 @@
+package hl7
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestParseHL7Message_ValidateFieldLengths(t *testing.T) {
+	is := is.New(t)
+
+	longName := strings.Repeat("A", 60)
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.3|\rPID|1||123||" + longName + "^John||19900101|M"
+
+	// PID-5 is limited to 48 characters in HL7 2.3
+	msg, err := parseHL7Message(hl7String, parseOptions{fieldLengths: fieldLengthsTruncate})
+	is.NoErr(err)
+	is.Equal(msg.PID.LastName, longName[:48])
+	is.Equal(msg.PID.FirstName, "") // cut off with the rest of the field
+	is.Equal(len(msg.Warnings), 1)
+	is.Equal(msg.Warnings[0].Field, "PID-5")
+
+	_, err = parseHL7Message(hl7String, parseOptions{fieldLengths: fieldLengthsError})
+	var fieldErr *FieldError
+	is.True(errors.As(err, &fieldErr))
+	is.Equal(fieldErr.Field, "PID-5")
+
+	// HL7 2.5 allows 250 characters
+	msg, err = parseHL7Message(strings.Replace(hl7String, "|2.3|", "|2.5|", 1), parseOptions{fieldLengths: fieldLengthsError})
+	is.NoErr(err)
+	is.Equal(msg.PID.LastName, longName)
+
+	// escape sequences count as one character and are not cut
+	escapedName := strings.Repeat("A", 47) + "\\T\\B"
+	msg, err = parseHL7Message(strings.Replace(hl7String, longName, escapedName, 1), parseOptions{fieldLengths: fieldLengthsTruncate})
+	is.NoErr(err)
+	is.Equal(msg.PID.LastName, strings.Repeat("A", 47)+"&")
+	is.Equal(len(msg.Warnings), 1)
+
+	// versions without known lengths are not checked, with a warning
+	msg, err = parseHL7Message(strings.Replace(hl7String, "|2.3|", "|2.9|", 1), parseOptions{fieldLengths: fieldLengthsError})
+	is.NoErr(err)
+	is.Equal(msg.PID.LastName, longName)
+	is.Equal(len(msg.Warnings), 1)
+	is.Equal(msg.Warnings[0].Field, "MSH-12")
+}
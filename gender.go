@@ -0,0 +1,123 @@
+package hl7
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// hl7Genders maps HL7 v2 administrative sex codes (table 0001) to FHIR
+// administrative genders. The ambiguous (A) and not applicable (N) codes have
+// no FHIR counterpart and become other.
+var hl7Genders = map[string]string{
+	"M": "male",
+	"F": "female",
+	"O": "other",
+	"A": "other",
+	"N": "other",
+	"U": "unknown",
+}
+
+// fhirGenders maps FHIR administrative genders back to HL7 v2 administrative
+// sex codes, so that the codes of hl7Genders other than A and N round-trip.
+var fhirGenders = map[string]string{
+	"male":    "M",
+	"female":  "F",
+	"other":   "O",
+	"unknown": "U",
+}
+
+// parseGenderMap parses the genderMap JSON object mapping site specific
+// gender codes to FHIR administrative genders. Codes are matched case
+// insensitively.
+func parseGenderMap(raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var genders map[string]string
+	if err := json.Unmarshal([]byte(raw), &genders); err != nil {
+		return nil, fmt.Errorf("failed to parse gender map: %w", err)
+	}
+	genderMap := make(map[string]string, len(genders))
+	for code, gender := range genders {
+		if _, ok := fhirGenders[gender]; !ok {
+			return nil, fmt.Errorf("gender map %q: %q is not a FHIR gender (male, female, other, unknown)", code, gender)
+		}
+		genderMap[strings.ToUpper(code)] = gender
+	}
+	return genderMap, nil
+}
+
+// customGender returns the FHIR gender the genderMap option maps code to.
+func (p *Processor) customGender(code string) (string, bool) {
+	gender, ok := p.genderMap[strings.ToUpper(code)]
+	return gender, ok
+}
+
+// hl7ToFHIRGender converts an HL7 v2 administrative sex code, the genderMap
+// option taking precedence over table 0001. A FHIR gender sent in place of
+// the code is kept; other codes become unknown, an empty code stays empty.
+func (p *Processor) hl7ToFHIRGender(sex string) string {
+	if gender, ok := p.customGender(sex); ok {
+		return gender
+	}
+	if gender, ok := hl7Genders[strings.ToUpper(sex)]; ok {
+		return gender
+	}
+	if _, ok := fhirGenders[strings.ToLower(sex)]; ok {
+		return strings.ToLower(sex)
+	}
+	if sex == "" {
+		return ""
+	}
+	return "unknown"
+}
+
+// dataAbsentReasonURL identifies the FHIR data-absent-reason extension.
+const dataAbsentReasonURL = "http://hl7.org/fhir/StructureDefinition/data-absent-reason"
+
+// dataAbsentGenderEmpty is the DataAbsentGender mode leaving PID-8 empty.
+const dataAbsentGenderEmpty = "empty"
+
+// patientSex returns the HL7 v2 administrative sex (PID-8) of a FHIR patient.
+// A gender flagged with a data-absent-reason extension is written as U or
+// left empty, depending on dataAbsentGender.
+func (p *Processor) patientSex(patient FHIRPatient) string {
+	if patient.Gender != "" || !isDataAbsent(patient.GenderElement) {
+		return fhirToHL7Gender(patient.Gender)
+	}
+	if p.config.DataAbsentGender == dataAbsentGenderEmpty {
+		return ""
+	}
+	return fhirGenders["unknown"]
+}
+
+// isDataAbsent reports whether a FHIR primitive element carries a
+// data-absent-reason extension.
+func isDataAbsent(element *Element) bool {
+	if element == nil {
+		return false
+	}
+	for _, ext := range element.Extension {
+		if ext.URL == dataAbsentReasonURL {
+			return true
+		}
+	}
+	return false
+}
+
+// fhirToHL7Gender converts a FHIR administrative gender to an HL7 v2
+// administrative sex code. A table 0001 code sent in place of the gender is
+// kept; other values become U (unknown), an empty gender stays empty.
+func fhirToHL7Gender(gender string) string {
+	if sex, ok := fhirGenders[gender]; ok {
+		return sex
+	}
+	if _, ok := hl7Genders[strings.ToUpper(gender)]; ok {
+		return strings.ToUpper(gender)
+	}
+	if gender == "" {
+		return ""
+	}
+	return fhirGenders["unknown"]
+}
@@ -0,0 +1,112 @@
+package hl7
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultSegmentGrammar lists the segments the trigger events require, keyed
+// by message type and trigger event (MSH-9.1^MSH-9.2). Optional segments and
+// the segment order are not checked.
+var defaultSegmentGrammar = map[string][]string{
+	"ADT^A01": {"MSH", "EVN", "PID", "PV1"}, // admit
+	"ADT^A02": {"MSH", "EVN", "PID", "PV1"}, // transfer
+	"ADT^A03": {"MSH", "EVN", "PID", "PV1"}, // discharge
+	"ADT^A04": {"MSH", "EVN", "PID", "PV1"}, // register
+	"ADT^A05": {"MSH", "EVN", "PID", "PV1"}, // pre-admit
+	"ADT^A08": {"MSH", "EVN", "PID", "PV1"}, // update patient information
+	"ADT^A23": {"MSH", "EVN", "PID", "PV1"}, // delete a patient record
+	"ADT^A28": {"MSH", "EVN", "PID", "PV1"}, // add person information
+	"ADT^A31": {"MSH", "EVN", "PID", "PV1"}, // update person information
+	"ADT^A40": {"MSH", "EVN", "PID", "MRG"}, // merge patient
+	"ORU^R01": {"MSH", "OBR"},               // observation result
+}
+
+// messageTypePattern matches the message type keys of the segment grammar.
+var messageTypePattern = regexp.MustCompile(`^[A-Z0-9]{3}\^[A-Z0-9]{3}$`)
+
+// segmentNamePattern matches HL7 v2 segment names.
+var segmentNamePattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{2}$`)
+
+// parseSegmentGrammar parses the segmentGrammar JSON object mapping message
+// types to their required segments and returns the default grammar with the
+// overrides applied.
+func parseSegmentGrammar(raw string) (map[string][]string, error) {
+	grammar := make(map[string][]string, len(defaultSegmentGrammar))
+	for messageType, segments := range defaultSegmentGrammar {
+		grammar[messageType] = segments
+	}
+	if strings.TrimSpace(raw) == "" {
+		return grammar, nil
+	}
+
+	var overrides map[string][]string
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse segment grammar: %w", err)
+	}
+	for messageType, segments := range overrides {
+		if !messageTypePattern.MatchString(messageType) {
+			return nil, fmt.Errorf("segment grammar %q: message types use the TYPE^EVENT notation, e.g. ADT^A01", messageType)
+		}
+		for _, segment := range segments {
+			if !segmentNamePattern.MatchString(segment) {
+				return nil, fmt.Errorf("segment grammar %q: invalid segment name %q", messageType, segment)
+			}
+		}
+		grammar[messageType] = segments
+	}
+	return grammar, nil
+}
+
+// validateSegments checks that every HL7 v2 message of a payload, see
+// splitMessages, contains the segments its message type requires according
+// to grammar. Message types without a grammar are not checked.
+func validateSegments(payload string, grammar map[string][]string) error {
+	payload = normalizeDelimiters(payload)
+	messages := splitMessages(payload)
+	if messages == nil {
+		messages = []string{payload}
+	}
+	for _, message := range messages {
+		if err := validateMessageSegments(message, grammar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMessageSegments checks a single message for validateSegments.
+func validateMessageSegments(message string, grammar map[string][]string) error {
+	present := make(map[string]bool)
+	var messageType string
+	var fields []string
+	for rest := message; rest != ""; {
+		var segment string
+		segment, rest = nextSegment(rest)
+		if segment == "" {
+			continue
+		}
+		fields = splitFields(segment, fields[:0])
+		present[fields[0]] = true
+		if fields[0] == "MSH" && len(fields) > 8 {
+			// MSH-1 is the field separator itself, MSH-9 is at index 8
+			messageType = component(fields[8], '^', 1) + "^" + component(fields[8], '^', 2)
+		}
+	}
+
+	var missing []string
+	for _, segment := range grammar[messageType] {
+		if !present[segment] {
+			missing = append(missing, segment)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &FieldError{
+		Segment: missing[0],
+		Message: fmt.Sprintf("%s message is missing required segments: %s", messageType, strings.Join(missing, ", ")),
+	}
+}
@@ -0,0 +1,69 @@
+package hl7
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_ValidateSegments(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	header := "MSH|^~\\&|APP|FAC|||20230815||ADT^A01|MSG00001|P|2.5\r" +
+		"EVN|A01|20230815\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M"
+
+	p := NewProcessor()
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":        "hl7",
+		"outputType":       "fhir",
+		"validateSegments": "true",
+	}))
+
+	// an A01 without PV1 is rejected
+	result := p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(header)}}})
+	errRecord, ok := result[0].(sdk.ErrorRecord)
+	is.True(ok)
+	var convErr *ConversionError
+	is.True(errors.As(errRecord.Error, &convErr))
+	is.Equal(convErr.Segment, "PV1")
+	is.Equal(convErr.Err.Error(), "failed to validate HL7 segments: ADT^A01 message is missing required segments: PV1")
+
+	result = p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(header + "\rPV1|1|I")}}})
+	_, ok = result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	// without validation the message is converted
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	}))
+	result = p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(header)}}})
+	_, ok = result[0].(sdk.SingleRecord)
+	is.True(ok)
+}
+
+func TestParseSegmentGrammar(t *testing.T) {
+	is := is.New(t)
+
+	grammar, err := parseSegmentGrammar(`{"ADT^A01": ["MSH", "PID"], "SIU^S12": ["MSH", "SCH", "PID"]}`)
+	is.NoErr(err)
+	is.Equal(grammar["ADT^A01"], []string{"MSH", "PID"})           // overridden
+	is.Equal(grammar["SIU^S12"], []string{"MSH", "SCH", "PID"})    // added
+	is.Equal(grammar["ADT^A40"], defaultSegmentGrammar["ADT^A40"]) // kept
+
+	is.NoErr(validateSegments("MSH|^~\\&|APP|FAC|||20230815||SIU^S12|1|P|2.5\rSCH|1\rPID|1||123", grammar))
+	err = validateSegments("MSH|^~\\&|APP|FAC|||20230815||SIU^S12^SIU_S12|1|P|2.5", grammar)
+	is.Equal(err.Error(), "SIU^S12 message is missing required segments: SCH, PID")
+	// message types without a grammar are not checked
+	is.NoErr(validateSegments("MSH|^~\\&|APP|FAC|||20230815||ADT^A60|1|P|2.5", grammar))
+
+	_, err = parseSegmentGrammar(`{"A01": ["MSH"]}`)
+	is.True(err != nil) // message type without trigger event
+	_, err = parseSegmentGrammar(`{"ADT^A01": ["msh"]}`)
+	is.True(err != nil) // invalid segment name
+}
@@ -0,0 +1,183 @@
+package hl7
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PatientLink represents a FHIR Patient.link.
+type PatientLink struct {
+	Other Reference `json:"other"`
+	Type  string    `json:"type"`
+}
+
+// Merge is an MRG segment, naming the patient merged into the patient of its
+// group.
+type Merge struct {
+	// PriorIdentifiers are the identifiers of the merged patient (MRG-1).
+	PriorIdentifiers []PatientIdentifier
+}
+
+// parseMerge parses the fields of an MRG segment.
+func parseMerge(fields []string) Merge {
+	return Merge{
+		PriorIdentifiers: parsePatientIdentifiers(fieldPath{Segment: "MRG", Field: 1}.field(fields)),
+	}
+}
+
+// parseHL7Groups parses a payload that may carry several messages, see
+// splitMessages, each of which may carry several patient groups, such as an
+// ADT^A40 merge, see splitPatientGroups. Each group is parsed as a message of
+// its own, in payload order.
+func parseHL7Groups(payload string, opts parseOptions) ([]HL7Message, error) {
+	payload = normalizeDelimiters(payload)
+	messages := splitMessages(payload)
+	if messages == nil {
+		messages = []string{payload}
+	}
+	var msgs []HL7Message
+	for _, message := range messages {
+		groups := splitPatientGroups(message)
+		if groups == nil {
+			groups = []string{message}
+		}
+		messageMsgs := make([]HL7Message, len(groups))
+		for i, group := range groups {
+			msg, err := parseHL7Message(group, opts)
+			if err != nil {
+				return nil, err
+			}
+			messageMsgs[i] = msg
+		}
+		// the event of the message header applies to all of its patients
+		for i := range messageMsgs[1:] {
+			messageMsgs[i+1].EVN = messageMsgs[0].EVN
+		}
+		// set IDs number the patients of a message
+		if opts.validateSetID {
+			sortBySetID(messageMsgs)
+		}
+		msgs = append(msgs, messageMsgs...)
+	}
+	return msgs, nil
+}
+
+// splitMessages splits a payload holding several messages, e.g. separated by
+// blank lines, into the messages. Every MSH segment starts a message; empty
+// segments are dropped. nil is returned for payloads with less than two
+// messages.
+func splitMessages(payload string) []string {
+	var messages []string
+	var message []string
+	for rest := payload; rest != ""; {
+		var segment string
+		segment, rest = nextSegment(rest)
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "MSH|") && message != nil {
+			messages = append(messages, strings.Join(message, "\r"))
+			message = nil
+		}
+		message = append(message, segment)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return append(messages, strings.Join(message, "\r"))
+}
+
+// sortBySetID orders patient groups by their numeric PID-1 set ID. Groups
+// without a set ID keep their order, after the numbered ones.
+func sortBySetID(msgs []HL7Message) {
+	setID := func(msg HL7Message) int {
+		n, err := strconv.Atoi(msg.PID.SetID)
+		if err != nil {
+			return math.MaxInt
+		}
+		return n
+	}
+	slices.SortStableFunc(msgs, func(a, b HL7Message) int {
+		return cmp.Compare(setID(a), setID(b))
+	})
+}
+
+// splitPatientGroups splits a message into its patient groups. Every PID
+// segment starts a group holding the segments up to the next PID. The first
+// group also holds the segments preceding the first PID (MSH, EVN), the
+// others only the MSH, so shared segments are reported once. nil is returned
+// for messages with less than two groups or without an MSH.
+func splitPatientGroups(message string) []string {
+	if !strings.HasPrefix(message, "MSH|") {
+		return nil
+	}
+	var header, groups []string
+	var group []string
+	for rest := message; rest != ""; {
+		var segment string
+		segment, rest = nextSegment(rest)
+		if segment == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(segment, "PID|"):
+			if group == nil {
+				group = append(header, segment)
+				continue
+			}
+			groups = append(groups, strings.Join(group, "\r"))
+			group = []string{header[0], segment}
+		case group == nil:
+			header = append(header, segment)
+		default:
+			group = append(group, segment)
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	return append(groups, strings.Join(group, "\r"))
+}
+
+// convertPatientGroups converts the patient groups of a message. The patient
+// of the first group is returned along with the resources of all groups: the
+// resources converted from its own segments, then the patients of the other
+// groups, each followed by their resources.
+func (p *Processor) convertPatientGroups(groups []HL7Message) (FHIRPatient, []interface{}, error) {
+	patient, err := p.convertHL7ToFHIR(groups[0])
+	if err != nil {
+		return FHIRPatient{}, nil, err
+	}
+	resources, err := p.convertResources(groups[0])
+	if err != nil {
+		return FHIRPatient{}, nil, err
+	}
+	for _, group := range groups[1:] {
+		other, err := p.convertHL7ToFHIR(group)
+		if err != nil {
+			return FHIRPatient{}, nil, err
+		}
+		other.ResourceType = "Patient"
+		groupResources, err := p.convertResources(group)
+		if err != nil {
+			return FHIRPatient{}, nil, err
+		}
+		resources = append(append(resources, other), groupResources...)
+	}
+	return patient, resources, nil
+}
+
+// mergeLinks returns the links of a patient to the patients merged into it.
+func mergeLinks(merge *Merge) []PatientLink {
+	if merge == nil || len(merge.PriorIdentifiers) == 0 {
+		return nil
+	}
+	prior := merge.PriorIdentifiers[0]
+	return []PatientLink{{
+		Other: Reference{Identifier: &Identifier{System: prior.AssigningAuthority, Value: prior.ID}},
+		Type:  "replaces",
+	}}
+}
@@ -0,0 +1,186 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_PatientGroups(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	})
+	is.NoErr(err)
+
+	// an ADT^A40 merging two pairs of patients
+	input := "MSH|^~\\&|ADT_APP|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A40|123|P|2.5|\r" +
+		"EVN|A40|20230815120000\r" +
+		"PID|1||100^^^HOSP^MR||Smith^John||19900101|M\r" +
+		"MRG|900^^^HOSP^MR\r" +
+		"PID|2||200^^^HOSP^MR||Doe^Jane||19850505|F\r" +
+		"MRG|800^^^HOSP^MR"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var bundle struct {
+		ResourceType string `json:"resourceType"`
+		Entry        []struct {
+			Resource FHIRPatient `json:"resource"`
+		} `json:"entry"`
+	}
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &bundle)
+	is.NoErr(err)
+	is.Equal(bundle.ResourceType, "Bundle")
+	is.Equal(len(bundle.Entry), 2)
+
+	first, second := bundle.Entry[0].Resource, bundle.Entry[1].Resource
+	is.Equal(first.ID, "100")
+	is.Equal(first.Name[0].Family[0], "Smith")
+	is.Equal(first.Link, []PatientLink{{
+		Other: Reference{Identifier: &Identifier{System: "HOSP", Value: "900"}},
+		Type:  "replaces",
+	}})
+	is.Equal(second.ResourceType, "Patient")
+	is.Equal(second.ID, "200")
+	is.Equal(second.Gender, "female")
+	is.Equal(second.Link[0].Other.Identifier.Value, "800")
+}
+
+func TestProcess_Merge(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	})
+	is.NoErr(err)
+
+	// MRN 111 merged into the surviving MRN 222
+	input := "MSH|^~\\&|ADT_APP|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A40|123|P|2.5|\r" +
+		"EVN|A40|20230815120000\r" +
+		"PID|1||222^^^HOSP^MR||Smith^John||19900101|M\r" +
+		"MRG|111^^^HOSP^MR"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	// a single pair is a plain Patient
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+	is.Equal(patient.ID, "222")
+	is.Equal(patient.Link, []PatientLink{{
+		Other: Reference{Identifier: &Identifier{System: "HOSP", Value: "111"}},
+		Type:  "replaces",
+	}})
+}
+
+func TestProcess_MultipleMessages(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":     "hl7",
+		"outputType":    "fhir",
+		"includeActive": "true",
+	})
+	is.NoErr(err)
+
+	// two messages separated by a blank line, the second deleting a patient
+	input := "MSH|^~\\&|ADT_APP|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|1|P|2.5\n" +
+		"EVN|A01|20230815120000\n" +
+		"PID|1||111^^^HOSP^MR||Smith^John||19900101|M\n" +
+		"\n" +
+		"MSH|^~\\&|ADT_APP|FACILITY|HL7_PARSER|FACILITY|20230815120500||ADT^A23|2|P|2.5\n" +
+		"EVN|A23|20230815120500\n" +
+		"PID|1||222^^^HOSP^MR||Doe^Jane||19850505|F\n"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var bundle struct {
+		ResourceType string `json:"resourceType"`
+		Entry        []struct {
+			Resource FHIRPatient `json:"resource"`
+		} `json:"entry"`
+	}
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &bundle))
+	is.Equal(bundle.ResourceType, "Bundle")
+	is.Equal(len(bundle.Entry), 2)
+
+	// each patient is read with the header of its own message
+	first, second := bundle.Entry[0].Resource, bundle.Entry[1].Resource
+	is.Equal(first.ID, "111")
+	is.Equal(*first.Active, true)
+	is.Equal(second.ID, "222")
+	is.Equal(second.Name[0].Family[0], "Doe")
+	is.Equal(*second.Active, false)
+}
+
+func TestSplitMessages(t *testing.T) {
+	is := is.New(t)
+
+	messages := splitMessages("MSH|^~\\&|A\rPID|1||1\r\r\rMSH|^~\\&|B\rPID|1||2\r")
+	is.Equal(messages, []string{"MSH|^~\\&|A\rPID|1||1", "MSH|^~\\&|B\rPID|1||2"})
+
+	is.Equal(splitMessages("MSH|^~\\&|A\rPID|1||1\rPID|2||2"), nil) // a single message
+}
+
+func TestParseHL7Groups_SingleGroup(t *testing.T) {
+	is := is.New(t)
+
+	groups, err := parseHL7Groups("MSH|^~\\&|APP|FAC|APP|FAC|20230815120000||ADT^A01|123|P|2.5\rPID|1||123||Smith^John", parseOptions{})
+	is.NoErr(err)
+	is.Equal(len(groups), 1)
+	is.Equal(groups[0].PID.ID, "123")
+	is.Equal(groups[0].MRG, (*Merge)(nil))
+
+	_, err = parseHL7Groups("PID|1||123\rPID|2||456", parseOptions{})
+	is.True(err != nil) // missing MSH
+}
+
+func TestParseHL7Groups_SetID(t *testing.T) {
+	is := is.New(t)
+	input := "MSH|^~\\&|ADT_APP|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A40|123|P|2.5|\r" +
+		"EVN|A40|20230815120000\r" +
+		"PID|2||200^^^HOSP^MR||Doe^Jane||19850505|F\r" +
+		"PID|||300^^^HOSP^MR||Roe^Sam||19700303|M\r" +
+		"PID|1||100^^^HOSP^MR||Smith^John||19900101|M"
+
+	// patients are ordered by set ID when validating, after those without
+	groups, err := parseHL7Groups(input, parseOptions{validateSetID: true})
+	is.NoErr(err)
+	is.Equal(len(groups), 3)
+	is.Equal(groups[0].PID.ID, "100")
+	is.Equal(groups[1].PID.ID, "200")
+	is.Equal(groups[2].PID.ID, "300")
+
+	// message order otherwise
+	groups, err = parseHL7Groups(input, parseOptions{})
+	is.NoErr(err)
+	is.Equal(groups[0].PID.ID, "200")
+
+	// a non-numeric set ID is rejected
+	invalid := strings.Replace(input, "PID|2|", "PID|A|", 1)
+	_, err = parseHL7Groups(invalid, parseOptions{validateSetID: true})
+	var fieldErr *FieldError
+	is.True(errors.As(err, &fieldErr))
+	is.Equal(fieldErr.Field, "PID-1")
+	is.Equal(fieldErr.Message, `invalid PID-1 set ID "A": must be numeric`)
+	_, err = parseHL7Groups(invalid, parseOptions{})
+	is.NoErr(err)
+}
@@ -0,0 +1,158 @@
+package hl7
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fieldPath identifies a value in an HL7 v2 message using the
+// SEG-field[.component] notation, e.g. PID-3.1.
+type fieldPath struct {
+	Segment   string
+	Field     int
+	Component int // 0 selects the whole field
+}
+
+var fieldPathPattern = regexp.MustCompile(`^([A-Z][A-Z0-9]{2})-([1-9][0-9]*)(?:\.([1-9][0-9]*))?$`)
+
+// parseFieldPath parses a path like PID-3 or PID-3.1.
+func parseFieldPath(s string) (fieldPath, error) {
+	m := fieldPathPattern.FindStringSubmatch(s)
+	if m == nil {
+		return fieldPath{}, fmt.Errorf("invalid field path %q, expected format SEG-field[.component]", s)
+	}
+	field, _ := strconv.Atoi(m[2])
+	component := 0
+	if m[3] != "" {
+		component, _ = strconv.Atoi(m[3])
+	}
+	return fieldPath{Segment: m[1], Field: field, Component: component}, nil
+}
+
+// field returns the raw field at the path, including all repetitions, or an
+// empty string if the segment does not contain it.
+func (fp fieldPath) field(fields []string) string {
+	idx := fp.Field
+	if fp.Segment == "MSH" {
+		// MSH-1 is the field separator itself, so MSH fields are shifted by one
+		idx--
+	}
+	if idx < 1 || idx >= len(fields) {
+		return ""
+	}
+	return fields[idx]
+}
+
+// value returns the unescaped value at the path from the first repetition of
+// the field, or an empty string if the segment does not contain it.
+func (fp fieldPath) value(fields []string) string {
+	v, _, _ := nextToken(fp.field(fields), '~')
+	if fp.Component > 0 {
+		v = component(v, '^', fp.Component)
+	}
+	return unescapeHL7(v)
+}
+
+// hl7Fields maps logical field names to their location in HL7Message.
+var hl7Fields = map[string]func(*HL7Message) *string{
+	"sendingApplication": func(m *HL7Message) *string { return &m.MSH.SendingApplication },
+	"sendingFacility":    func(m *HL7Message) *string { return &m.MSH.SendingFacility },
+	"dateTime":           func(m *HL7Message) *string { return &m.MSH.DateTime },
+	"messageType":        func(m *HL7Message) *string { return &m.MSH.MessageType },
+	"controlId":          func(m *HL7Message) *string { return &m.MSH.ControlID },
+	"patientId":          func(m *HL7Message) *string { return &m.PID.ID },
+	"lastName":           func(m *HL7Message) *string { return &m.PID.LastName },
+	"firstName":          func(m *HL7Message) *string { return &m.PID.FirstName },
+	"birthDate":          func(m *HL7Message) *string { return &m.PID.BirthDate },
+	"gender":             func(m *HL7Message) *string { return &m.PID.Gender },
+	"street":             func(m *HL7Message) *string { return &m.PID.Address.Street },
+	"city":               func(m *HL7Message) *string { return &m.PID.Address.City },
+	"state":              func(m *HL7Message) *string { return &m.PID.Address.State },
+	"postalCode":         func(m *HL7Message) *string { return &m.PID.Address.PostalCode },
+	"country":            func(m *HL7Message) *string { return &m.PID.Address.Country },
+	"deathDateTime":      func(m *HL7Message) *string { return &m.PID.DeathDateTime },
+	"deathIndicator":     func(m *HL7Message) *string { return &m.PID.DeathIndicator },
+}
+
+// defaultFieldMappings holds the standard positions of the logical fields.
+var defaultFieldMappings = map[string]fieldPath{
+	"sendingApplication": {Segment: "MSH", Field: 3},
+	"sendingFacility":    {Segment: "MSH", Field: 4},
+	"dateTime":           {Segment: "MSH", Field: 7},
+	"messageType":        {Segment: "MSH", Field: 9},
+	"controlId":          {Segment: "MSH", Field: 10},
+	"patientId":          {Segment: "PID", Field: 3, Component: 1},
+	"lastName":           {Segment: "PID", Field: 5, Component: 1},
+	"firstName":          {Segment: "PID", Field: 5, Component: 2},
+	"birthDate":          {Segment: "PID", Field: 7},
+	"gender":             {Segment: "PID", Field: 8},
+	"street":             {Segment: "PID", Field: 11, Component: 1},
+	"city":               {Segment: "PID", Field: 11, Component: 2},
+	"state":              {Segment: "PID", Field: 11, Component: 3},
+	"postalCode":         {Segment: "PID", Field: 11, Component: 4},
+	"country":            {Segment: "PID", Field: 11, Component: 5},
+	"deathDateTime":      {Segment: "PID", Field: 29, Component: 1},
+	"deathIndicator":     {Segment: "PID", Field: 30},
+}
+
+// parseFieldMappings parses the fieldMappings JSON object and returns the
+// default mappings with the overrides applied.
+func parseFieldMappings(raw string) (map[string]fieldPath, error) {
+	mappings := make(map[string]fieldPath, len(defaultFieldMappings))
+	for name, path := range defaultFieldMappings {
+		mappings[name] = path
+	}
+	if strings.TrimSpace(raw) == "" {
+		return mappings, nil
+	}
+
+	var overrides map[string]string
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse field mappings: %w", err)
+	}
+	for name, p := range overrides {
+		if _, ok := hl7Fields[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q in field mappings", name)
+		}
+		path, err := parseFieldPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("field mapping %q: %w", name, err)
+		}
+		mappings[name] = path
+	}
+	return mappings, nil
+}
+
+// parseFieldDefaults parses the defaults JSON object mapping field paths of
+// generated segments to the value of the field when left empty.
+func parseFieldDefaults(raw string) (map[fieldPath]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var values map[string]string
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, fmt.Errorf("failed to parse defaults: %w", err)
+	}
+	defaults := make(map[fieldPath]string, len(values))
+	for p, value := range values {
+		path, err := parseFieldPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("default %q: %w", p, err)
+		}
+		if path.Component > 0 {
+			return nil, fmt.Errorf("default %q: defaults apply to whole fields", p)
+		}
+		count, ok := segmentFieldCounts[path.Segment]
+		if !ok || path.Field > count {
+			return nil, fmt.Errorf("default %q: not a field of the generated segments", p)
+		}
+		if path.Segment == "MSH" && path.Field <= 2 {
+			return nil, fmt.Errorf("default %q: MSH-1 and MSH-2 hold the delimiters", p)
+		}
+		defaults[path] = value
+	}
+	return defaults, nil
+}
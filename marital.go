@@ -0,0 +1,84 @@
+package hl7
+
+// maritalStatusSystem is the code system of the FHIR maritalStatus value set.
+const maritalStatusSystem = "http://terminology.hl7.org/CodeSystem/v3-MaritalStatus"
+
+// maritalStatusV2System is the code system of HL7 v2 marital statuses.
+const maritalStatusV2System = "http://terminology.hl7.org/CodeSystem/v2-0002"
+
+// nullFlavorSystem is the code system of HL7 v3 null flavors.
+const nullFlavorSystem = "http://terminology.hl7.org/CodeSystem/v3-NullFlavor"
+
+// hl7MaritalStatuses maps HL7 v2 marital statuses (table 0002) to the HL7 v3
+// MaritalStatus codes used by FHIR, with their display.
+var hl7MaritalStatuses = map[string]Coding{
+	"A": {System: maritalStatusSystem, Code: "L", Display: "Legally Separated"},
+	"B": {System: maritalStatusSystem, Code: "U", Display: "unmarried"},
+	"C": {System: maritalStatusSystem, Code: "C", Display: "Common Law"},
+	"D": {System: maritalStatusSystem, Code: "D", Display: "Divorced"},
+	"E": {System: maritalStatusSystem, Code: "L", Display: "Legally Separated"},
+	"I": {System: maritalStatusSystem, Code: "I", Display: "Interlocutory"},
+	"M": {System: maritalStatusSystem, Code: "M", Display: "Married"},
+	"N": {System: maritalStatusSystem, Code: "A", Display: "Annulled"},
+	"P": {System: maritalStatusSystem, Code: "T", Display: "Domestic partner"},
+	"R": {System: maritalStatusSystem, Code: "T", Display: "Domestic partner"},
+	"S": {System: maritalStatusSystem, Code: "S", Display: "Never Married"},
+	"W": {System: maritalStatusSystem, Code: "W", Display: "Widowed"},
+	"U": {System: nullFlavorSystem, Code: "UNK", Display: "unknown"},
+}
+
+// hl7MaritalStatusDisplays holds the display of the HL7 v2 marital statuses
+// without an HL7 v3 equivalent, which are kept in the v2 code system.
+var hl7MaritalStatusDisplays = map[string]string{
+	"G": "Living together",
+	"O": "Other",
+	"T": "Unreported",
+}
+
+// fhirMaritalStatuses maps the HL7 v3 MaritalStatus codes back to HL7 v2
+// marital statuses. Codes several v2 statuses map to are written back as the
+// first one of table 0002 (e.g. L as A); P (polygamous) has no v2 code.
+var fhirMaritalStatuses = map[Coding]string{
+	{System: maritalStatusSystem, Code: "A"}: "N",
+	{System: maritalStatusSystem, Code: "C"}: "C",
+	{System: maritalStatusSystem, Code: "D"}: "D",
+	{System: maritalStatusSystem, Code: "I"}: "I",
+	{System: maritalStatusSystem, Code: "L"}: "A",
+	{System: maritalStatusSystem, Code: "M"}: "M",
+	{System: maritalStatusSystem, Code: "S"}: "S",
+	{System: maritalStatusSystem, Code: "T"}: "P",
+	{System: maritalStatusSystem, Code: "U"}: "B",
+	{System: maritalStatusSystem, Code: "W"}: "W",
+	{System: nullFlavorSystem, Code: "UNK"}:  "U",
+}
+
+// hl7ToFHIRMaritalStatus converts an HL7 v2 marital status (PID-16) to a FHIR
+// maritalStatus. Codes without an HL7 v3 equivalent keep their v2 code
+// system. It returns nil for an empty code.
+func hl7ToFHIRMaritalStatus(code string) *CodeableConcept {
+	if code == "" {
+		return nil
+	}
+	status, ok := hl7MaritalStatuses[code]
+	if !ok {
+		status = Coding{System: maritalStatusV2System, Code: code, Display: hl7MaritalStatusDisplays[code]}
+	}
+	return &CodeableConcept{Coding: []Coding{status}}
+}
+
+// fhirToHL7MaritalStatus returns the HL7 v2 marital status (table 0002) of a
+// FHIR maritalStatus, or an empty string if it has no known code.
+func fhirToHL7MaritalStatus(status *CodeableConcept) string {
+	if status == nil {
+		return ""
+	}
+	for _, coding := range status.Coding {
+		if coding.System == maritalStatusV2System {
+			return coding.Code
+		}
+		if code, ok := fhirMaritalStatuses[Coding{System: coding.System, Code: coding.Code}]; ok {
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package hl7
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestHL7ToFHIRMaritalStatus(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		code string
+		want Coding
+	}{
+		{"S", Coding{System: maritalStatusSystem, Code: "S", Display: "Never Married"}},
+		{"C", Coding{System: maritalStatusSystem, Code: "C", Display: "Common Law"}},
+		{"E", Coding{System: maritalStatusSystem, Code: "L", Display: "Legally Separated"}},
+		{"N", Coding{System: maritalStatusSystem, Code: "A", Display: "Annulled"}},
+		{"I", Coding{System: maritalStatusSystem, Code: "I", Display: "Interlocutory"}},
+		{"B", Coding{System: maritalStatusSystem, Code: "U", Display: "unmarried"}},
+		{"R", Coding{System: maritalStatusSystem, Code: "T", Display: "Domestic partner"}},
+		{"U", Coding{System: nullFlavorSystem, Code: "UNK", Display: "unknown"}},
+		// no v3 equivalent
+		{"G", Coding{System: maritalStatusV2System, Code: "G", Display: "Living together"}},
+		{"X", Coding{System: maritalStatusV2System, Code: "X"}},
+	}
+	for _, tt := range tests {
+		status := hl7ToFHIRMaritalStatus(tt.code)
+		is.Equal(status.Coding, []Coding{tt.want}) // coding of tt.code
+	}
+	is.Equal(hl7ToFHIRMaritalStatus(""), nil)
+}
+
+func TestFHIRToHL7MaritalStatus(t *testing.T) {
+	is := is.New(t)
+
+	// every v3 code maps back to a v2 status mapping to it
+	for coding, code := range fhirMaritalStatuses {
+		is.Equal(hl7MaritalStatuses[code].Code, coding.Code)
+		is.Equal(fhirToHL7MaritalStatus(&CodeableConcept{Coding: []Coding{coding}}), code)
+	}
+
+	// polygamous has no HL7 v2 code
+	polygamous := &CodeableConcept{Coding: []Coding{{System: maritalStatusSystem, Code: "P", Display: "Polygamous"}}}
+	is.Equal(fhirToHL7MaritalStatus(polygamous), "")
+}
@@ -0,0 +1,54 @@
+package hl7
+
+import (
+	"crypto/md5"  //nolint:gosec // match keys are not a security feature
+	"crypto/sha1" //nolint:gosec // match keys are not a security feature
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"strings"
+)
+
+// metadataMatchKey is the metadata key holding the patient matching key
+// emitted when MatchKeyHash is set.
+const metadataMatchKey = "hl7.matchKey"
+
+// matchKeyHashes maps the MatchKeyHash schemes to their hash functions.
+var matchKeyHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha1":   sha1.New,
+	"md5":    md5.New,
+}
+
+// matchKey returns the hex encoded hash of the composite matching key of a
+// patient: family name, given name, birth date and MRN. Names are compared
+// case-insensitively. The MRN is the value of the first identifier of type
+// MR, or the patient ID.
+func matchKey(patient FHIRPatient, scheme string) string {
+	var family, given string
+	if len(patient.Name) > 0 {
+		if name := patient.Name[0]; len(name.Family) > 0 {
+			family = name.Family[0]
+		}
+		if name := patient.Name[0]; len(name.Given) > 0 {
+			given = name.Given[0]
+		}
+	}
+	mrn := patient.ID
+	for _, id := range patient.Identifier {
+		if id.Type != nil && len(id.Type.Coding) > 0 && id.Type.Coding[0].Code == "MR" {
+			mrn = id.Value
+			break
+		}
+	}
+
+	key := strings.Join([]string{
+		strings.ToUpper(strings.TrimSpace(family)),
+		strings.ToUpper(strings.TrimSpace(given)),
+		fhirToHL7Timestamp(patient.BirthDate),
+		strings.TrimSpace(mrn),
+	}, "|")
+	h := matchKeyHashes[scheme]()
+	h.Write([]byte(key))
+	return hex.EncodeToString(h.Sum(nil))
+}
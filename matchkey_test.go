@@ -0,0 +1,50 @@
+package hl7
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_MatchKey(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	process := func(inputType, outputType, scheme, input string) string {
+		p := NewProcessor()
+		err := p.Configure(ctx, map[string]string{
+			"inputType":    inputType,
+			"outputType":   outputType,
+			"matchKeyHash": scheme,
+		})
+		is.NoErr(err)
+		result := p.Process(ctx, []opencdc.Record{{
+			Payload: opencdc.Change{After: opencdc.RawData(input)},
+		}})
+		rec, ok := result[0].(sdk.SingleRecord)
+		is.True(ok) // should be a single record
+		return rec.Metadata[metadataMatchKey]
+	}
+
+	hl7Input := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A01|MSG00001|P|2.5\r" +
+		"PID|1||123^^^HOSP^MR~456^^^HOSP^PI||Doe^John||19800101|M"
+	fhirInput := `{"resourceType":"Patient","id":"123","name":[{"family":["DOE"],"given":[" john "]}],"birthDate":"1980-01-01","gender":"male",
+		"identifier":[{"value":"123","type":{"coding":[{"code":"MR"}]}}]}`
+
+	key := process("hl7", "fhir", "sha256", hl7Input)
+	is.Equal(len(key), 64)
+	is.Equal(process("hl7", "fhir", "sha256", hl7Input), key)    // stable across runs
+	is.Equal(process("fhir", "hl7", "sha256", fhirInput), key)   // and across input types
+	is.Equal(process("fhir", "hl7v3", "sha256", fhirInput), key) // and output types
+
+	other := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A01|MSG00001|P|2.5\r" +
+		"PID|1||124^^^HOSP^MR||Doe^John||19800101|M"
+	is.True(process("hl7", "fhir", "sha256", other) != key) // another MRN is another patient
+
+	is.Equal(len(process("hl7", "fhir", "sha1", hl7Input)), 40)
+	is.Equal(len(process("hl7", "fhir", "md5", hl7Input)), 32)
+	is.Equal(process("hl7", "fhir", "", hl7Input), "") // disabled by default
+}
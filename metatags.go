@@ -0,0 +1,52 @@
+package hl7
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Meta represents the FHIR resource metadata.
+type Meta struct {
+	Tag []Coding `json:"tag,omitempty"`
+}
+
+// parseHeaderTagSystems parses the headerTagSystems JSON object mapping the
+// application and facility fields of the message header (MSH-3 to MSH-6)
+// to the systems of the FHIR meta tags holding their values.
+func parseHeaderTagSystems(raw string) (map[int]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var systems map[string]string
+	if err := json.Unmarshal([]byte(raw), &systems); err != nil {
+		return nil, fmt.Errorf("failed to parse header tag systems: %w", err)
+	}
+	headerTags := make(map[int]string, len(systems))
+	for p, system := range systems {
+		path, err := parseFieldPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("header tag system %q: %w", p, err)
+		}
+		if path.Segment != "MSH" || path.Field < 3 || path.Field > 6 || path.Component > 0 {
+			return nil, fmt.Errorf("header tag system %q: only MSH-3 to MSH-6 can be read from tags", p)
+		}
+		headerTags[path.Field] = system
+	}
+	return headerTags, nil
+}
+
+// headerTag returns the code of the first meta tag of the patient with the
+// system configured for the MSH field.
+func (p *Processor) headerTag(patient FHIRPatient, field int) (string, bool) {
+	system, ok := p.headerTags[field]
+	if !ok || patient.Meta == nil {
+		return "", false
+	}
+	for _, tag := range patient.Meta.Tag {
+		if tag.System == system && tag.Code != "" {
+			return tag.Code, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,40 @@
+package hl7
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestConvertFHIRToHL7_HeaderTags(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	is.NoErr(p.Configure(context.Background(), map[string]string{
+		"inputType":        "fhir",
+		"outputType":       "hl7",
+		"headerTagSystems": `{"MSH-3": "urn:example:sending-app", "MSH-4": "urn:example:sending-facility", "MSH-6": "urn:example:receiving-facility"}`,
+	}))
+
+	patient := FHIRPatient{
+		ID: "123",
+		Meta: &Meta{Tag: []Coding{
+			{System: "urn:example:other", Code: "IGNORED"},
+			{System: "urn:example:sending-app", Code: "EHR"},
+			{System: "urn:example:sending-facility", Code: "MAIN^HOSPITAL"},
+		}},
+		Name:                 []HumanName{{Family: []string{"Smith"}, Given: []string{"John"}}},
+		BirthDate:            "1990-01-01",
+		ManagingOrganization: &Reference{Display: "Other Hospital"},
+	}
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	mshFields := splitHL7Field(splitHL7Message(hl7Message)[0])
+	is.Equal(mshFields[2], "EHR")             // MSH-3
+	is.Equal(mshFields[3], `MAIN\S\HOSPITAL`) // MSH-4, tag over managingOrganization
+	is.Equal(mshFields[4], "HL7_PARSER")      // MSH-5, not configured
+	is.Equal(mshFields[5], "FACILITY")        // MSH-6, no tag
+
+	_, err = parseHeaderTagSystems(`{"MSH-7": "urn:example:time"}`)
+	is.True(err != nil)
+}
@@ -0,0 +1,27 @@
+package hl7
+
+import "strings"
+
+// MLLP frame delimiters: a message is sent as <VT>message<FS><CR>.
+const (
+	mllpStartBlock = "\x0b"
+	mllpEndBlock   = "\x1c\r"
+)
+
+// unwrapMLLP removes the MLLP frame around message. Messages without a frame
+// are returned unchanged.
+func unwrapMLLP(message string) string {
+	if !strings.HasPrefix(message, mllpStartBlock) {
+		return message
+	}
+	message = strings.TrimPrefix(message, mllpStartBlock)
+	if i := strings.LastIndexByte(message, mllpEndBlock[0]); i >= 0 {
+		message = message[:i]
+	}
+	return message
+}
+
+// wrapMLLP wraps message in an MLLP frame.
+func wrapMLLP(message string) string {
+	return mllpStartBlock + message + mllpEndBlock
+}
@@ -0,0 +1,48 @@
+package hl7
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_MLLPFraming(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":   "hl7",
+		"outputType":  "fhir",
+		"mllpFraming": "true",
+	})
+	is.NoErr(err)
+
+	message := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\rPID|1||123||Smith^John||1990-01-01|male\r"
+	result := p.Process(context.Background(), []opencdc.Record{
+		{Payload: opencdc.Change{After: opencdc.RawData("\x0b" + message + "\x1c\r")}},
+		{Payload: opencdc.Change{After: opencdc.RawData(message)}}, // unframed input is accepted too
+	})
+	for _, r := range result {
+		_, ok := r.(sdk.SingleRecord)
+		is.True(ok)
+	}
+
+	// HL7 output is framed
+	err = p.Configure(context.Background(), map[string]string{
+		"inputType":   "fhir",
+		"outputType":  "hl7",
+		"mllpFraming": "true",
+	})
+	is.NoErr(err)
+	result = p.Process(context.Background(), []opencdc.Record{
+		{Payload: opencdc.Change{After: opencdc.RawData(`{"id": "123"}`)}},
+	})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	out := rec.Payload.After.(opencdc.StructuredData)["hl7"].(string)
+	is.True(strings.HasPrefix(out, "\x0bMSH|"))
+	is.True(strings.HasSuffix(out, "\x1c\r"))
+}
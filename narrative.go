@@ -0,0 +1,48 @@
+package hl7
+
+import (
+	"html"
+	"strings"
+)
+
+// patientNarrative returns a generated narrative summarizing the name,
+// gender, birth date, identifiers and addresses of a patient. Values are
+// escaped, so the div is valid XHTML.
+func patientNarrative(patient FHIRPatient) *Narrative {
+	var name []string
+	if len(patient.Name) > 0 {
+		name = append(name, patient.Name[0].Given...)
+		name = append(name, patient.Name[0].Family...)
+	}
+
+	var b strings.Builder
+	b.WriteString(`<div xmlns="http://www.w3.org/1999/xhtml">`)
+	b.WriteString("<p><b>" + html.EscapeString(strings.Join(name, " ")) + "</b>")
+	if patient.Gender != "" {
+		b.WriteString(", " + html.EscapeString(patient.Gender))
+	}
+	if patient.BirthDate != "" {
+		b.WriteString(", born " + html.EscapeString(patient.BirthDate))
+	}
+	b.WriteString("</p>")
+	for _, id := range patient.Identifier {
+		label := "Identifier"
+		if id.Type != nil && len(id.Type.Coding) > 0 {
+			label = id.Type.Coding[0].Code
+		}
+		b.WriteString("<p>" + html.EscapeString(label) + ": " + html.EscapeString(id.Value) + "</p>")
+	}
+	for _, addr := range patient.Address {
+		var parts []string
+		for _, part := range append(addr.Line, addr.City, strings.TrimSpace(addr.State+" "+addr.PostalCode), addr.Country) {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		if len(parts) > 0 {
+			b.WriteString("<p>" + html.EscapeString(strings.Join(parts, ", ")) + "</p>")
+		}
+	}
+	b.WriteString("</div>")
+	return &Narrative{Status: "generated", Div: b.String()}
+}
@@ -0,0 +1,58 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_GenerateNarrative(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	input := "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|MSG00001|P|2.5\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M|||123 Main St^^Springfield^IL^62701^USA"
+
+	p := NewProcessor()
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":         "hl7",
+		"outputType":        "fhir",
+		"generateNarrative": "true",
+	}))
+	result := p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+	is.True(patient.Text != nil)
+	is.Equal(patient.Text.Status, "generated")
+	is.True(strings.Contains(patient.Text.Div, "<b>John Doe</b>, male, born 1980-01-01"))
+	is.True(strings.Contains(patient.Text.Div, "<p>MR: 123</p>"))
+	is.NoErr(xml.Unmarshal([]byte(patient.Text.Div), new(struct{}))) // valid XHTML
+
+	// no narrative by default
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	}))
+	result = p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	rec, ok = result[0].(sdk.SingleRecord)
+	is.True(ok)
+	is.True(!strings.Contains(string(rec.Payload.After.Bytes()), `"text"`))
+}
+
+func TestPatientNarrative_Escaping(t *testing.T) {
+	is := is.New(t)
+
+	narrative := patientNarrative(FHIRPatient{
+		Name: []HumanName{{Family: []string{"O'Brien & <Sons>"}, Given: []string{"Ann"}}},
+	})
+	is.Equal(narrative.Div, `<div xmlns="http://www.w3.org/1999/xhtml"><p><b>Ann O&#39;Brien &amp; &lt;Sons&gt;</b></p></div>`)
+	is.NoErr(xml.Unmarshal([]byte(narrative.Div), new(struct{})))
+}
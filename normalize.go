@@ -0,0 +1,81 @@
+package hl7
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// normalizeFHIRPatient normalizes a FHIR Patient converted from FHIR to
+// FHIR: active defaults to true when absent. The other fields are kept as
+// they are, including those the processor does not model, and numbers keep
+// their precision. In the ignore mode of OnInvalidFHIRType, fields of the
+// wrong JSON type are dropped.
+func (p *Processor) normalizeFHIRPatient(raw []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var patient map[string]interface{}
+	if err := decoder.Decode(&patient); err != nil {
+		return nil, fmt.Errorf("failed to parse FHIR JSON: %w", err)
+	}
+	if p.config.OnInvalidFHIRType == onInvalidFHIRTypeIgnore {
+		if err := dropInvalidFields(patient); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := patient["active"]; !ok {
+		patient["active"] = true
+	}
+	return patient, nil
+}
+
+// dropInvalidFields removes the fields of a FHIR Patient that do not decode
+// into FHIRPatient, one type mismatch at a time.
+func dropInvalidFields(patient map[string]interface{}) error {
+	for {
+		data, err := json.Marshal(patient)
+		if err != nil {
+			return fmt.Errorf("failed to marshal FHIR patient: %w", err)
+		}
+		var typeErr *json.UnmarshalTypeError
+		if err := json.Unmarshal(data, &FHIRPatient{}); !errors.As(err, &typeErr) || typeErr.Field == "" {
+			return nil
+		}
+		if _, ok := dropField(patient, strings.Split(typeErr.Field, ".")); !ok {
+			return nil
+		}
+	}
+}
+
+// dropField returns value without the field at path, a dotted path of object
+// keys and array indexes, and whether the field was found.
+func dropField(value interface{}, path []string) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return v, false
+		}
+		if len(path) == 1 {
+			delete(v, path[0])
+			return v, true
+		}
+		v[path[0]], ok = dropField(child, path[1:])
+		return v, ok
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(v) {
+			return v, false
+		}
+		if len(path) == 1 {
+			return append(v[:i:i], v[i+1:]...), true
+		}
+		var ok bool
+		v[i], ok = dropField(v[i], path[1:])
+		return v, ok
+	}
+	return value, false
+}
@@ -0,0 +1,78 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_FHIRToFHIR(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "fhir",
+	})
+	is.NoErr(err)
+
+	result := p.Process(context.Background(), []opencdc.Record{
+		{Payload: opencdc.Change{After: opencdc.RawData(`{"resourceType":"Patient","id":"1","active":false}`)}},
+		{Payload: opencdc.Change{After: opencdc.RawData(`{"resourceType":"Patient","id":"2","photo":[{"url":"https://example.com/2.png"}]}`)}},
+		{Payload: opencdc.Change{After: opencdc.RawData(`{"resourceType":"Observation","id":"3"}`)}},
+	})
+
+	// active survives
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+	is.Equal(patient.ID, "1")
+	is.Equal(*patient.Active, false)
+
+	// active defaults to true, fields the processor does not model are kept
+	rec, ok = result[1].(sdk.SingleRecord)
+	is.True(ok)
+	var normalized map[string]interface{}
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &normalized))
+	is.Equal(normalized["active"], true)
+	is.Equal(normalized["photo"], []interface{}{map[string]interface{}{"url": "https://example.com/2.png"}})
+
+	_, ok = result[2].(sdk.ErrorRecord)
+	is.True(ok) // not a Patient
+}
+
+func TestProcess_FHIRToFHIRNumbersAndInvalidTypes(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	input := `{"resourceType":"Patient","id":"1","gender":1,"name":[{"family":"Doe","given":["John"]}],` +
+		`"extension":[{"url":"https://example.com/weight","valueDecimal":72.50}],` +
+		`"multipleBirthInteger":9007199254740993}`
+
+	p := NewProcessor()
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":         "fhir",
+		"outputType":        "fhir",
+		"onInvalidFHIRType": "ignore",
+	}))
+	result := p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	output := string(rec.Payload.After.Bytes())
+
+	// numbers are written as they were read
+	is.True(strings.Contains(output, `"valueDecimal":72.50`))
+	is.True(strings.Contains(output, `"multipleBirthInteger":9007199254740993`))
+
+	// fields of the wrong type are dropped, the rest is kept
+	var normalized map[string]interface{}
+	is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &normalized))
+	_, ok = normalized["gender"]
+	is.True(!ok)
+	is.Equal(normalized["name"], []interface{}{map[string]interface{}{"given": []interface{}{"John"}}})
+	is.Equal(normalized["id"], "1")
+}
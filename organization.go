@@ -0,0 +1,65 @@
+package hl7
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// FHIROrganization represents a FHIR Organization resource.
+type FHIROrganization struct {
+	ResourceType string       `json:"resourceType"`
+	ID           string       `json:"id,omitempty"`
+	Identifier   []Identifier `json:"identifier,omitempty"`
+	Name         string       `json:"name,omitempty"`
+}
+
+// defaultSendingFacility is the sending facility (MSH-4) of generated HL7 v2
+// messages for patients without a managing organization.
+const defaultSendingFacility = "FACILITY"
+
+// containedOrganization returns the Organization contained in the patient
+// under the local reference ref, e.g. #org1.
+func containedOrganization(patient FHIRPatient, ref string) (FHIROrganization, bool) {
+	id, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return FHIROrganization{}, false
+	}
+	for _, raw := range patient.Contained {
+		var org FHIROrganization
+		if err := json.Unmarshal(raw, &org); err != nil {
+			continue
+		}
+		if org.ResourceType == "Organization" && org.ID == id {
+			return org, true
+		}
+	}
+	return FHIROrganization{}, false
+}
+
+// managingOrganizationName returns the name of the managing organization of
+// the patient: the display of the reference or, for a local reference, the
+// name of the contained Organization.
+func managingOrganizationName(patient FHIRPatient) string {
+	ref := patient.ManagingOrganization
+	if ref == nil {
+		return ""
+	}
+	if ref.Display != "" {
+		return ref.Display
+	}
+	if org, ok := containedOrganization(patient, ref.Reference); ok {
+		return org.Name
+	}
+	return ""
+}
+
+// hl7ManagingOrganization returns the managing organization named by the
+// sending facility (MSH-4) of an HL7 v2 message, nil when MSH-4 is empty or
+// the placeholder of patients without one.
+func hl7ManagingOrganization(sendingFacility string) *Reference {
+	name := component(sendingFacility, '^', 1)
+	if name == "" || name == defaultSendingFacility {
+		return nil
+	}
+	return &Reference{Display: name}
+}
@@ -0,0 +1,92 @@
+package hl7
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcess_ContainedOrganization(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	p := NewProcessor()
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+	}))
+
+	input := `{
+		"resourceType": "Patient",
+		"id": "123",
+		"contained": [
+			{"resourceType": "Practitioner", "id": "org1"},
+			{"resourceType": "Organization", "id": "org1", "name": "General Hospital"}
+		],
+		"managingOrganization": {"reference": "#org1"},
+		"name": [{"family": ["Smith"], "given": ["John"]}],
+		"birthDate": "1990-01-01"
+	}`
+	result := p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	segments := splitHL7Message(rec.Payload.After.(opencdc.StructuredData)["hl7"].(string))
+	is.Equal(splitHL7Field(segments[0])[3], "General Hospital") // MSH-4
+}
+
+func TestProcess_BundleOrganization(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	p := NewProcessor()
+	is.NoErr(p.Configure(ctx, map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+	}))
+
+	input := `{
+		"resourceType": "Bundle",
+		"entry": [
+			{"resource": {"resourceType": "Patient", "id": "1", "managingOrganization": {"reference": "Organization/org1"},
+				"name": [{"family": ["Smith"], "given": ["John"]}], "birthDate": "1990-01-01"}},
+			{"resource": {"resourceType": "Patient", "id": "2", "managingOrganization": {"reference": "urn:uuid:5e3c"},
+				"name": [{"family": ["Doe"], "given": ["Jane"]}], "birthDate": "1985-05-05"}},
+			{"fullUrl": "urn:uuid:5e3c", "resource": {"resourceType": "Organization", "id": "org2", "name": "Clinic"}},
+			{"resource": {"resourceType": "Organization", "id": "org1", "name": "General Hospital"}}
+		]
+	}`
+	result := p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(input)}}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+	segments := splitHL7Message(rec.Payload.After.(opencdc.StructuredData)["hl7"].(string))
+	is.Equal(splitHL7Field(segments[1])[3], "General Hospital") // MSH-4 of the first message
+	is.Equal(splitHL7Field(segments[3])[3], "Clinic")           // MSH-4 of the second message
+}
+
+func TestRoundTrip_ManagingOrganization(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|APP|General \\T\\ Co^1.2.3^ISO|||20230815120000||ADT^A01|123|P|2.5\r" +
+		"PID|1||123^^^^MR||Smith^John||19900101|M"
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.ManagingOrganization, &Reference{Display: "General & Co"})
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[0])[3], "General \\T\\ Co") // MSH-4
+
+	// the placeholder of patients without a managing organization
+	patient.ManagingOrganization = nil
+	hl7Message, err = p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	msg, err = parseHL7Message(hl7Message, parseOptions{})
+	is.NoErr(err)
+	patient, err = p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.ManagingOrganization, nil)
+}
@@ -8,29 +8,370 @@ import (
 )
 
 const (
-	ProcessorConfigInputType  = "inputType"
-	ProcessorConfigOutputType = "outputType"
+	ProcessorConfigAgeInBirthDate            = "ageInBirthDate"
+	ProcessorConfigAggregateErrors           = "aggregateErrors"
+	ProcessorConfigCharset                   = "charset"
+	ProcessorConfigCompressOutput            = "compressOutput"
+	ProcessorConfigConcurrency               = "concurrency"
+	ProcessorConfigCountryFormat             = "countryFormat"
+	ProcessorConfigDataAbsentGender          = "dataAbsentGender"
+	ProcessorConfigDefaultIdentifierSystem   = "defaultIdentifierSystem"
+	ProcessorConfigDefaults                  = "defaults"
+	ProcessorConfigDg1AsCondition            = "dg1AsCondition"
+	ProcessorConfigErrorMode                 = "errorMode"
+	ProcessorConfigExcludeExpiredIdentifiers = "excludeExpiredIdentifiers"
+	ProcessorConfigFhirDefaults              = "fhirDefaults"
+	ProcessorConfigFieldMappings             = "fieldMappings"
+	ProcessorConfigGenderMap                 = "genderMap"
+	ProcessorConfigGenerateNarrative         = "generateNarrative"
+	ProcessorConfigHeaderTagSystems          = "headerTagSystems"
+	ProcessorConfigHistoricalNameType        = "historicalNameType"
+	ProcessorConfigHl7V3Format               = "hl7v3Format"
+	ProcessorConfigIn1AsCoverage             = "in1AsCoverage"
+	ProcessorConfigIncludeActive             = "includeActive"
+	ProcessorConfigIncludeErrorMetadata      = "includeErrorMetadata"
+	ProcessorConfigIncludeResourceType       = "includeResourceType"
+	ProcessorConfigIncludeSourceBinary       = "includeSourceBinary"
+	ProcessorConfigInputType                 = "inputType"
+	ProcessorConfigJsonIndent                = "jsonIndent"
+	ProcessorConfigMatchKeyHash              = "matchKeyHash"
+	ProcessorConfigMllpFraming               = "mllpFraming"
+	ProcessorConfigNk1AsRelatedPerson        = "nk1AsRelatedPerson"
+	ProcessorConfigOnInvalidFHIRType         = "onInvalidFHIRType"
+	ProcessorConfigOnUnsupportedResource     = "onUnsupportedResource"
+	ProcessorConfigOutputCharset             = "outputCharset"
+	ProcessorConfigOutputType                = "outputType"
+	ProcessorConfigParseMode                 = "parseMode"
+	ProcessorConfigPreserveUnknownSegments   = "preserveUnknownSegments"
+	ProcessorConfigPrettyPrint               = "prettyPrint"
+	ProcessorConfigPrimaryIdentifierType     = "primaryIdentifierType"
+	ProcessorConfigSegmentGrammar            = "segmentGrammar"
+	ProcessorConfigSegmentTerminator         = "segmentTerminator"
+	ProcessorConfigTelecomPeriod             = "telecomPeriod"
+	ProcessorConfigTimezone                  = "timezone"
+	ProcessorConfigTxaAsComposition          = "txaAsComposition"
+	ProcessorConfigValidateFieldLengths      = "validateFieldLengths"
+	ProcessorConfigValidateOnly              = "validateOnly"
+	ProcessorConfigValidateSegments          = "validateSegments"
+	ProcessorConfigValidateSetID             = "validateSetID"
+	ProcessorConfigVerifyOutput              = "verifyOutput"
 )
 
 func (ProcessorConfig) Parameters() map[string]config.Parameter {
 	return map[string]config.Parameter{
+		ProcessorConfigAgeInBirthDate: {
+			Default:     "error",
+			Description: "AgeInBirthDate controls how HL7 v2 messages carrying an age instead of\na date in the birth date field are handled. \"error\" rejects them,\n\"estimate\" replaces the age with the approximate birth year.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"error", "estimate"}},
+			},
+		},
+		ProcessorConfigAggregateErrors: {
+			Default:     "false",
+			Description: "AggregateErrors reports all validation problems of an HL7 v2 message,\nsuch as missing fields, unknown segments in strict mode or over-long\nfields, in a single error instead of failing on the first one.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigCharset: {
+			Default:     "utf-8",
+			Description: "Charset is the character set of HL7 v2 input not declaring one in\nMSH-18: \"utf-8\", \"iso-8859-1\" (Latin-1) or \"windows-1252\". Input is\ntranscoded to UTF-8 before parsing, except for messages wrapped in\nJSON, which is UTF-8.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"utf-8", "iso-8859-1", "windows-1252"}},
+			},
+		},
+		ProcessorConfigCompressOutput: {
+			Default:     "false",
+			Description: "CompressOutput gzips the FHIR JSON and HL7v3 XML output, e.g. large\nBundles, and sets the hl7.contentEncoding metadata to \"gzip\". HL7 v2\noutput is not compressed.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigConcurrency: {
+			Default:     "1",
+			Description: "Concurrency is the number of records of a batch converted in parallel.\nThe order of the processed records is preserved.",
+			Type:        config.ParameterTypeInt,
+			Validations: []config.Validation{
+				config.ValidationGreaterThan{V: 0},
+			},
+		},
+		ProcessorConfigCountryFormat: {
+			Default:     "",
+			Description: "CountryFormat normalizes address countries in both directions to ISO\n3166-1 alpha-2 codes, alpha-3 codes or country names. Unknown countries\nare left untouched, as are all countries when not set.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"alpha2", "alpha3", "name"}},
+			},
+		},
+		ProcessorConfigDataAbsentGender: {
+			Default:     "unknown",
+			Description: "DataAbsentGender is the HL7 v2 administrative sex (PID-8) of FHIR\nPatients without a gender but with a data-absent-reason extension on\nit: \"unknown\" writes U, \"empty\" leaves the field empty.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"unknown", "empty"}},
+			},
+		},
+		ProcessorConfigDefaultIdentifierSystem: {
+			Default:     "",
+			Description: "DefaultIdentifierSystem is the system URI of the FHIR identifiers\nconverted from HL7 v2 identifiers without an assigning authority\n(CX-4), e.g. urn:oid:2.16.840.1.113883.19.5. Such identifiers have\nno system when empty.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigDefaults: {
+			Default:     "",
+			Description: "Defaults is a JSON object with the values of fields left empty in\ngenerated HL7 v2 messages, e.g. {\"MSH-4\": \"MAIN_HOSPITAL\"}. Paths use\nthe SEG-field notation; values are raw HL7 and may contain components.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigDg1AsCondition: {
+			Default:     "false",
+			Description: "DG1AsCondition wraps the generated FHIR Patient in a Bundle that also\nholds every diagnosis (DG1) as a Condition resource. The ICD code system\nis taken from DG1-3.3 or, when missing, detected from the code format.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigErrorMode: {
+			Default:     "errorRecord",
+			Description: "ErrorMode controls how records that can not be converted are returned.\n\"errorRecord\" returns them as errors, \"operationOutcome\" returns a\nrecord holding a FHIR OperationOutcome describing the error, so it\ntravels in-band to FHIR consumers.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"errorRecord", "operationOutcome"}},
+			},
+		},
+		ProcessorConfigExcludeExpiredIdentifiers: {
+			Default:     "false",
+			Description: "ExcludeExpiredIdentifiers drops PID-3 identifiers whose expiration date\n(CX-8) lies in the past from generated FHIR resources. They are kept\nwith use \"old\" and an ended period otherwise.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigFhirDefaults: {
+			Default:     "",
+			Description: "FHIRDefaults is a JSON object with the values of fields left empty in\nFHIR Patients converted from HL7 v2 and HL7v3, e.g.\n{\"address.country\": \"US\"}. Address fields apply to every address. The\nvalues of gender, birthDate and address.use must be valid FHIR values.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigFieldMappings: {
+			Default:     "",
+			Description: "FieldMappings is a JSON object overriding where logical fields are read\nfrom in HL7 v2 messages, e.g. {\"patientId\": \"PID-2\"}. Paths use the\nSEG-field[.component] notation.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigGenderMap: {
+			Default:     "",
+			Description: "GenderMap is a JSON object mapping site specific gender codes of HL7 v2\nand HL7v3 input to FHIR genders, e.g. {\"O\": \"other\"}. It extends and\noverrides the standard codes; the targets must be FHIR genders.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigGenerateNarrative: {
+			Default:     "false",
+			Description: "GenerateNarrative adds a generated text narrative (text.div) to the\nFHIR Patients converted from HL7 v2, summarizing the name, gender,\nbirth date, identifiers and addresses.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigHeaderTagSystems: {
+			Default:     "",
+			Description: "HeaderTagSystems is a JSON object naming the FHIR meta tag systems the\nsending and receiving application and facility (MSH-3 to MSH-6) of\ngenerated HL7 v2 messages are read from, e.g.\n{\"MSH-3\": \"http://example.org/sending-application\"}. The code of the\nfirst tag with the system is used; the defaults apply otherwise.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigHistoricalNameType: {
+			Default:     "NOUSE",
+			Description: "HistoricalNameType is the HL7 name type code (XPN-7) emitted for FHIR\nnames that are no longer in use, i.e. names with use \"old\" or a period\nthat ended in the past.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigHl7V3Format: {
+			Default:     "bare",
+			Description: "HL7V3Format is the structure of HL7v3 input: \"bare\" for a Patient root\nelement, \"cda\" for a CDA ClinicalDocument holding the patient under\nrecordTarget/patientRole.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"bare", "cda"}},
+			},
+		},
+		ProcessorConfigIn1AsCoverage: {
+			Default:     "false",
+			Description: "IN1AsCoverage wraps the generated FHIR Patient in a Bundle that also\nholds every insurance (IN1) as a Coverage resource, ordered by IN1-1\n(1 for the primary insurer, 2 for the secondary).",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigIncludeActive: {
+			Default:     "false",
+			Description: "IncludeActive sets the FHIR Patient.active flag from the trigger event\nof HL7 v2 messages: false for events deleting the patient record (A23,\nA29), true otherwise.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigIncludeErrorMetadata: {
+			Default:     "false",
+			Description: "IncludeErrorMetadata attaches the error classification and the failed\nraw input to the errors of records that could not be converted, so a\ndead-letter connector can triage them.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigIncludeResourceType: {
+			Default:     "true",
+			Description: "IncludeResourceType sets the resourceType field of generated FHIR\nresources. Disable it for consumers expecting a bare Patient object.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigIncludeSourceBinary: {
+			Default:     "false",
+			Description: "IncludeSourceBinary wraps the generated FHIR Patient in a Bundle that\nalso holds the original HL7 message as a Binary resource, for lossless\narchival.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
 		ProcessorConfigInputType: {
 			Default:     "",
-			Description: "",
+			Description: "InputType is the type of the input records. The hl7.inputType metadata\nof a record takes precedence. \"auto\" detects the type of records\nwithout metadata from the payload.",
 			Type:        config.ParameterTypeString,
 			Validations: []config.Validation{
 				config.ValidationRequired{},
-				config.ValidationInclusion{List: []string{"fhir", "hl7", "hl7v3"}},
+				config.ValidationInclusion{List: []string{"fhir", "hl7", "hl7v3", "auto"}},
+			},
+		},
+		ProcessorConfigJsonIndent: {
+			Default:     "2",
+			Description: "JSONIndent is the indentation of pretty-printed FHIR JSON: the number\nof spaces per level (at most 8) or \"tab\" to indent with tabs.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigMatchKeyHash: {
+			Default:     "",
+			Description: "MatchKeyHash emits a patient matching key in the hl7.matchKey metadata\nkey, for downstream deduplication: the hex encoded hash of the\nnormalized family name, given name, birth date and MRN. It selects the\nhash function, \"sha256\", \"sha1\" or \"md5\". No key is emitted when not\nset.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"sha256", "sha1", "md5"}},
+			},
+		},
+		ProcessorConfigMllpFraming: {
+			Default:     "false",
+			Description: "MLLPFraming strips the MLLP frame (<VT>message<FS><CR>) from HL7 v2\ninput and wraps HL7 v2 output in it. Input without a frame is accepted\nas well.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigNk1AsRelatedPerson: {
+			Default:     "false",
+			Description: "NK1AsRelatedPerson wraps the generated FHIR Patient in a Bundle that\nalso holds every next of kin (NK1) as a RelatedPerson resource. The\nnext of kin are still listed in Patient.contact.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigOnInvalidFHIRType: {
+			Default:     "error",
+			Description: "OnInvalidFHIRType controls how FHIR fields of the wrong JSON type, e.g.\na numeric gender, are handled. \"error\" rejects the record with an error\nnaming the field, \"ignore\" drops the field and converts the rest.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"error", "ignore"}},
 			},
 		},
+		ProcessorConfigOnUnsupportedResource: {
+			Default:     "error",
+			Description: "OnUnsupportedResource controls how FHIR input that is neither a Patient\nnor, for HL7 v2 output, a Bundle is handled. \"error\" rejects the\nrecord, \"pass\" passes it through unchanged. Input without a\nresourceType is converted as a Patient.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"pass", "error"}},
+			},
+		},
+		ProcessorConfigOutputCharset: {
+			Default:     "",
+			Description: "OutputCharset is the character set (HL7 table 0211, e.g. \"UNICODE\nUTF-8\") declared in MSH-18 of generated HL7 v2 messages, one of the\ncharacter sets the Charset option reads. MSH-18 is left empty when not\nset.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
 		ProcessorConfigOutputType: {
 			Default:     "",
-			Description: "",
+			Description: "OutputType is the type the records are converted to. The\nhl7.outputType metadata of a record takes precedence. \"auto\" converts\nrecords without metadata from FHIR to HL7 v2 and from HL7 to FHIR.",
 			Type:        config.ParameterTypeString,
 			Validations: []config.Validation{
 				config.ValidationRequired{},
-				config.ValidationInclusion{List: []string{"fhir", "hl7", "hl7v3"}},
+				config.ValidationInclusion{List: []string{"fhir", "hl7", "hl7v3", "auto"}},
+			},
+		},
+		ProcessorConfigParseMode: {
+			Default:     "lenient",
+			Description: "ParseMode controls how HL7 v2 input is parsed. In strict mode messages\nwith missing expected fields or unknown segments are rejected, in\nlenient mode the processor extracts what it can and reports the dropped\ndata as warnings in the record metadata.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"strict", "lenient"}},
+			},
+		},
+		ProcessorConfigPreserveUnknownSegments: {
+			Default:     "false",
+			Description: "PreserveUnknownSegments keeps the segments an HL7 v2 message generated\nfrom a FHIR Patient does not carry (e.g. Z-segments, EVN, IN1) in the\nrecord metadata when converting HL7 v2 to FHIR and appends them, in\ntheir original order, to the HL7 v2 message generated from a FHIR\nrecord carrying that metadata. EVN is put back after MSH.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigPrettyPrint: {
+			Default:     "false",
+			Description: "PrettyPrint indents generated FHIR JSON, by JSONIndent, and HL7v3 XML,\nby two spaces. Output is compact otherwise.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigPrimaryIdentifierType: {
+			Default:     "MR",
+			Description: "PrimaryIdentifierType is the identifier type code (HL7 table 0203) of the\nFHIR identifier emitted as the primary PID-3 repetition.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigSegmentGrammar: {
+			Default:     "",
+			Description: "SegmentGrammar is a JSON object extending and overriding the required\nsegments checked by ValidateSegments, keyed by message type, e.g.\n{\"ADT^A28\": [\"MSH\", \"EVN\", \"PID\"]}.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigSegmentTerminator: {
+			Default:     "\\r",
+			Description: "SegmentTerminator separates the segments of generated HL7 v2 messages.\nIt is written in escaped form: \"\\r\" (as required by the HL7 standard),\n\"\\n\" or \"\\r\\n\".",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"\\r", "\\n", "\\r\\n"}},
 			},
 		},
+		ProcessorConfigTelecomPeriod: {
+			Default:     "false",
+			Description: "TelecomPeriod emits the validity period of FHIR telecoms as the\neffective start (XTN-13) and expiration (XTN-14) dates of the phone\nnumbers (PID-13/PID-14) of generated HL7 v2 messages.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigTimezone: {
+			Default:     "UTC",
+			Description: "Timezone is the IANA time zone (e.g. \"America/New_York\") of the\nmessage timestamps (MSH-7, BHS-7) of generated HL7 v2 messages, which\ncarry its numeric UTC offset. HL7 v2 times without UTC offset are read\nin it too, FHIR dateTimes require an offset.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigTxaAsComposition: {
+			Default:     "false",
+			Description: "TXAAsComposition wraps the generated FHIR Patient in a Bundle that also\nholds the document of MDM messages (TXA and the OBX segments with its\ncontent) as a Composition resource.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigValidateFieldLengths: {
+			Default:     "none",
+			Description: "ValidateFieldLengths checks the fields of HL7 v2 input against the\nmaximum lengths defined by the message's HL7 version (MSH-12).\n\"truncate\" cuts over-long values and reports a warning, \"error\" rejects\nthe message. Messages of versions without known lengths are not\nchecked and get a warning.",
+			Type:        config.ParameterTypeString,
+			Validations: []config.Validation{
+				config.ValidationInclusion{List: []string{"none", "truncate", "error"}},
+			},
+		},
+		ProcessorConfigValidateOnly: {
+			Default:     "false",
+			Description: "ValidateOnly parses and converts records but discards the output:\nconverted records are returned unchanged, with a summary of the\ndiscarded output in the hl7.validation metadata key, and records that\nfail are returned as errors. It allows checking a batch of messages\nbefore a migration.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigValidateSegments: {
+			Default:     "false",
+			Description: "ValidateSegments rejects HL7 v2 messages missing a segment their\nmessage type requires, e.g. an ADT^A01 without PV1.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigValidateSetID: {
+			Default:     "false",
+			Description: "ValidateSetID rejects HL7 v2 messages with a non-numeric PID-1 set ID\nand, for messages with several PID segments, orders the patients by\nset ID instead of by segment order.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
+		ProcessorConfigVerifyOutput: {
+			Default:     "false",
+			Description: "VerifyOutput parses every generated HL7 v2 message again and fails the\nconversion if the patient identifiers, name or address do not\nround-trip, e.g. because of a delimiter that was not escaped.",
+			Type:        config.ParameterTypeBool,
+			Validations: []config.Validation{},
+		},
 	}
 }
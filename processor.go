@@ -4,8 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/conduitio/conduit-commons/config"
@@ -17,32 +22,387 @@ import (
 type Processor struct {
 	sdk.UnimplementedProcessor
 	config ProcessorConfig
+
+	fieldMappings  map[string]fieldPath
+	fieldDefaults  map[fieldPath]string
+	fhirDefaults   map[string]string
+	jsonIndent     string
+	genderMap      map[string]string
+	segmentGrammar map[string][]string
+	headerTags     map[int]string
+	location       *time.Location
+	// clock returns the current time of generated message timestamps and of
+	// the checks for ended periods and estimated ages. Tests replace it for
+	// deterministic output.
+	clock func() time.Time
 }
 
 // ProcessorConfig holds the configuration for the processor.
 type ProcessorConfig struct {
-	InputType  string `json:"inputType" validate:"required,inclusion=fhir|hl7|hl7v3"`
-	OutputType string `json:"outputType" validate:"required,inclusion=fhir|hl7|hl7v3"`
+	// InputType is the type of the input records. The hl7.inputType metadata
+	// of a record takes precedence. "auto" detects the type of records
+	// without metadata from the payload.
+	InputType string `json:"inputType" validate:"required,inclusion=fhir|hl7|hl7v3|auto"`
+	// OutputType is the type the records are converted to. The
+	// hl7.outputType metadata of a record takes precedence. "auto" converts
+	// records without metadata from FHIR to HL7 v2 and from HL7 to FHIR.
+	OutputType string `json:"outputType" validate:"required,inclusion=fhir|hl7|hl7v3|auto"`
+	// FieldMappings is a JSON object overriding where logical fields are read
+	// from in HL7 v2 messages, e.g. {"patientId": "PID-2"}. Paths use the
+	// SEG-field[.component] notation.
+	FieldMappings string `json:"fieldMappings"`
+	// Defaults is a JSON object with the values of fields left empty in
+	// generated HL7 v2 messages, e.g. {"MSH-4": "MAIN_HOSPITAL"}. Paths use
+	// the SEG-field notation; values are raw HL7 and may contain components.
+	Defaults string `json:"defaults"`
+	// FHIRDefaults is a JSON object with the values of fields left empty in
+	// FHIR Patients converted from HL7 v2 and HL7v3, e.g.
+	// {"address.country": "US"}. Address fields apply to every address. The
+	// values of gender, birthDate and address.use must be valid FHIR values.
+	FHIRDefaults string `json:"fhirDefaults"`
+	// GenderMap is a JSON object mapping site specific gender codes of HL7 v2
+	// and HL7v3 input to FHIR genders, e.g. {"O": "other"}. It extends and
+	// overrides the standard codes; the targets must be FHIR genders.
+	GenderMap string `json:"genderMap"`
+	// DataAbsentGender is the HL7 v2 administrative sex (PID-8) of FHIR
+	// Patients without a gender but with a data-absent-reason extension on
+	// it: "unknown" writes U, "empty" leaves the field empty.
+	DataAbsentGender string `json:"dataAbsentGender" default:"unknown" validate:"inclusion=unknown|empty"`
+	// ParseMode controls how HL7 v2 input is parsed. In strict mode messages
+	// with missing expected fields or unknown segments are rejected, in
+	// lenient mode the processor extracts what it can and reports the dropped
+	// data as warnings in the record metadata.
+	ParseMode string `json:"parseMode" default:"lenient" validate:"inclusion=strict|lenient"`
+	// AggregateErrors reports all validation problems of an HL7 v2 message,
+	// such as missing fields, unknown segments in strict mode or over-long
+	// fields, in a single error instead of failing on the first one.
+	AggregateErrors bool `json:"aggregateErrors" default:"false"`
+	// PrimaryIdentifierType is the identifier type code (HL7 table 0203) of the
+	// FHIR identifier emitted as the primary PID-3 repetition.
+	PrimaryIdentifierType string `json:"primaryIdentifierType" default:"MR"`
+	// ExcludeExpiredIdentifiers drops PID-3 identifiers whose expiration date
+	// (CX-8) lies in the past from generated FHIR resources. They are kept
+	// with use "old" and an ended period otherwise.
+	ExcludeExpiredIdentifiers bool `json:"excludeExpiredIdentifiers" default:"false"`
+	// DefaultIdentifierSystem is the system URI of the FHIR identifiers
+	// converted from HL7 v2 identifiers without an assigning authority
+	// (CX-4), e.g. urn:oid:2.16.840.1.113883.19.5. Such identifiers have
+	// no system when empty.
+	DefaultIdentifierSystem string `json:"defaultIdentifierSystem"`
+	// HistoricalNameType is the HL7 name type code (XPN-7) emitted for FHIR
+	// names that are no longer in use, i.e. names with use "old" or a period
+	// that ended in the past.
+	HistoricalNameType string `json:"historicalNameType" default:"NOUSE"`
+	// IncludeErrorMetadata attaches the error classification and the failed
+	// raw input to the errors of records that could not be converted, so a
+	// dead-letter connector can triage them.
+	IncludeErrorMetadata bool `json:"includeErrorMetadata" default:"false"`
+	// ErrorMode controls how records that can not be converted are returned.
+	// "errorRecord" returns them as errors, "operationOutcome" returns a
+	// record holding a FHIR OperationOutcome describing the error, so it
+	// travels in-band to FHIR consumers.
+	ErrorMode string `json:"errorMode" default:"errorRecord" validate:"inclusion=errorRecord|operationOutcome"`
+	// OnUnsupportedResource controls how FHIR input that is neither a Patient
+	// nor, for HL7 v2 output, a Bundle is handled. "error" rejects the
+	// record, "pass" passes it through unchanged. Input without a
+	// resourceType is converted as a Patient.
+	OnUnsupportedResource string `json:"onUnsupportedResource" default:"error" validate:"inclusion=pass|error"`
+	// OnInvalidFHIRType controls how FHIR fields of the wrong JSON type, e.g.
+	// a numeric gender, are handled. "error" rejects the record with an error
+	// naming the field, "ignore" drops the field and converts the rest.
+	OnInvalidFHIRType string `json:"onInvalidFHIRType" default:"error" validate:"inclusion=error|ignore"`
+	// IncludeActive sets the FHIR Patient.active flag from the trigger event
+	// of HL7 v2 messages: false for events deleting the patient record (A23,
+	// A29), true otherwise.
+	IncludeActive bool `json:"includeActive" default:"false"`
+	// GenerateNarrative adds a generated text narrative (text.div) to the
+	// FHIR Patients converted from HL7 v2, summarizing the name, gender,
+	// birth date, identifiers and addresses.
+	GenerateNarrative bool `json:"generateNarrative" default:"false"`
+	// AgeInBirthDate controls how HL7 v2 messages carrying an age instead of
+	// a date in the birth date field are handled. "error" rejects them,
+	// "estimate" replaces the age with the approximate birth year.
+	AgeInBirthDate string `json:"ageInBirthDate" default:"error" validate:"inclusion=error|estimate"`
+	// ValidateFieldLengths checks the fields of HL7 v2 input against the
+	// maximum lengths defined by the message's HL7 version (MSH-12).
+	// "truncate" cuts over-long values and reports a warning, "error" rejects
+	// the message. Messages of versions without known lengths are not
+	// checked and get a warning.
+	ValidateFieldLengths string `json:"validateFieldLengths" default:"none" validate:"inclusion=none|truncate|error"`
+	// IncludeResourceType sets the resourceType field of generated FHIR
+	// resources. Disable it for consumers expecting a bare Patient object.
+	IncludeResourceType bool `json:"includeResourceType" default:"true"`
+	// PrettyPrint indents generated FHIR JSON, by JSONIndent, and HL7v3 XML,
+	// by two spaces. Output is compact otherwise.
+	PrettyPrint bool `json:"prettyPrint" default:"false"`
+	// JSONIndent is the indentation of pretty-printed FHIR JSON: the number
+	// of spaces per level (at most 8) or "tab" to indent with tabs.
+	JSONIndent string `json:"jsonIndent" default:"2"`
+	// CompressOutput gzips the FHIR JSON and HL7v3 XML output, e.g. large
+	// Bundles, and sets the hl7.contentEncoding metadata to "gzip". HL7 v2
+	// output is not compressed.
+	CompressOutput bool `json:"compressOutput" default:"false"`
+	// IncludeSourceBinary wraps the generated FHIR Patient in a Bundle that
+	// also holds the original HL7 message as a Binary resource, for lossless
+	// archival.
+	IncludeSourceBinary bool `json:"includeSourceBinary" default:"false"`
+	// NK1AsRelatedPerson wraps the generated FHIR Patient in a Bundle that
+	// also holds every next of kin (NK1) as a RelatedPerson resource. The
+	// next of kin are still listed in Patient.contact.
+	NK1AsRelatedPerson bool `json:"nk1AsRelatedPerson" default:"false"`
+	// TXAAsComposition wraps the generated FHIR Patient in a Bundle that also
+	// holds the document of MDM messages (TXA and the OBX segments with its
+	// content) as a Composition resource.
+	TXAAsComposition bool `json:"txaAsComposition" default:"false"`
+	// DG1AsCondition wraps the generated FHIR Patient in a Bundle that also
+	// holds every diagnosis (DG1) as a Condition resource. The ICD code system
+	// is taken from DG1-3.3 or, when missing, detected from the code format.
+	DG1AsCondition bool `json:"dg1AsCondition" default:"false"`
+	// IN1AsCoverage wraps the generated FHIR Patient in a Bundle that also
+	// holds every insurance (IN1) as a Coverage resource, ordered by IN1-1
+	// (1 for the primary insurer, 2 for the secondary).
+	IN1AsCoverage bool `json:"in1AsCoverage" default:"false"`
+	// Concurrency is the number of records of a batch converted in parallel.
+	// The order of the processed records is preserved.
+	Concurrency int `json:"concurrency" default:"1" validate:"greater-than=0"`
+	// SegmentTerminator separates the segments of generated HL7 v2 messages.
+	// It is written in escaped form: "\r" (as required by the HL7 standard),
+	// "\n" or "\r\n".
+	SegmentTerminator string `json:"segmentTerminator" default:"\\r" validate:"inclusion=\\r|\\n|\\r\\n"`
+	// VerifyOutput parses every generated HL7 v2 message again and fails the
+	// conversion if the patient identifiers, name or address do not
+	// round-trip, e.g. because of a delimiter that was not escaped.
+	VerifyOutput bool `json:"verifyOutput" default:"false"`
+	// ValidateOnly parses and converts records but discards the output:
+	// converted records are returned unchanged, with a summary of the
+	// discarded output in the hl7.validation metadata key, and records that
+	// fail are returned as errors. It allows checking a batch of messages
+	// before a migration.
+	ValidateOnly bool `json:"validateOnly" default:"false"`
+	// MatchKeyHash emits a patient matching key in the hl7.matchKey metadata
+	// key, for downstream deduplication: the hex encoded hash of the
+	// normalized family name, given name, birth date and MRN. It selects the
+	// hash function, "sha256", "sha1" or "md5". No key is emitted when not
+	// set.
+	MatchKeyHash string `json:"matchKeyHash" validate:"inclusion=sha256|sha1|md5"`
+	// HL7V3Format is the structure of HL7v3 input: "bare" for a Patient root
+	// element, "cda" for a CDA ClinicalDocument holding the patient under
+	// recordTarget/patientRole.
+	HL7V3Format string `json:"hl7v3Format" default:"bare" validate:"inclusion=bare|cda"`
+	// MLLPFraming strips the MLLP frame (<VT>message<FS><CR>) from HL7 v2
+	// input and wraps HL7 v2 output in it. Input without a frame is accepted
+	// as well.
+	MLLPFraming bool `json:"mllpFraming" default:"false"`
+	// OutputCharset is the character set (HL7 table 0211, e.g. "UNICODE
+	// UTF-8") declared in MSH-18 of generated HL7 v2 messages, one of the
+	// character sets the Charset option reads. MSH-18 is left empty when not
+	// set.
+	OutputCharset string `json:"outputCharset"`
+	// HeaderTagSystems is a JSON object naming the FHIR meta tag systems the
+	// sending and receiving application and facility (MSH-3 to MSH-6) of
+	// generated HL7 v2 messages are read from, e.g.
+	// {"MSH-3": "http://example.org/sending-application"}. The code of the
+	// first tag with the system is used; the defaults apply otherwise.
+	HeaderTagSystems string `json:"headerTagSystems"`
+	// Charset is the character set of HL7 v2 input not declaring one in
+	// MSH-18: "utf-8", "iso-8859-1" (Latin-1) or "windows-1252". Input is
+	// transcoded to UTF-8 before parsing, except for messages wrapped in
+	// JSON, which is UTF-8.
+	Charset string `json:"charset" default:"utf-8" validate:"inclusion=utf-8|iso-8859-1|windows-1252"`
+	// Timezone is the IANA time zone (e.g. "America/New_York") of the
+	// message timestamps (MSH-7, BHS-7) of generated HL7 v2 messages, which
+	// carry its numeric UTC offset. HL7 v2 times without UTC offset are read
+	// in it too, FHIR dateTimes require an offset.
+	Timezone string `json:"timezone" default:"UTC"`
+	// CountryFormat normalizes address countries in both directions to ISO
+	// 3166-1 alpha-2 codes, alpha-3 codes or country names. Unknown countries
+	// are left untouched, as are all countries when not set.
+	CountryFormat string `json:"countryFormat" validate:"inclusion=alpha2|alpha3|name"`
+	// PreserveUnknownSegments keeps the segments an HL7 v2 message generated
+	// from a FHIR Patient does not carry (e.g. Z-segments, EVN, IN1) in the
+	// record metadata when converting HL7 v2 to FHIR and appends them, in
+	// their original order, to the HL7 v2 message generated from a FHIR
+	// record carrying that metadata. EVN is put back after MSH.
+	PreserveUnknownSegments bool `json:"preserveUnknownSegments" default:"false"`
+	// TelecomPeriod emits the validity period of FHIR telecoms as the
+	// effective start (XTN-13) and expiration (XTN-14) dates of the phone
+	// numbers (PID-13/PID-14) of generated HL7 v2 messages.
+	TelecomPeriod bool `json:"telecomPeriod" default:"false"`
+	// ValidateSetID rejects HL7 v2 messages with a non-numeric PID-1 set ID
+	// and, for messages with several PID segments, orders the patients by
+	// set ID instead of by segment order.
+	ValidateSetID bool `json:"validateSetID" default:"false"`
+	// ValidateSegments rejects HL7 v2 messages missing a segment their
+	// message type requires, e.g. an ADT^A01 without PV1.
+	ValidateSegments bool `json:"validateSegments" default:"false"`
+	// SegmentGrammar is a JSON object extending and overriding the required
+	// segments checked by ValidateSegments, keyed by message type, e.g.
+	// {"ADT^A28": ["MSH", "EVN", "PID"]}.
+	SegmentGrammar string `json:"segmentGrammar"`
 }
 
+// parseModeStrict is the ParseMode rejecting incomplete messages.
+const parseModeStrict = "strict"
+
+// onUnsupportedResourcePass is the OnUnsupportedResource mode passing
+// records through unchanged.
+const onUnsupportedResourcePass = "pass"
+
+// ageInBirthDateEstimate is the AgeInBirthDate mode estimating the birth year.
+const ageInBirthDateEstimate = "estimate"
+
+// metadataWarnings is the metadata key holding warnings produced while
+// parsing in lenient mode.
+const metadataWarnings = "hl7.warnings"
+
+// metadataUnknownSegments is the metadata key holding the JSON array of raw
+// segments kept by PreserveUnknownSegments.
+const metadataUnknownSegments = "hl7.unknownSegments"
+
+// metadataNullFields is the metadata key holding the JSON array of the PID
+// fields sent as the HL7 explicit null "", e.g. ["PID-5"].
+const metadataNullFields = "hl7.nullFields"
+
+// hl7Null is the HL7 v2 field value deleting the value at the receiver, as
+// opposed to an empty field leaving it unchanged.
+const hl7Null = `""`
+
 // FHIRPatient represents a FHIR Patient resource structure.
 type FHIRPatient struct {
-	ID   string `json:"id"`
-	Name []struct {
-		Family []string `json:"family"`
-		Given  []string `json:"given"`
-	} `json:"name"`
-	BirthDate string `json:"birthDate"`
-	Gender    string `json:"gender"`
-	Address   []struct {
-		Line       []string `json:"line"`
-		City       string   `json:"city"`
-		State      string   `json:"state"`
-		PostalCode string   `json:"postalCode"`
-		Country    string   `json:"country"`
-	} `json:"address"`
+	ResourceType string         `json:"resourceType,omitempty"`
+	ID           string         `json:"id"`
+	Meta         *Meta          `json:"meta,omitempty"`
+	Text         *Narrative     `json:"text,omitempty"`
+	Active       *bool          `json:"active,omitempty"`
+	Extension    []Extension    `json:"extension,omitempty"`
+	Identifier   []Identifier   `json:"identifier,omitempty"`
+	Name         []HumanName    `json:"name"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+	BirthDate    string         `json:"birthDate"`
+	Gender       string         `json:"gender"`
+	// GenderElement carries the extensions of gender, e.g. a
+	// data-absent-reason when gender is not known.
+	GenderElement *Element `json:"_gender,omitempty"`
+	// DeceasedBoolean and DeceasedDateTime are the two forms of the
+	// deceased[x] choice; at most one of them is set.
+	DeceasedBoolean  *bool  `json:"deceasedBoolean,omitempty"`
+	DeceasedDateTime string `json:"deceasedDateTime,omitempty"`
+	// DeceasedDateTimeElement keeps a PID-29 timestamp of a precision FHIR
+	// can not represent in an originalText extension.
+	DeceasedDateTimeElement *Element         `json:"_deceasedDateTime,omitempty"`
+	MaritalStatus           *CodeableConcept `json:"maritalStatus,omitempty"`
+	// MultipleBirthBoolean and MultipleBirthInteger are the two forms of the
+	// multipleBirth[x] choice; at most one of them is set.
+	MultipleBirthBoolean *bool            `json:"multipleBirthBoolean,omitempty"`
+	MultipleBirthInteger *int             `json:"multipleBirthInteger,omitempty"`
+	Address              []Address        `json:"address,omitempty"`
+	Contact              []PatientContact `json:"contact,omitempty"`
+	Communication        []Communication  `json:"communication,omitempty"`
+	// Contained holds the resources contained in the patient, such as the
+	// Organization a local managingOrganization reference (#id) points to.
+	Contained []json.RawMessage `json:"contained,omitempty"`
+	// ManagingOrganization is the sending facility (MSH-4) of generated HL7
+	// v2 messages when it has a display or references a contained or, in a
+	// Bundle, another Organization with a name. It is read back from MSH-4
+	// as a display.
+	ManagingOrganization *Reference    `json:"managingOrganization,omitempty"`
+	Link                 []PatientLink `json:"link,omitempty"`
+}
+
+// Communication represents a FHIR Patient.communication.
+type Communication struct {
+	Language  CodeableConcept `json:"language"`
+	Preferred bool            `json:"preferred,omitempty"`
+}
+
+// inactiveTriggerEvents lists the ADT trigger events that delete the patient
+// record.
+var inactiveTriggerEvents = map[string]bool{
+	"A23": true, // delete a patient record
+	"A29": true, // delete person information
+}
+
+// languageSystem is the code system of FHIR communication languages.
+const languageSystem = "urn:ietf:bcp:47"
+
+// Address represents a FHIR Address.
+type Address struct {
+	Use        string   `json:"use,omitempty"`
+	Type       string   `json:"type,omitempty"`
+	Line       []string `json:"line"`
+	City       string   `json:"city"`
+	State      string   `json:"state"`
+	PostalCode string   `json:"postalCode"`
+	Country    string   `json:"country"`
+}
+
+// ContactPoint represents a FHIR ContactPoint.
+type ContactPoint struct {
+	System string  `json:"system,omitempty"`
+	Value  string  `json:"value"`
+	Use    string  `json:"use,omitempty"`
+	Period *Period `json:"period,omitempty"`
+}
+
+// HumanName represents a FHIR HumanName.
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Family []string `json:"family"`
+	Given  []string `json:"given"`
+	Period *Period  `json:"period,omitempty"`
+}
+
+// Period represents a FHIR Period.
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// Identifier represents a FHIR Identifier.
+type Identifier struct {
+	Use    string           `json:"use,omitempty"`
+	Type   *CodeableConcept `json:"type,omitempty"`
+	System string           `json:"system,omitempty"`
+	Value  string           `json:"value"`
+	Period *Period          `json:"period,omitempty"`
+}
+
+// CodeableConcept represents a FHIR CodeableConcept.
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Coding represents a FHIR Coding.
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// Extension represents a FHIR extension.
+type Extension struct {
+	URL                  string           `json:"url"`
+	ValueString          string           `json:"valueString,omitempty"`
+	ValueCode            string           `json:"valueCode,omitempty"`
+	ValueCoding          *Coding          `json:"valueCoding,omitempty"`
+	ValueCodeableConcept *CodeableConcept `json:"valueCodeableConcept,omitempty"`
+	Extension            []Extension      `json:"extension,omitempty"`
+}
+
+// Element holds the extensions of a FHIR primitive value, sent as the
+// property prefixed with an underscore, e.g. _gender.
+type Element struct {
+	Extension []Extension `json:"extension,omitempty"`
 }
 
+// nationalityExtensionURL identifies the FHIR patient-nationality extension.
+// The nationality itself is carried in its "code" sub-extension.
+const nationalityExtensionURL = "http://hl7.org/fhir/StructureDefinition/patient-nationality"
+
+// identifierTypeSystem is the code system of HL7 v2 identifier types (table 0203).
+const identifierTypeSystem = "http://terminology.hl7.org/CodeSystem/v2-0203"
+
 // HL7Message struct to parse incoming HL7
 type HL7Message struct {
 	MSH struct {
@@ -53,47 +413,193 @@ type HL7Message struct {
 		ControlID          string
 	}
 	PID struct {
-		ID        string
-		LastName  string
-		FirstName string
-		BirthDate string
-		Gender    string
-		Address   struct {
-			Street     string
-			City       string
-			State      string
-			PostalCode string
-			Country    string
-		}
+		// SetID is the set ID (PID-1) numbering the PID segments of a
+		// message.
+		SetID       string
+		ID          string
+		Identifiers []PatientIdentifier
+		LastName    string
+		FirstName   string
+		BirthDate   string
+		Gender      string
+		Address     PatientAddress
+		// Addresses holds all repetitions of PID-11, the first one being
+		// Address.
+		Addresses     []PatientAddress
+		Nationality   CodedElement
+		MaritalStatus CodedElement
+		// Language is the primary language (PID-15).
+		Language CodedElement
+		// Race (PID-10) and Ethnicity (PID-22) hold all repetitions
+		Race      []CodedElement
+		Ethnicity []CodedElement
+		// AccountNumber is the patient account number (PID-18), nil if
+		// not sent.
+		AccountNumber *PatientIdentifier
+		// DeathDateTime (PID-29) and DeathIndicator (PID-30, Y/N)
+		DeathDateTime  string
+		DeathIndicator string
+		// MultipleBirthIndicator (PID-24, Y/N) and BirthOrder (PID-25)
+		MultipleBirthIndicator string
+		BirthOrder             string
+		// NullFields lists the fields sent as the explicit null "", e.g.
+		// PID-5. They are parsed as empty fields.
+		NullFields []string
 	}
+	// EVN is the event of ADT messages, nil for messages without an EVN
+	// segment.
+	EVN *Event
+	// NK1 holds the next of kin segments, in message order.
+	NK1 []NextOfKin
+	// MRG names the patient merged into the patient of the message, nil
+	// for messages other than merges.
+	MRG *Merge
+	// TXA is the document header of MDM messages, nil for other messages.
+	TXA *DocumentHeader
+	// OBR holds the observation requests with their observations, in message
+	// order.
+	OBR []ObservationRequest
+	// OBX holds the observation segments not belonging to an OBR, in message
+	// order.
+	OBX []ObservationResult
+	// DG1 holds the diagnosis segments, in message order.
+	DG1 []Diagnosis
+	// IN1 holds the insurance segments, in message order.
+	IN1 []Insurance
+	// UnknownSegments holds the raw segments not regenerated from a FHIR
+	// Patient, in message order, when they are preserved.
+	UnknownSegments []string
+	// Warnings lists data that was dropped while parsing in lenient mode.
+	Warnings []ParseWarning
+}
+
+// ParseWarning describes a problem found while parsing an HL7 v2 message in
+// lenient mode.
+type ParseWarning struct {
+	Segment string `json:"segment"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// PatientIdentifier is a single repetition of PID-3.
+type PatientIdentifier struct {
+	ID                 string
+	AssigningAuthority string
+	IdentifierType     string
+	// EffectiveDate and ExpirationDate are the raw CX-7 and CX-8 timestamps.
+	EffectiveDate  string
+	ExpirationDate string
+}
+
+// PatientAddress is a single repetition of PID-11.
+type PatientAddress struct {
+	Street     string
+	City       string
+	State      string
+	PostalCode string
+	Country    string
+	// Type is the address type (XAD-7), e.g. H for home or M for mailing.
+	Type string
+}
+
+// addressTypeComponent is the XAD component holding the address type.
+const addressTypeComponent = 7
+
+// addressNames lists the logical fields making up PID.Address.
+var addressNames = []string{"street", "city", "state", "postalCode", "country"}
+
+// hl7AddressTypes maps HL7 address types (table 0190) to a FHIR address use
+// and type.
+var hl7AddressTypes = map[string]struct{ use, typ string }{
+	"H":  {use: "home"},
+	"M":  {typ: "postal"},
+	"B":  {use: "work"},
+	"O":  {use: "work"},
+	"C":  {use: "temp"},
+	"BA": {use: "old"},
+	"BI": {use: "billing"},
+}
+
+// CodedElement is an HL7 v2 coded element (CE): identifier^text^coding system.
+type CodedElement struct {
+	Code   string
+	Text   string
+	System string
 }
 
+// hl7V3Namespace is the default XML namespace of HL7 v3 documents.
+const hl7V3Namespace = "urn:hl7-org:v3"
+
 // Add HL7v3 Patient structure
 type HL7V3Patient struct {
 	XMLName xml.Name `xml:"Patient"`
-	ID      string   `xml:"id"`
-	Name    struct {
-		Given  string `xml:"given"`
-		Family string `xml:"family"`
-	} `xml:"name"`
-	Gender struct {
-		Code string `xml:"code"`
-	} `xml:"administrativeGenderCode"`
+	// Xmlns is the default namespace written on generated documents. The
+	// tag of XMLName takes precedence over its namespace when marshaling.
+	Xmlns     string      `xml:"xmlns,attr,omitempty"`
+	ID        string      `xml:"id"`
+	Name      []HL7V3Name `xml:"name"`
+	Gender    HL7V3Gender `xml:"administrativeGenderCode"`
 	BirthTime struct {
 		Value string `xml:"value"`
 	} `xml:"birthTime"`
-	Address struct {
-		Street     string `xml:"streetAddressLine"`
-		City       string `xml:"city"`
-		State      string `xml:"state"`
-		PostalCode string `xml:"postalCode"`
-	} `xml:"addr"`
+	Address []HL7V3Address `xml:"addr"`
+	Telecom []HL7V3Telecom `xml:"telecom"`
 }
 
+// HL7V3Telecom represents an HL7v3 telecom element.
+type HL7V3Telecom struct {
+	Value string `xml:"value,attr"`
+	Use   string `xml:"use,attr,omitempty"`
+}
+
+// HL7V3Name represents an HL7v3 name element.
+type HL7V3Name struct {
+	Given  string `xml:"given"`
+	Family string `xml:"family"`
+}
+
+// HL7V3Address represents an HL7v3 addr element.
+type HL7V3Address struct {
+	Use        string `xml:"use,attr,omitempty"`
+	Street     string `xml:"streetAddressLine"`
+	City       string `xml:"city"`
+	State      string `xml:"state"`
+	PostalCode string `xml:"postalCode"`
+}
+
+// hl7V3AddressUses maps HL7v3 address use codes to FHIR address uses.
+var hl7V3AddressUses = map[string]string{
+	"H":   "home",
+	"HP":  "home",
+	"HV":  "home",
+	"WP":  "work",
+	"TMP": "temp",
+	"OLD": "old",
+	"BAD": "old",
+}
+
+// fhirAddressUses maps FHIR address uses to HL7v3 address use codes.
+var fhirAddressUses = map[string]string{
+	"home": "H",
+	"work": "WP",
+	"temp": "TMP",
+	"old":  "OLD",
+}
+
+// HL7V3Gender represents an HL7v3 administrativeGenderCode element.
+type HL7V3Gender struct {
+	Code       string `xml:"code,omitempty"`
+	CodeSystem string `xml:"codeSystem,attr,omitempty"`
+	NullFlavor string `xml:"nullFlavor,attr,omitempty"`
+}
+
+// administrativeGenderOID is the code system of HL7v3 administrative gender codes.
+const administrativeGenderOID = "2.16.840.1.113883.5.1"
+
 // NewProcessor creates a new processor instance.
 func NewProcessor() sdk.Processor {
 	sdk.Logger(context.Background()).Info().Msg("Creating new HL7 processor instance")
-	return &Processor{}
+	return &Processor{clock: time.Now}
 }
 
 // func NewProcessor() sdk.Processor {
@@ -109,6 +615,58 @@ func (p *Processor) Configure(ctx context.Context, cfg config.Config) error {
 		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
 		return err
 	}
+	p.fieldMappings, err = parseFieldMappings(p.config.FieldMappings)
+	if err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+		return err
+	}
+	p.fieldDefaults, err = parseFieldDefaults(p.config.Defaults)
+	if err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+		return err
+	}
+	p.fhirDefaults, err = parseFHIRDefaults(p.config.FHIRDefaults)
+	if err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+		return err
+	}
+	p.jsonIndent, err = parseJSONIndent(p.config.JSONIndent)
+	if err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+		return err
+	}
+	p.genderMap, err = parseGenderMap(p.config.GenderMap)
+	if err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+		return err
+	}
+	p.segmentGrammar, err = parseSegmentGrammar(p.config.SegmentGrammar)
+	if err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+		return err
+	}
+	p.headerTags, err = parseHeaderTagSystems(p.config.HeaderTagSystems)
+	if err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+		return err
+	}
+	if system := p.config.DefaultIdentifierSystem; system != "" {
+		if u, err := url.Parse(system); err != nil || !u.IsAbs() {
+			err := fmt.Errorf("invalid default identifier system %q: must be an absolute URI", system)
+			sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+			return err
+		}
+	}
+	if err := validateOutputCharset(p.config.OutputCharset); err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+		return err
+	}
+	p.location, err = time.LoadLocation(p.config.Timezone)
+	if err != nil {
+		err = fmt.Errorf("invalid timezone %q: %w", p.config.Timezone, err)
+		sdk.Logger(ctx).Error().Err(err).Msg("Error configuring processor")
+		return err
+	}
 	sdk.Logger(ctx).Info().Msg("Successfully configured HL7 processor")
 	return nil
 }
@@ -126,168 +684,676 @@ func (p *Processor) Specification() (sdk.Specification, error) {
 	}, nil
 }
 
+// parseOptions controls how HL7 v2 messages are parsed.
+type parseOptions struct {
+	// fieldMappings holds the location of each logical field. Defaults are
+	// used when nil.
+	fieldMappings map[string]fieldPath
+	// strict rejects messages with missing expected fields or unknown
+	// segments instead of collecting warnings.
+	strict bool
+	// estimateAge replaces an age found in place of the birth date with the
+	// approximate birth year instead of rejecting the message.
+	estimateAge bool
+	// fieldLengths is the field length validation mode, see
+	// checkFieldLengths. Field lengths are not checked when empty.
+	fieldLengths string
+	// preserveUnknown keeps unknown segments in HL7Message.UnknownSegments
+	// instead of rejecting or ignoring them.
+	preserveUnknown bool
+	// aggregateErrors collects the validation problems of a message into
+	// ValidationErrors instead of failing on the first one.
+	aggregateErrors bool
+	// clock returns the current time ages are estimated against, time.Now
+	// when nil.
+	clock func() time.Time
+	// validateSetID rejects non-numeric PID-1 set IDs and orders the patient
+	// groups of a message by set ID.
+	validateSetID bool
+}
+
+// knownSegments lists the segments the parser extracts data from.
+var knownSegments = map[string]bool{
+	"MSH": true,
+	"EVN": true,
+	"PID": true,
+	"NK1": true,
+	"IN1": true,
+	"DG1": true,
+	"TXA": true,
+	"ORC": true,
+	"OBR": true,
+	"OBX": true,
+	"MRG": true,
+}
+
+// regeneratedSegments lists the segments of HL7 v2 messages generated from
+// a FHIR Patient. The other segments are lost in a round trip unless
+// preserved.
+var regeneratedSegments = map[string]bool{
+	"MSH": true,
+	"PID": true,
+	"NK1": true,
+}
+
+// expectedFields lists the logical fields a message must carry. Strict
+// parsing rejects messages without them, lenient parsing records a warning.
+var expectedFields = []string{"lastName", "birthDate"}
+
+// lenientDateFields lists the optional logical date/time fields lenient
+// parsing drops, with a warning, when they can not be read. Strict parsing
+// leaves them to fail the conversion.
+var lenientDateFields = []string{"deathDateTime"}
+
 // Add function to parse HL7 message
-func parseHL7Message(message string) (HL7Message, error) {
+func parseHL7Message(message string, opts parseOptions) (HL7Message, error) {
+	message = normalizeDelimiters(message)
 	// Validate minimum HL7 structure
 	if !strings.HasPrefix(message, "MSH|") {
-		return HL7Message{}, fmt.Errorf("invalid HL7 message - missing MSH segment")
+		return HL7Message{}, &FieldError{Segment: "MSH", Message: "invalid HL7 message - missing MSH segment"}
 	}
 
-	var msg HL7Message
-	segments := strings.Split(message, "\n")
+	mappings := opts.fieldMappings
+	if mappings == nil {
+		mappings = defaultFieldMappings
+	}
 
-	for _, segment := range segments {
-		fields := strings.Split(segment, "|")
+	var msg HL7Message
+	var hasPID bool
+	var version string
+	var errs ValidationErrors
+	// orderStatus is ORC-5 of the last ORC segment, applying to the next OBR
+	var orderStatus string
+	// reject returns err to fail with, or records it and returns nil when
+	// errors are aggregated
+	reject := func(err *FieldError) error {
+		if !opts.aggregateErrors {
+			return err
+		}
+		errs = append(errs, err)
+		return nil
+	}
+	fields := make([]string, 0, pidFieldCount+1)
 
-		switch fields[0] {
-		case "MSH":
-			msg.MSH.SendingApplication = fields[2]
-			msg.MSH.SendingFacility = fields[3]
-			msg.MSH.DateTime = fields[6]
-			msg.MSH.MessageType = fields[8]
-			msg.MSH.ControlID = fields[9]
-		case "PID":
-			// Validate required PID fields
-			if len(fields) < 4 || fields[3] == "" {
-				return HL7Message{}, fmt.Errorf("missing patient ID in PID segment")
+	for rest := message; rest != ""; {
+		var segment string
+		segment, rest = nextSegment(rest)
+		if segment == "" {
+			continue
+		}
+		fields = splitFields(segment, fields[:0])
+		if fields[0] == "PID" {
+			hasPID = true
+			for i := 1; i < len(fields); i++ {
+				if fields[i] == hl7Null {
+					msg.PID.NullFields = append(msg.PID.NullFields, fmt.Sprintf("PID-%d", i))
+					fields[i] = ""
+				}
 			}
-			msg.PID.ID = fields[3]
+		}
 
-			// Parse name (format: LastName^FirstName)
-			if len(fields) > 5 && fields[5] != "" {
-				nameParts := strings.Split(fields[5], "^")
-				if len(nameParts) > 0 {
-					msg.PID.LastName = nameParts[0]
-				}
-				if len(nameParts) > 1 {
-					msg.PID.FirstName = nameParts[1]
+		if opts.fieldLengths != "" && opts.fieldLengths != fieldLengthsNone {
+			if fields[0] == "MSH" {
+				version = fieldPath{Segment: "MSH", Field: 12}.value(fields)
+			}
+			warnings, lengthErrs := checkFieldLengths(fields, version, opts.fieldLengths)
+			for _, lengthErr := range lengthErrs {
+				if err := reject(lengthErr); err != nil {
+					return HL7Message{}, err
 				}
 			}
+			msg.Warnings = append(msg.Warnings, warnings...)
+		}
 
-			msg.PID.BirthDate = fields[7]
-			msg.PID.Gender = fields[8]
-
-			// Parse address (format: Street^City^State^PostalCode^Country)
-			if len(fields) > 11 && fields[11] != "" {
-				addrParts := strings.Split(fields[11], "^")
-				if len(addrParts) > 0 {
-					msg.PID.Address.Street = addrParts[0]
-				}
-				if len(addrParts) > 1 {
-					msg.PID.Address.City = addrParts[1]
-				}
-				if len(addrParts) > 2 {
-					msg.PID.Address.State = addrParts[2]
-				}
-				if len(addrParts) > 3 {
-					msg.PID.Address.PostalCode = addrParts[3]
+		var mapped bool
+		for name, path := range mappings {
+			if path.Segment == fields[0] {
+				*hl7Fields[name](&msg) = path.value(fields)
+				mapped = true
+			}
+		}
+		if opts.preserveUnknown && !regeneratedSegments[fields[0]] {
+			msg.UnknownSegments = append(msg.UnknownSegments, segment)
+		} else if !mapped && !knownSegments[fields[0]] {
+			switch {
+			case opts.strict:
+				err := reject(&FieldError{
+					Segment: fields[0],
+					Message: fmt.Sprintf("unknown segment %s", fields[0]),
+				})
+				if err != nil {
+					return HL7Message{}, err
 				}
-				if len(addrParts) > 4 {
-					msg.PID.Address.Country = addrParts[4]
+			default:
+				msg.Warnings = append(msg.Warnings, ParseWarning{
+					Segment: fields[0],
+					Message: "unknown segment ignored",
+				})
+			}
+		}
+		if idPath := mappings["patientId"]; idPath.Segment == fields[0] {
+			msg.PID.Identifiers = parsePatientIdentifiers(idPath.field(fields))
+		}
+		if streetPath := mappings["street"]; streetPath.Segment == fields[0] {
+			msg.PID.Addresses = parsePatientAddresses(streetPath.field(fields), streetPath, mappings)
+		}
+		switch fields[0] {
+		case "PID":
+			msg.PID.SetID = fieldPath{Segment: "PID", Field: 1}.value(fields)
+			if _, err := strconv.Atoi(msg.PID.SetID); opts.validateSetID && msg.PID.SetID != "" && err != nil {
+				err := reject(&FieldError{
+					Segment: "PID",
+					Field:   "PID-1",
+					Message: fmt.Sprintf("invalid PID-1 set ID %q: must be numeric", msg.PID.SetID),
+				})
+				if err != nil {
+					return HL7Message{}, err
 				}
 			}
+			msg.PID.Race = parseCodedElements(fieldPath{Segment: "PID", Field: 10}.field(fields))
+			msg.PID.AccountNumber = parseAccountNumber(accountNumberPath.field(fields))
+			msg.PID.Ethnicity = parseCodedElements(fieldPath{Segment: "PID", Field: 22}.field(fields))
+			msg.PID.Language = parseCodedElement(fieldPath{Segment: "PID", Field: 15}.field(fields))
+			msg.PID.MaritalStatus = parseCodedElement(fieldPath{Segment: "PID", Field: 16}.field(fields))
+			msg.PID.Nationality = parseCodedElement(fieldPath{Segment: "PID", Field: 28}.field(fields))
+			msg.PID.MultipleBirthIndicator = fieldPath{Segment: "PID", Field: 24}.value(fields)
+			msg.PID.BirthOrder = fieldPath{Segment: "PID", Field: 25}.value(fields)
+		case "NK1":
+			msg.NK1 = append(msg.NK1, parseNextOfKin(fields))
+		case "EVN":
+			evn := parseEvent(fields)
+			msg.EVN = &evn
+		case "MRG":
+			mrg := parseMerge(fields)
+			msg.MRG = &mrg
+		case "TXA":
+			txa := parseDocumentHeader(fields)
+			msg.TXA = &txa
+		case "ORC":
+			orderStatus = fieldPath{Segment: "ORC", Field: 5}.value(fields)
+		case "OBR":
+			obr := parseObservationRequest(fields)
+			obr.OrderStatus, orderStatus = orderStatus, ""
+			msg.OBR = append(msg.OBR, obr)
+		case "OBX":
+			obx := parseObservationResult(fields)
+			if n := len(msg.OBR); n > 0 {
+				msg.OBR[n-1].Observations = append(msg.OBR[n-1].Observations, obx)
+			} else {
+				msg.OBX = append(msg.OBX, obx)
+			}
+		case "DG1":
+			msg.DG1 = append(msg.DG1, parseDiagnosis(fields))
+		case "IN1":
+			msg.IN1 = append(msg.IN1, parseInsurance(fields, len(msg.IN1)+1))
+		}
+	}
+
+	// The first address is read through the field mappings like any other
+	// field, so keep those values
+	if len(msg.PID.Addresses) > 0 {
+		msg.PID.Address.Type = msg.PID.Addresses[0].Type
+		msg.PID.Addresses[0] = msg.PID.Address
+	}
+
+	// The patient ID is the first MRN, if there is one
+	for _, id := range msg.PID.Identifiers {
+		if id.IdentifierType == "MR" {
+			msg.PID.ID = id.ID
+			break
 		}
 	}
 
 	// Post-validation
 	if msg.PID.ID == "" {
-		return HL7Message{}, fmt.Errorf("missing PID segment")
+		idPath := mappings["patientId"]
+		idErr := &FieldError{Segment: "PID", Message: "missing PID segment"}
+		if hasPID {
+			idErr = &FieldError{
+				Segment: idPath.Segment,
+				Field:   fmt.Sprintf("%s-%d", idPath.Segment, idPath.Field),
+				Message: "missing patient ID in PID segment",
+			}
+		}
+		if err := reject(idErr); err != nil {
+			return HL7Message{}, err
+		}
+	}
+
+	for _, name := range expectedFields {
+		if *hl7Fields[name](&msg) != "" {
+			continue
+		}
+		path := mappings[name]
+		field := fmt.Sprintf("%s-%d", path.Segment, path.Field)
+		if slices.Contains(msg.PID.NullFields, field) {
+			// explicitly deleted, not missing
+			continue
+		}
+		if opts.strict {
+			err := reject(&FieldError{
+				Segment: path.Segment,
+				Field:   field,
+				Message: fmt.Sprintf("missing expected field %s (%s)", name, field),
+			})
+			if err != nil {
+				return HL7Message{}, err
+			}
+			continue
+		}
+		msg.Warnings = append(msg.Warnings, ParseWarning{
+			Segment: path.Segment,
+			Field:   field,
+			Message: fmt.Sprintf("missing expected field %s", name),
+		})
+	}
+
+	if !opts.strict {
+		for _, name := range lenientDateFields {
+			value := hl7Fields[name](&msg)
+			if *value == "" {
+				continue
+			}
+			if _, err := hl7ToFHIRTimestamp(*value, time.UTC); err != nil {
+				path := mappings[name]
+				msg.Warnings = append(msg.Warnings, ParseWarning{
+					Segment: path.Segment,
+					Field:   fmt.Sprintf("%s-%d", path.Segment, path.Field),
+					Message: fmt.Sprintf("unparseable %s dropped: %v", name, err),
+				})
+				*value = ""
+			}
+		}
+	}
+
+	// Some feeds send the age in years in place of the birth date
+	if isAge(msg.PID.BirthDate) {
+		path := mappings["birthDate"]
+		field := fmt.Sprintf("%s-%d", path.Segment, path.Field)
+		if !opts.estimateAge {
+			err := reject(&FieldError{
+				Segment: path.Segment,
+				Field:   field,
+				Message: fmt.Sprintf("%s contains age %s instead of a birth date", field, msg.PID.BirthDate),
+			})
+			if err != nil {
+				return HL7Message{}, err
+			}
+		} else {
+			age, _ := strconv.Atoi(msg.PID.BirthDate)
+			clock := opts.clock
+			if clock == nil {
+				clock = time.Now
+			}
+			msg.PID.BirthDate = strconv.Itoa(clock().Year() - age)
+			msg.Warnings = append(msg.Warnings, ParseWarning{
+				Segment: path.Segment,
+				Field:   field,
+				Message: fmt.Sprintf("birth year %s estimated from age %d", msg.PID.BirthDate, age),
+			})
+		}
 	}
 
+	if len(errs) > 0 {
+		return HL7Message{}, errs
+	}
 	return msg, nil
 }
 
+// isAge reports whether a birth date value is an age: a number too short to
+// be a date.
+func isAge(v string) bool {
+	if v == "" || len(v) > 3 {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		if v[i] < '0' || v[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePatientIdentifiers parses the repetitions of a CX field such as PID-3.
+func parsePatientIdentifiers(field string) []PatientIdentifier {
+	var ids []PatientIdentifier
+	for rest, more := field, true; more; {
+		var repetition string
+		repetition, rest, more = nextToken(rest, '~')
+		id := unescapeHL7(component(repetition, '^', 1))
+		if id == "" {
+			continue
+		}
+		ids = append(ids, PatientIdentifier{
+			ID:                 id,
+			AssigningAuthority: unescapeHL7(component(repetition, '^', 4)),
+			IdentifierType:     component(repetition, '^', 5),
+			EffectiveDate:      component(repetition, '^', 7),
+			ExpirationDate:     component(repetition, '^', 8),
+		})
+	}
+	return ids
+}
+
+// parsePatientAddresses parses the repetitions of an XAD field such as
+// PID-11. The components are located through the mappings of the address
+// fields pointing into the same field.
+func parsePatientAddresses(field string, xad fieldPath, mappings map[string]fieldPath) []PatientAddress {
+	var addrs []PatientAddress
+	for rest, more := field, true; more; {
+		var repetition string
+		repetition, rest, more = nextToken(rest, '~')
+		if repetition == "" {
+			continue
+		}
+		var addr PatientAddress
+		values := []*string{&addr.Street, &addr.City, &addr.State, &addr.PostalCode, &addr.Country}
+		for i, name := range addressNames {
+			path := mappings[name]
+			if path.Segment == xad.Segment && path.Field == xad.Field && path.Component > 0 {
+				*values[i] = unescapeHL7(component(repetition, '^', path.Component))
+			}
+		}
+		addr.Type = component(repetition, '^', addressTypeComponent)
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// convertAddress converts an HL7 v2 address to a FHIR address, the address
+// type setting its use or type.
+func (p *Processor) convertAddress(addr PatientAddress) Address {
+	t := hl7AddressTypes[addr.Type]
+	address := Address{
+		Use:        t.use,
+		Type:       t.typ,
+		City:       addr.City,
+		State:      addr.State,
+		PostalCode: addr.PostalCode,
+		Country:    normalizeCountry(addr.Country, p.config.CountryFormat),
+	}
+	if addr.Street != "" {
+		address.Line = []string{addr.Street}
+	}
+	return address
+}
+
+// parseCodedElements parses the repetitions of a CE field.
+func parseCodedElements(field string) []CodedElement {
+	var values []CodedElement
+	for rest, more := field, true; more; {
+		var repetition string
+		repetition, rest, more = nextToken(rest, '~')
+		if repetition == "" {
+			continue
+		}
+		values = append(values, parseCodedElement(repetition))
+	}
+	return values
+}
+
+// parseCodedElement parses the first repetition of a CE field.
+func parseCodedElement(field string) CodedElement {
+	field, _, _ = nextToken(field, '~')
+	return CodedElement{
+		Code:   unescapeHL7(component(field, '^', 1)),
+		Text:   unescapeHL7(component(field, '^', 2)),
+		System: unescapeHL7(component(field, '^', 3)),
+	}
+}
+
+// Inspect parses the segment structure of an HL7 v2 message without
+// converting it. It returns one entry per segment in the form "SEG:n", where
+// n is the number of the last field present in the segment.
+func (p *Processor) Inspect(message string) ([]string, error) {
+	message = normalizeDelimiters(message)
+	if !strings.HasPrefix(message, "MSH|") {
+		return nil, fmt.Errorf("invalid HL7 message - missing MSH segment")
+	}
+
+	var result []string
+	var fields []string
+	for rest := message; rest != ""; {
+		var segment string
+		segment, rest = nextSegment(rest)
+		if segment == "" {
+			continue
+		}
+		fields = splitFields(segment, fields[:0])
+		count := len(fields) - 1
+		if fields[0] == "MSH" {
+			// MSH-1 is the field separator itself
+			count++
+		}
+		result = append(result, fmt.Sprintf("%s:%d", fields[0], count))
+	}
+	return result, nil
+}
+
+// parseOptions returns the parse options derived from the configuration.
+func (p *Processor) parseOptions() parseOptions {
+	return parseOptions{
+		fieldMappings: p.fieldMappings,
+		strict:        p.config.ParseMode == parseModeStrict,
+		estimateAge:   p.config.AgeInBirthDate == ageInBirthDateEstimate,
+		fieldLengths:  p.config.ValidateFieldLengths,
+
+		preserveUnknown: p.config.PreserveUnknownSegments,
+		aggregateErrors: p.config.AggregateErrors,
+		validateSetID:   p.config.ValidateSetID,
+		clock:           p.clock,
+	}
+}
+
 // Add function to convert HL7 to FHIR
 func (p *Processor) convertHL7ToFHIR(msg HL7Message) (FHIRPatient, error) {
 	if msg.PID.ID == "" {
 		return FHIRPatient{}, fmt.Errorf("missing patient ID")
 	}
-	if msg.PID.LastName == "" {
-		return FHIRPatient{}, fmt.Errorf("missing patient last name")
+	// explicitly deleted fields are not missing
+	if p.config.ParseMode == parseModeStrict {
+		if msg.PID.LastName == "" && !slices.Contains(msg.PID.NullFields, "PID-5") {
+			return FHIRPatient{}, fmt.Errorf("missing patient last name")
+		}
+		if msg.PID.BirthDate == "" && !slices.Contains(msg.PID.NullFields, "PID-7") {
+			return FHIRPatient{}, fmt.Errorf("missing birth date")
+		}
 	}
-	if msg.PID.BirthDate == "" {
-		return FHIRPatient{}, fmt.Errorf("missing birth date")
+
+	pids := msg.PID.Identifiers
+	if len(pids) == 0 {
+		pids = []PatientIdentifier{{ID: msg.PID.ID}}
+	}
+	if msg.PID.AccountNumber != nil {
+		pids = append(pids, *msg.PID.AccountNumber)
+	}
+	identifiers := make([]Identifier, 0, len(pids))
+	now := p.now()
+	for _, pi := range pids {
+		identifier := Identifier{
+			System: pi.AssigningAuthority,
+			Value:  pi.ID,
+		}
+		if identifier.System == "" {
+			identifier.System = p.config.DefaultIdentifierSystem
+		}
+		if pi.IdentifierType != "" {
+			identifier.Type = &CodeableConcept{
+				Coding: []Coding{{System: identifierTypeSystem, Code: pi.IdentifierType}},
+			}
+		}
+		period, err := hl7ToFHIRPeriod(pi.EffectiveDate, pi.ExpirationDate, p.timeLocation())
+		if err != nil {
+			return FHIRPatient{}, fmt.Errorf("identifier %s: %w", pi.ID, err)
+		}
+		identifier.Period = period
+		if periodEnded(period, now) {
+			if p.config.ExcludeExpiredIdentifiers {
+				continue
+			}
+			identifier.Use = "old"
+		}
+		identifiers = append(identifiers, identifier)
 	}
 
 	patient := FHIRPatient{
-		ID: msg.PID.ID,
-		Name: []struct {
-			Family []string `json:"family"`
-			Given  []string `json:"given"`
-		}{
+		ID:         msg.PID.ID,
+		Identifier: identifiers,
+		Name: []HumanName{
 			{
 				Family: []string{msg.PID.LastName},
 				Given:  []string{msg.PID.FirstName},
 			},
 		},
-		BirthDate: msg.PID.BirthDate,
-		Gender:    strings.ToLower(msg.PID.Gender),
-		Address: []struct {
-			Line       []string `json:"line"`
-			City       string   `json:"city"`
-			State      string   `json:"state"`
-			PostalCode string   `json:"postalCode"`
-			Country    string   `json:"country"`
-		}{
-			{
-				Line:       []string{msg.PID.Address.Street},
-				City:       msg.PID.Address.City,
-				State:      msg.PID.Address.State,
-				PostalCode: msg.PID.Address.PostalCode,
-				Country:    msg.PID.Address.Country,
+		Gender: p.hl7ToFHIRGender(msg.PID.Gender),
+	}
+	if slices.Contains(msg.PID.NullFields, "PID-5") {
+		// an explicitly deleted name is cleared, not sent as an empty name
+		patient.Name = []HumanName{}
+	}
+	birthDate, err := hl7ToFHIRDate(msg.PID.BirthDate, p.timeLocation())
+	if err != nil {
+		return FHIRPatient{}, fmt.Errorf("invalid birth date: %w", err)
+	}
+	patient.BirthDate = birthDate
+	addrs := msg.PID.Addresses
+	if len(addrs) == 0 {
+		addrs = []PatientAddress{msg.PID.Address}
+	}
+	for _, addr := range addrs {
+		// an empty XAD such as ^^^^ carries no address
+		if addr == (PatientAddress{}) {
+			continue
+		}
+		patient.Address = append(patient.Address, p.convertAddress(addr))
+	}
+	switch {
+	case msg.PID.DeathDateTime != "":
+		deceased, element, err := hl7ToFHIRTimestampElement(msg.PID.DeathDateTime, p.timeLocation())
+		if err != nil {
+			return FHIRPatient{}, fmt.Errorf("invalid death date/time: %w", err)
+		}
+		patient.DeceasedDateTime = deceased
+		patient.DeceasedDateTimeElement = element
+	case msg.PID.DeathIndicator == "Y":
+		deceased := true
+		patient.DeceasedBoolean = &deceased
+	case msg.PID.DeathIndicator == "N":
+		deceased := false
+		patient.DeceasedBoolean = &deceased
+	}
+	if order, err := strconv.Atoi(msg.PID.BirthOrder); err == nil && order > 0 {
+		patient.MultipleBirthInteger = &order
+	} else if indicator := msg.PID.MultipleBirthIndicator; indicator == "Y" || indicator == "N" {
+		multipleBirth := indicator == "Y"
+		patient.MultipleBirthBoolean = &multipleBirth
+	}
+	patient.MaritalStatus = hl7ToFHIRMaritalStatus(msg.PID.MaritalStatus.Code)
+	if p.config.IncludeActive {
+		active := !inactiveTriggerEvents[component(msg.MSH.MessageType, '^', 2)]
+		patient.Active = &active
+	}
+	if l := msg.PID.Language; l.Code != "" {
+		patient.Communication = []Communication{{
+			Language: CodeableConcept{
+				Coding: []Coding{{System: languageSystem, Code: l.Code, Display: l.Text}},
 			},
-		},
+			Preferred: true,
+		}}
+	}
+	for _, nk1 := range msg.NK1 {
+		// FHIR requires a contact to have some detail (pat-1)
+		if contact := p.convertNextOfKin(nk1); !isEmptyContact(contact) {
+			patient.Contact = append(patient.Contact, contact)
+		}
+	}
+	patient.Link = mergeLinks(msg.MRG)
+	patient.ManagingOrganization = hl7ManagingOrganization(msg.MSH.SendingFacility)
+	if ext, ok := newUSCoreExtension(usCoreRaceURL, msg.PID.Race); ok {
+		patient.Extension = append(patient.Extension, ext)
+	}
+	if ext, ok := newUSCoreExtension(usCoreEthnicityURL, msg.PID.Ethnicity); ok {
+		patient.Extension = append(patient.Extension, ext)
+	}
+	if n := msg.PID.Nationality; n.Code != "" || n.Text != "" {
+		concept := codeableConcept(n)
+		patient.Extension = append(patient.Extension, Extension{
+			URL:       nationalityExtensionURL,
+			Extension: []Extension{{URL: "code", ValueCodeableConcept: &concept}},
+		})
+	}
+	p.applyFHIRDefaults(&patient)
+	if p.config.GenerateNarrative {
+		patient.Text = patientNarrative(patient)
 	}
 	return patient, nil
 }
 
 // Add HL7v3 to FHIR conversion
 func (p *Processor) convertHL7V3ToFHIR(v3Patient HL7V3Patient) (FHIRPatient, error) {
-	// Convert HL7v3 date format (YYYYMMDDHHMMSS) to FHIR date (YYYY-MM-DD)
+	// Convert HL7v3 date format (YYYYMMDDHHMMSS) to FHIR date (YYYY-MM-DD),
+	// or to a FHIR dateTime if the birth time is more than a date at midnight.
+	// A year or month keeps its precision.
 	birthDate := ""
-	if len(v3Patient.BirthTime.Value) >= 8 {
-		birthDate = fmt.Sprintf("%s-%s-%s",
-			v3Patient.BirthTime.Value[0:4],
-			v3Patient.BirthTime.Value[4:6],
-			v3Patient.BirthTime.Value[6:8],
-		)
+	if value := v3Patient.BirthTime.Value; len(value) >= 8 {
+		birthDate = fmt.Sprintf("%s-%s-%s", value[0:4], value[4:6], value[6:8])
+		if strings.TrimRight(value[8:], "0") != "" {
+			var err error
+			if birthDate, err = hl7ToFHIRTimestamp(value, p.timeLocation()); err != nil {
+				return FHIRPatient{}, fmt.Errorf("invalid birthTime: %w", err)
+			}
+		}
+	} else if value != "" {
+		var err error
+		if birthDate, err = hl7ToFHIRTimestamp(value, p.timeLocation()); err != nil {
+			return FHIRPatient{}, fmt.Errorf("invalid birthTime: %w", err)
+		}
 	}
 
 	// Map gender codes
 	genderMap := map[string]string{
-		"M": "male",
-		"F": "female",
-		"U": "unknown",
+		"M":  "male",
+		"F":  "female",
+		"U":  "unknown",
+		"UN": "other",
+	}
+
+	gender := genderMap[v3Patient.Gender.Code]
+	if custom, ok := p.customGender(v3Patient.Gender.Code); ok {
+		gender = custom
+	}
+	if v3Patient.Gender.CodeSystem != "" && v3Patient.Gender.CodeSystem != administrativeGenderOID {
+		return FHIRPatient{}, fmt.Errorf("unexpected administrativeGenderCode codeSystem %q, expected %s",
+			v3Patient.Gender.CodeSystem, administrativeGenderOID)
+	}
+	if v3Patient.Gender.NullFlavor != "" {
+		gender = "unknown"
 	}
 
 	patient := FHIRPatient{
-		ID: v3Patient.ID,
-		Name: []struct {
-			Family []string `json:"family"`
-			Given  []string `json:"given"`
-		}{
-			{
-				Family: []string{v3Patient.Name.Family},
-				Given:  []string{v3Patient.Name.Given},
-			},
-		},
+		ID:        v3Patient.ID,
 		BirthDate: birthDate,
-		Gender:    genderMap[v3Patient.Gender.Code],
-		Address: []struct {
-			Line       []string `json:"line"`
-			City       string   `json:"city"`
-			State      string   `json:"state"`
-			PostalCode string   `json:"postalCode"`
-			Country    string   `json:"country"`
-		}{
-			{
-				Line:       []string{v3Patient.Address.Street},
-				City:       v3Patient.Address.City,
-				State:      v3Patient.Address.State,
-				PostalCode: v3Patient.Address.PostalCode,
-			},
-		},
+		Gender:    gender,
+	}
+	for _, name := range v3Patient.Name {
+		patient.Name = append(patient.Name, HumanName{
+			Family: []string{name.Family},
+			Given:  []string{name.Given},
+		})
 	}
+	for _, addr := range v3Patient.Address {
+		patient.Address = append(patient.Address, Address{
+			Use:        hl7V3AddressUses[addr.Use],
+			Line:       []string{addr.Street},
+			City:       addr.City,
+			State:      addr.State,
+			PostalCode: addr.PostalCode,
+		})
+	}
+	for _, telecom := range v3Patient.Telecom {
+		patient.Telecom = append(patient.Telecom, convertTelecom(telecom))
+	}
+	p.applyFHIRDefaults(&patient)
 	return patient, nil
 }
 
@@ -297,158 +1363,713 @@ func (p *Processor) Process(ctx context.Context, records []opencdc.Record) []sdk
 	logger.Info().Int("count", len(records)).Msg("Processing records")
 	result := make([]sdk.ProcessedRecord, len(records))
 
-	for i, record := range records {
-		logger.Info().Int("index", i).Msg("Processing record")
+	if p.config.Concurrency <= 1 {
+		for i, record := range records {
+			result[i] = p.processRecord(ctx, i, record)
+		}
+		return result
+	}
 
-		var resultData interface{}
-		var conversionErr error
+	// Records are handed out to a bounded pool of workers, each writing the
+	// result at the index of its record so the output order is preserved.
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(p.config.Concurrency, len(records)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				result[i] = p.processRecord(ctx, i, records[i])
+			}
+		}()
+	}
 
-		switch p.config.InputType + "->" + p.config.OutputType {
-		case "fhir->hl7":
-			rawBytes := record.Payload.After.Bytes()
-			var patient FHIRPatient
-			if err := json.Unmarshal(rawBytes, &patient); err != nil {
-				logger.Error().Err(err).Msg("Failed to parse FHIR patient")
-				result[i] = sdk.ErrorRecord{Error: fmt.Errorf("failed to parse FHIR JSON: %w", err)}
-				continue
+dispatch:
+	for i := range records {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	// Records that were not dispatched before the context was cancelled
+	for i := range result {
+		if result[i] == nil {
+			result[i] = sdk.ErrorRecord{Error: fmt.Errorf("processing cancelled: %w", ctx.Err())}
+		}
+	}
+	return result
+}
+
+// processRecord converts a single record.
+func (p *Processor) processRecord(ctx context.Context, i int, record opencdc.Record) sdk.ProcessedRecord {
+	logger := sdk.Logger(ctx)
+	logger.Info().Int("index", i).Msg("Processing record")
+
+	var resultData interface{}
+	var conversionErr error
+	// source is the HL7 message the FHIR output is converted from
+	var source string
+	// resources accompany the FHIR patient in a Bundle
+	var resources []interface{}
+	// patient is the FHIR patient converted to or from
+	var patient FHIRPatient
+
+	original := record.Payload.After
+
+	// A record without payload would otherwise fail with a misleading parse
+	// error, e.g. a missing MSH segment
+	if record.Payload.After == nil || strings.TrimSpace(string(record.Payload.After.Bytes())) == "" {
+		return p.errorRecord(record, errorClassParse, errors.New("empty payload"))
+	}
+
+	// The record metadata may name another conversion than configured
+	rp, err := p.recordProcessor(record)
+	if err != nil {
+		return p.errorRecord(record, errorClassConversion, err)
+	}
+	p = rp
+
+	switch p.config.InputType + "->" + p.config.OutputType {
+	case "fhir->hl7":
+		rawBytes := record.Payload.After.Bytes()
+		if err := p.unmarshalFHIR(ctx, rawBytes, &patient); err != nil {
+			logger.Error().Err(err).Msg("Failed to parse FHIR patient")
+			return p.errorRecord(record, errorClassParse, fmt.Errorf("failed to parse FHIR JSON: %w", err))
+		}
+		if patient.ResourceType == "Bundle" {
+			resultData, conversionErr = p.convertBundleToHL7(ctx, rawBytes)
+			break
+		}
+		if !isPatientResource(patient) {
+			return p.unsupportedResource(record, patient.ResourceType)
+		}
+		hl7Message, err := p.convertFHIRToHL7(patient)
+		if err == nil && p.config.PreserveUnknownSegments {
+			hl7Message, err = p.appendUnknownSegments(hl7Message, record.Metadata)
+		}
+		resultData, conversionErr = hl7Message, err
+	case "fhir->hl7v3":
+		rawBytes := record.Payload.After.Bytes()
+		if err := p.unmarshalFHIR(ctx, rawBytes, &patient); err != nil {
+			logger.Error().Err(err).Msg("Failed to parse FHIR patient")
+			return p.errorRecord(record, errorClassParse, fmt.Errorf("failed to parse FHIR JSON: %w", err))
+		}
+		if !isPatientResource(patient) {
+			return p.unsupportedResource(record, patient.ResourceType)
+		}
+		resultData, conversionErr = p.convertFHIRToHL7V3(patient)
+	case "fhir->fhir":
+		rawBytes := record.Payload.After.Bytes()
+		if err := p.unmarshalFHIR(ctx, rawBytes, &patient); err != nil {
+			logger.Error().Err(err).Msg("Failed to parse FHIR patient")
+			return p.errorRecord(record, errorClassParse, fmt.Errorf("failed to parse FHIR JSON: %w", err))
+		}
+		if !isPatientResource(patient) {
+			return p.unsupportedResource(record, patient.ResourceType)
+		}
+		resultData, conversionErr = p.normalizeFHIRPatient(rawBytes)
+	case "hl7->fhir":
+		rawBytes := record.Payload.After.Bytes()
+		logger.Debug().Str("input", string(rawBytes)).Msg("Raw input for HL7 parsing")
+		source = string(rawBytes)
+		if p.config.MLLPFraming {
+			source = unwrapMLLP(source)
+		}
+		if strings.HasPrefix(source, "{") {
+			// JSON is UTF-8, the wrapped message is not transcoded
+			var wrapper struct {
+				HL7 string `json:"hl7"`
 			}
-			resultData, conversionErr = p.convertFHIRToHL7(patient)
-		case "fhir->hl7v3":
-			rawBytes := record.Payload.After.Bytes()
-			var patient FHIRPatient
-			if err := json.Unmarshal(rawBytes, &patient); err != nil {
-				logger.Error().Err(err).Msg("Failed to parse FHIR patient")
-				result[i] = sdk.ErrorRecord{Error: fmt.Errorf("failed to parse FHIR JSON: %w", err)}
-				continue
+			if err := json.Unmarshal([]byte(source), &wrapper); err != nil {
+				logger.Error().Err(err).Msg("Failed to parse HL7 wrapper")
+				return p.errorRecord(record, errorClassParse, fmt.Errorf("failed to parse HL7 JSON: %w", err))
 			}
-			resultData, conversionErr = p.convertFHIRToHL7V3(patient)
-		case "hl7->fhir":
-			rawBytes := record.Payload.After.Bytes()
-			logger.Debug().Str("input", string(rawBytes)).Msg("Raw input for HL7 parsing")
-			var hl7msg HL7Message
-			var err error
-
-			if strings.HasPrefix(string(rawBytes), "{") {
-				var wrapper struct {
-					HL7 string `json:"hl7"`
-				}
-				if err := json.Unmarshal(rawBytes, &wrapper); err != nil {
-					logger.Error().Err(err).Msg("Failed to parse HL7 wrapper")
-					result[i] = sdk.ErrorRecord{Error: fmt.Errorf("failed to parse HL7 JSON: %w", err)}
-					continue
-				}
-				hl7msg, err = parseHL7Message(wrapper.HL7)
-			} else {
-				hl7msg, err = parseHL7Message(string(rawBytes))
+			source = wrapper.HL7
+		} else {
+			source = p.hl7Source([]byte(source))
+		}
+		if p.config.ValidateSegments {
+			if err := validateSegments(source, p.segmentGrammar); err != nil {
+				logger.Error().Err(err).Msg("HL7 message is missing required segments")
+				return p.errorRecord(record, errorClassParse, fmt.Errorf("failed to validate HL7 segments: %w", err))
 			}
+		}
 
+		groups, err := parseHL7Groups(source, p.parseOptions())
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to parse HL7 message")
+			return p.errorRecord(record, errorClassParse, fmt.Errorf("failed to parse HL7: %w", err))
+		}
+		logger.Debug().Interface("parsed_hl7", groups).Msg("Parsed HL7 message")
+		var parseWarnings []ParseWarning
+		var unknownSegments, nullFields []string
+		for _, group := range groups {
+			parseWarnings = append(parseWarnings, group.Warnings...)
+			unknownSegments = append(unknownSegments, group.UnknownSegments...)
+			nullFields = append(nullFields, group.PID.NullFields...)
+		}
+		for _, warning := range parseWarnings {
+			logger.Debug().
+				Str("segment", warning.Segment).
+				Str("field", warning.Field).
+				Str("reason", warning.Message).
+				Msg("Lenient parsing dropped or changed data")
+		}
+		if len(parseWarnings) > 0 {
+			warnings, err := json.Marshal(parseWarnings)
 			if err != nil {
-				logger.Error().Err(err).Msg("Failed to parse HL7 message")
-				result[i] = sdk.ErrorRecord{Error: fmt.Errorf("failed to parse HL7: %w", err)}
-				continue
+				return p.errorRecord(record, errorClassMarshal, fmt.Errorf("failed to marshal parse warnings: %w", err))
 			}
-			logger.Debug().Interface("parsed_hl7", hl7msg).Msg("Parsed HL7 message")
-			resultData, conversionErr = p.convertHL7ToFHIR(hl7msg)
-			logger.Debug().Interface("fhir_patient", resultData).Msg("Converted FHIR patient")
-		case "hl7v3->fhir":
-			rawBytes := record.Payload.After.Bytes()
-			var v3Patient HL7V3Patient
-			if err := xml.Unmarshal(rawBytes, &v3Patient); err != nil {
-				logger.Error().Err(err).Msg("Failed to parse HL7v3 patient")
-				result[i] = sdk.ErrorRecord{Error: fmt.Errorf("failed to parse HL7v3 XML: %w", err)}
-				continue
+			if record.Metadata == nil {
+				record.Metadata = opencdc.Metadata{}
+			}
+			record.Metadata[metadataWarnings] = string(warnings)
+		}
+		if len(unknownSegments) > 0 {
+			segments, err := json.Marshal(unknownSegments)
+			if err != nil {
+				return p.errorRecord(record, errorClassMarshal, fmt.Errorf("failed to marshal unknown segments: %w", err))
+			}
+			if record.Metadata == nil {
+				record.Metadata = opencdc.Metadata{}
 			}
-			resultData, conversionErr = p.convertHL7V3ToFHIR(v3Patient)
-		default:
-			conversionErr = fmt.Errorf("unsupported conversion: %s->%s",
-				p.config.InputType, p.config.OutputType)
+			record.Metadata[metadataUnknownSegments] = string(segments)
 		}
+		if len(nullFields) > 0 {
+			fields, err := json.Marshal(nullFields)
+			if err != nil {
+				return p.errorRecord(record, errorClassMarshal, fmt.Errorf("failed to marshal null fields: %w", err))
+			}
+			if record.Metadata == nil {
+				record.Metadata = opencdc.Metadata{}
+			}
+			record.Metadata[metadataNullFields] = string(fields)
+		}
+		// the event of the first message, whose patient the record carries
+		if evn := groups[0].EVN; evn != nil {
+			if record.Metadata == nil {
+				record.Metadata = opencdc.Metadata{}
+			}
+			for key, value := range eventMetadata(*evn, p.timeLocation()) {
+				record.Metadata[key] = value
+			}
+		}
+		patient, resources, conversionErr = p.convertPatientGroups(groups)
+		resultData = patient
+		logger.Debug().Interface("fhir_patient", resultData).Msg("Converted FHIR patient")
+	case "hl7v3->fhir":
+		rawBytes := record.Payload.After.Bytes()
+		source = string(rawBytes)
+		v3Patient, err := unmarshalHL7V3(rawBytes, p.config.HL7V3Format)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to parse HL7v3 patient")
+			return p.errorRecord(record, errorClassParse, fmt.Errorf("failed to parse HL7v3 XML: %w", err))
+		}
+		patient, conversionErr = p.convertHL7V3ToFHIR(v3Patient)
+		resultData = patient
+	default:
+		conversionErr = fmt.Errorf("unsupported conversion: %s->%s",
+			p.config.InputType, p.config.OutputType)
+	}
 
-		if conversionErr != nil {
-			logger.Error().Err(conversionErr).Msg("Conversion error")
-			result[i] = sdk.ErrorRecord{Error: conversionErr}
-			continue
+	if conversionErr != nil {
+		logger.Error().Err(conversionErr).Msg("Conversion error")
+		return p.errorRecord(record, errorClassConversion, conversionErr)
+	}
+
+	// Bundles hold several patients, without a single key
+	if p.config.MatchKeyHash != "" && patient.ResourceType != "Bundle" {
+		if record.Metadata == nil {
+			record.Metadata = opencdc.Metadata{}
 		}
+		record.Metadata[metadataMatchKey] = matchKey(patient, p.config.MatchKeyHash)
+	}
 
-		// Marshal resultData based on output type
-		switch p.config.OutputType {
-		case "fhir":
-			fhirPatient, ok := resultData.(FHIRPatient)
-			if !ok {
-				result[i] = sdk.ErrorRecord{Error: fmt.Errorf("invalid FHIR output type")}
-				continue
-			}
-			fhirJSON, err := json.Marshal(fhirPatient)
+	// Marshal resultData based on output type
+	switch p.config.OutputType {
+	case "fhir":
+		// normalized FHIR input keeps the fields the processor does not model
+		if normalized, ok := resultData.(map[string]interface{}); ok {
+			fhirJSON, err := p.marshalJSON(normalized)
 			if err != nil {
-				result[i] = sdk.ErrorRecord{Error: fmt.Errorf("failed to marshal FHIR patient: %w", err)}
-				continue
+				return p.errorRecord(record, errorClassMarshal, fmt.Errorf("failed to marshal FHIR patient: %w", err))
 			}
 			record.Payload.After = opencdc.RawData(fhirJSON)
-		case "hl7":
-			hl7Message, ok := resultData.(string)
-			if !ok {
-				result[i] = sdk.ErrorRecord{Error: fmt.Errorf("invalid HL7 output type")}
-				continue
-			}
-			record.Payload.After = opencdc.StructuredData{"hl7": hl7Message}
-		case "hl7v3":
-			xmlData, ok := resultData.([]byte)
-			if !ok {
-				result[i] = sdk.ErrorRecord{Error: fmt.Errorf("invalid HL7v3 output type")}
-				continue
-			}
-			record.Payload.After = opencdc.RawData(xmlData)
+			break
+		}
+		fhirPatient, ok := resultData.(FHIRPatient)
+		if !ok {
+			return p.errorRecord(record, errorClassMarshal, fmt.Errorf("invalid FHIR output type"))
+		}
+		if p.config.IncludeResourceType {
+			fhirPatient.ResourceType = "Patient"
+		}
+		fhirJSON, err := p.marshalJSON(p.fhirOutput(fhirPatient, resources, source))
+		if err != nil {
+			return p.errorRecord(record, errorClassMarshal, fmt.Errorf("failed to marshal FHIR patient: %w", err))
+		}
+		record.Payload.After = opencdc.RawData(fhirJSON)
+	case "hl7":
+		hl7Message, ok := resultData.(string)
+		if !ok {
+			return p.errorRecord(record, errorClassMarshal, fmt.Errorf("invalid HL7 output type"))
 		}
+		if p.config.MLLPFraming {
+			hl7Message = wrapMLLP(hl7Message)
+		}
+		record.Payload.After = opencdc.StructuredData{"hl7": hl7Message}
+	case "hl7v3":
+		xmlData, ok := resultData.([]byte)
+		if !ok {
+			return p.errorRecord(record, errorClassMarshal, fmt.Errorf("invalid HL7v3 output type"))
+		}
+		record.Payload.After = opencdc.RawData(xmlData)
+	}
 
-		result[i] = sdk.SingleRecord(record)
+	if p.config.ValidateOnly {
+		return p.validationRecord(record, original)
 	}
+	if p.config.CompressOutput {
+		if err := compressPayload(&record); err != nil {
+			return p.errorRecord(record, errorClassMarshal, err)
+		}
+	}
+	return sdk.SingleRecord(record)
+}
 
-	return result
+// isPatientResource reports whether FHIR input holds a Patient. Input without
+// a resourceType is taken to be one.
+func isPatientResource(patient FHIRPatient) bool {
+	return patient.ResourceType == "" || patient.ResourceType == "Patient"
+}
+
+// unsupportedResource passes FHIR input of a resource type that can not be
+// converted through unchanged, or rejects it, depending on
+// onUnsupportedResource.
+func (p *Processor) unsupportedResource(record opencdc.Record, resourceType string) sdk.ProcessedRecord {
+	if p.config.OnUnsupportedResource == onUnsupportedResourcePass {
+		return sdk.SingleRecord(record)
+	}
+	return p.errorRecord(record, errorClassConversion, fmt.Errorf("unsupported FHIR resource type %s", resourceType))
 }
 
 func (p *Processor) convertFHIRToHL7(patient FHIRPatient) (string, error) {
-	currentTime := time.Now().Format("20060102150405")
-	msh := fmt.Sprintf("MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|%s||ADT^A01|%s|P|2.5|",
-		currentTime, currentTime)
+	// Strict mode requires the birth date in both directions
+	if p.config.ParseMode == parseModeStrict && patient.BirthDate == "" {
+		return "", &FieldError{Segment: "PID", Field: "PID-7", Message: "missing birth date"}
+	}
 
-	var firstName, lastName string
-	if len(patient.Name) > 0 {
-		if len(patient.Name[0].Family) > 0 {
-			lastName = patient.Name[0].Family[0]
-		}
-		if len(patient.Name[0].Given) > 0 {
-			firstName = patient.Name[0].Given[0]
+	currentTime := p.messageTimestamp()
+	// MSH-1 is the field separator itself, so MSH-n is stored at index n-1
+	msh := newSegment("MSH", mshFieldCount-1)
+	msh[1] = "^~\\&"
+	msh[2] = "FHIR_CONVERTER"
+	msh[3] = defaultSendingFacility
+	if name := managingOrganizationName(patient); name != "" {
+		msh[3] = escapeHL7(name)
+	}
+	msh[4] = "HL7_PARSER"
+	msh[5] = "FACILITY"
+	for field := 3; field <= 6; field++ {
+		if code, ok := p.headerTag(patient, field); ok {
+			msh[field-1] = escapeHL7(code)
 		}
 	}
+	msh[6] = currentTime
+	msh[8] = "ADT^A01"
+	// the control ID is the timestamp without the UTC offset
+	msh[9] = currentTime[:14]
+	msh[10] = "P"
+	msh[11] = "2.5"
+	msh[17] = escapeHL7(p.config.OutputCharset)
 
-	var street, city, state, zip, country string
+	name := p.formatPatientNames(patient.Name)
+
+	var address string
 	if len(patient.Address) > 0 {
-		addr := patient.Address[0]
-		if len(addr.Line) > 0 {
-			street = addr.Line[0]
+		repetitions := make([]string, len(patient.Address))
+		for i, addr := range patient.Address {
+			addr.Country = normalizeCountry(addr.Country, p.config.CountryFormat)
+			repetitions[i] = formatXAD(addr)
 		}
-		city = addr.City
-		state = addr.State
-		zip = addr.PostalCode
-		country = addr.Country
+		address = strings.Join(repetitions, "~")
 	}
 
-	pid := fmt.Sprintf("PID|1||%s|%s|%s^%s||%s|%s|||%s^%s^%s^%s^%s||||||%s",
-		patient.ID,
-		"",
-		lastName,
-		firstName,
-		patient.BirthDate,
-		patient.Gender,
-		street,
-		city,
-		state,
-		zip,
-		country,
-		patient.ID,
-	)
+	account, identifiers := splitAccountNumber(patient.Identifier)
+	patientID := escapeHL7(patient.ID)
+	if len(identifiers) > 0 {
+		identifiers = p.prioritizeIdentifiers(identifiers)
+		repetitions := make([]string, len(identifiers))
+		for i, id := range identifiers {
+			repetitions[i] = formatCX(id)
+		}
+		patientID = strings.Join(repetitions, "~")
+	}
+
+	pid := newSegment("PID", pidFieldCount)
+	pid[1] = "1"
+	pid[3] = patientID
+	pid[5] = name
+	pid[7] = fhirToHL7Timestamp(patient.BirthDate)
+	pid[8] = p.patientSex(patient)
+	pid[11] = address
+	pid[13], pid[14] = p.formatPhoneNumbers(patient.Telecom)
+	pid[17] = escapeHL7(patient.ID)
+	if account != nil {
+		pid[18] = formatCX(*account)
+	}
+	pid[10] = formatCodedElements(usCoreCodedElements(patient, usCoreRaceURL))
+	pid[15] = formatCodedElement(primaryLanguage(patient.Communication))
+	pid[16] = fhirToHL7MaritalStatus(patient.MaritalStatus)
+	pid[22] = formatCodedElements(usCoreCodedElements(patient, usCoreEthnicityURL))
+	pid[28] = formatCodedElement(patientNationality(patient))
+	if patient.DeceasedDateTime != "" {
+		pid[29] = fhirToHL7TimestampElement(patient.DeceasedDateTime, patient.DeceasedDateTimeElement, p.timeLocation())
+		pid[30] = "Y"
+	} else if patient.DeceasedBoolean != nil {
+		pid[30] = "N"
+		if *patient.DeceasedBoolean {
+			pid[30] = "Y"
+		}
+	}
+	if patient.MultipleBirthInteger != nil {
+		pid[24] = "Y"
+		pid[25] = strconv.Itoa(*patient.MultipleBirthInteger)
+	} else if patient.MultipleBirthBoolean != nil {
+		pid[24] = "N"
+		if *patient.MultipleBirthBoolean {
+			pid[24] = "Y"
+		}
+	}
 
-	return msh + "\n" + pid, nil
+	segments := []string{strings.Join(msh, "|"), strings.Join(pid, "|")}
+	// empty contacts carry nothing to send
+	setID := 1
+	for _, contact := range patient.Contact {
+		if isEmptyContact(contact) {
+			continue
+		}
+		segments = append(segments, p.formatNextOfKin(setID, contact))
+		setID++
+	}
+
+	for i, segment := range segments {
+		segments[i] = p.applyDefaults(segment)
+	}
+
+	// Catch values that shift the fields of the generated segments
+	for _, segment := range segments {
+		if err := validateFieldCount(segment); err != nil {
+			return "", err
+		}
+	}
+	message := strings.Join(segments, p.segmentTerminator())
+	if p.config.VerifyOutput {
+		if err := p.verifyHL7(message, patient); err != nil {
+			return "", err
+		}
+	}
+	return message, nil
+}
+
+// segmentTerminator returns the configured segment terminator.
+func (p *Processor) segmentTerminator() string {
+	switch p.config.SegmentTerminator {
+	case `\n`:
+		return "\n"
+	case `\r\n`:
+		return "\r\n"
+	default:
+		return "\r"
+	}
+}
+
+// segmentFieldCounts holds the number of fields of the generated segments.
+var segmentFieldCounts = map[string]int{
+	"MSH": mshFieldCount,
+	"PID": pidFieldCount,
+	"NK1": nk1FieldCount,
+	"OBX": obxFieldCount,
+}
+
+// applyDefaults fills the empty fields of a generated segment that have a
+// configured default value.
+func (p *Processor) applyDefaults(segment string) string {
+	if len(p.fieldDefaults) == 0 {
+		return segment
+	}
+	fields := strings.Split(segment, "|")
+	for path, value := range p.fieldDefaults {
+		if path.Segment != fields[0] {
+			continue
+		}
+		idx := path.Field
+		if path.Segment == "MSH" {
+			idx--
+		}
+		if idx < len(fields) && fields[idx] == "" {
+			fields[idx] = value
+		}
+	}
+	return strings.Join(fields, "|")
+}
+
+// validateFieldCount checks that a generated segment has the number of
+// fields documented in segmentFieldCounts.
+func validateFieldCount(segment string) error {
+	name := segment[:3]
+	got := strings.Count(segment, "|")
+	if name == "MSH" {
+		// MSH-1 is the field separator itself
+		got++
+	}
+	if want := segmentFieldCounts[name]; got != want {
+		return &FieldError{
+			Segment: name,
+			Message: fmt.Sprintf("generated %s segment has %d fields, expected %d", name, got, want),
+		}
+	}
+	return nil
+}
+
+// formatPatientNames formats the FHIR names as PID-5 repetitions. The first
+// current name is emitted first, followed by the historical names, which
+// carry the configured name type code (XPN-7) and their validity period
+// (XPN-12/XPN-13).
+func (p *Processor) formatPatientNames(names []HumanName) string {
+	var current string
+	var hasCurrent bool
+	var historical []string
+	now := p.now()
+	for _, n := range names {
+		var family, given string
+		if len(n.Family) > 0 {
+			family = n.Family[0]
+		}
+		if len(n.Given) > 0 {
+			given = n.Given[0]
+		}
+
+		if !isHistoricalName(n, now) {
+			if !hasCurrent {
+				current = joinComponents(family, given)
+				hasCurrent = true
+			}
+			continue
+		}
+
+		nameType := p.config.HistoricalNameType
+		if nameType == "" {
+			nameType = "NOUSE"
+		}
+		xpn := make([]string, 13)
+		xpn[0], xpn[1], xpn[6] = escapeHL7(family), escapeHL7(given), nameType
+		if n.Period != nil {
+			xpn[11] = fhirToHL7Timestamp(n.Period.Start)
+			xpn[12] = fhirToHL7Timestamp(n.Period.End)
+		}
+		historical = append(historical, strings.TrimRight(strings.Join(xpn, "^"), "^"))
+	}
+
+	if !hasCurrent && len(historical) == 0 {
+		return "^"
+	}
+	repetitions := historical
+	if hasCurrent {
+		repetitions = append([]string{current}, historical...)
+	}
+	return strings.Join(repetitions, "~")
+}
+
+// isHistoricalName reports whether the name is no longer in use, either
+// because it is marked as old or because its period ended before now.
+func isHistoricalName(n HumanName, now time.Time) bool {
+	return n.Use == "old" || periodEnded(n.Period, now)
+}
+
+// periodEnded reports whether period has an end before now.
+func periodEnded(period *Period, now time.Time) bool {
+	if period == nil || period.End == "" {
+		return false
+	}
+	end, err := parseFHIRTime(period.End)
+	return err == nil && end.Before(now)
+}
+
+// prioritizeIdentifiers returns the identifiers with the first one of the
+// configured primary type moved to the front, so it ends up as the primary
+// PID-3 repetition. The order of the other identifiers is preserved.
+func (p *Processor) prioritizeIdentifiers(identifiers []Identifier) []Identifier {
+	primaryType := p.config.PrimaryIdentifierType
+	if primaryType == "" {
+		primaryType = "MR"
+	}
+	for i, id := range identifiers {
+		if id.Type == nil || len(id.Type.Coding) == 0 || id.Type.Coding[0].Code != primaryType {
+			continue
+		}
+		prioritized := make([]Identifier, 0, len(identifiers))
+		prioritized = append(prioritized, id)
+		prioritized = append(prioritized, identifiers[:i]...)
+		return append(prioritized, identifiers[i+1:]...)
+	}
+	return identifiers
+}
+
+// formatCX formats an identifier as an HL7 extended composite ID:
+// ID^check digit^check digit scheme^assigning authority^identifier type.
+func formatCX(id Identifier) string {
+	var idType string
+	if id.Type != nil && len(id.Type.Coding) > 0 {
+		idType = id.Type.Coding[0].Code
+	}
+	if id.Period != nil {
+		return joinComponents(id.Value, "", "", id.System, idType, "",
+			fhirToHL7Timestamp(id.Period.Start), fhirToHL7Timestamp(id.Period.End))
+	}
+	if id.System == "" && idType == "" {
+		return escapeHL7(id.Value)
+	}
+	return joinComponents(id.Value, "", "", id.System, idType)
+}
+
+// Number of fields emitted in the generated segments.
+const (
+	mshFieldCount = 18
+	pidFieldCount = 30
+)
+
+// appendUnknownSegments appends the segments preserved in metadata by an
+// earlier HL7 v2 to FHIR conversion to message, in their original order.
+func (p *Processor) appendUnknownSegments(message string, metadata opencdc.Metadata) (string, error) {
+	raw, ok := metadata[metadataUnknownSegments]
+	if !ok {
+		return message, nil
+	}
+	var segments []string
+	if err := json.Unmarshal([]byte(raw), &segments); err != nil {
+		return "", fmt.Errorf("invalid %s metadata: %w", metadataUnknownSegments, err)
+	}
+	terminator := p.segmentTerminator()
+	for _, segment := range segments {
+		// the event segment follows the message header
+		if strings.HasPrefix(segment, "EVN|") {
+			header, rest, _ := strings.Cut(message, terminator)
+			message = header + terminator + segment + terminator + rest
+			continue
+		}
+		message += terminator + segment
+	}
+	return message, nil
+}
+
+// newSegment returns the fields of a segment with the given name and room for
+// fields 1 to n.
+func newSegment(name string, n int) []string {
+	fields := make([]string, n+1)
+	fields[0] = name
+	return fields
+}
+
+// primaryLanguage returns the language of the preferred communication, or of
+// the first one if none is preferred.
+func primaryLanguage(communication []Communication) CodedElement {
+	if len(communication) == 0 {
+		return CodedElement{}
+	}
+	primary := communication[0]
+	for _, c := range communication {
+		if c.Preferred {
+			primary = c
+			break
+		}
+	}
+	if len(primary.Language.Coding) == 0 {
+		return CodedElement{Text: primary.Language.Text}
+	}
+	coding := primary.Language.Coding[0]
+	return CodedElement{Code: coding.Code, Text: coding.Display}
+}
+
+// patientNationality returns the nationality from the patient-nationality
+// extension, or an empty CodedElement if the patient has none.
+func patientNationality(patient FHIRPatient) CodedElement {
+	for _, ext := range patient.Extension {
+		if ext.URL != nationalityExtensionURL {
+			continue
+		}
+		for _, sub := range ext.Extension {
+			if sub.URL != "code" || sub.ValueCodeableConcept == nil {
+				continue
+			}
+			concept := sub.ValueCodeableConcept
+			if len(concept.Coding) == 0 {
+				return CodedElement{Text: concept.Text}
+			}
+			coding := concept.Coding[0]
+			text := coding.Display
+			if text == "" {
+				text = concept.Text
+			}
+			return CodedElement{Code: coding.Code, Text: text, System: hl7CodingSystem(coding.System)}
+		}
+	}
+	return CodedElement{}
+}
+
+// formatCodedElement formats a coded element as identifier^text^coding
+// system, dropping empty trailing components.
+func formatCodedElement(ce CodedElement) string {
+	return strings.TrimRight(joinComponents(ce.Code, ce.Text, ce.System), "^")
+}
+
+// formatXAD formats an address as an HL7 extended address in the layout the
+// parser reads by default: street^city^state^zip^country, followed by the
+// address type in XAD-7 when the address has a use or type.
+func formatXAD(addr Address) string {
+	var street string
+	if len(addr.Line) > 0 {
+		street = addr.Line[0]
+	}
+	xad := joinComponents(street, addr.City, addr.State, addr.PostalCode, addr.Country)
+	if addrType := hl7AddressType(addr); addrType != "" {
+		xad += "^^" + addrType
+	}
+	return xad
+}
+
+// hl7AddressType returns the HL7 address type (table 0190) of a FHIR address.
+func hl7AddressType(addr Address) string {
+	if addr.Type == "postal" {
+		return "M"
+	}
+	switch addr.Use {
+	case "home":
+		return "H"
+	case "work":
+		return "B"
+	case "temp":
+		return "C"
+	case "old":
+		return "BA"
+	case "billing":
+		return "BI"
+	}
+	return ""
+}
+
+// formatCodedElements formats coded elements as the repetitions of a CE
+// field.
+func formatCodedElements(values []CodedElement) string {
+	repetitions := make([]string, len(values))
+	for i, v := range values {
+		repetitions[i] = formatCodedElement(v)
+	}
+	return strings.Join(repetitions, "~")
 }
 
 // Add validation for compatible types
@@ -462,86 +2083,112 @@ func (p *Processor) Validate(ctx context.Context, cfg config.Config) error {
 		return err
 	}
 
-	// Define valid conversion paths
-	validConversions := map[string][]string{
-		"fhir":  {"hl7", "hl7v3"},
-		"hl7":   {"fhir"},
-		"hl7v3": {"fhir"},
+	// The conversion of each record is checked when it is processed
+	if config.InputType == conversionAuto || config.OutputType == conversionAuto {
+		return nil
 	}
-
-	if allowed, exists := validConversions[config.InputType]; exists {
-		for _, a := range allowed {
-			if a == config.OutputType {
-				return nil
-			}
-		}
-	}
-
-	return fmt.Errorf("invalid conversion from %s to %s", config.InputType, config.OutputType)
+	return validateConversion(config.InputType, config.OutputType)
 }
 
 func (p *Processor) convertFHIRToHL7V3(patient FHIRPatient) ([]byte, error) {
-	// Convert FHIR date to HL7v3 format
+	// Convert FHIR date to HL7v3 format, keeping its precision. A dateTime
+	// birth date keeps its time and time zone.
 	birthTime := ""
 	if patient.BirthDate != "" {
-		birthTime = strings.ReplaceAll(patient.BirthDate, "-", "") + "000000"
+		if _, err := parseFHIRTime(patient.BirthDate); err != nil {
+			return nil, fmt.Errorf("invalid birthDate: %w", err)
+		}
+		birthTime = fhirToHL7Timestamp(patient.BirthDate)
 	}
 
 	v3Patient := HL7V3Patient{
-		XMLName: xml.Name{Local: "Patient", Space: "urn:hl7-org:v3"},
-		ID:      patient.ID,
-		Name: struct {
-			Given  string `xml:"given"`
-			Family string `xml:"family"`
-		}{
-			Given:  patient.Name[0].Given[0],
-			Family: patient.Name[0].Family[0],
-		},
-		Gender: struct {
-			Code string `xml:"code"`
-		}{
-			Code: strings.ToUpper(patient.Gender[:1]),
-		},
+		Xmlns:  hl7V3Namespace,
+		ID:     patient.ID,
+		Gender: fhirToHL7V3Gender(patient.Gender),
 		BirthTime: struct {
 			Value string `xml:"value"`
 		}{
 			Value: birthTime,
 		},
-		Address: struct {
-			Street     string `xml:"streetAddressLine"`
-			City       string `xml:"city"`
-			State      string `xml:"state"`
-			PostalCode string `xml:"postalCode"`
-		}{
-			Street:     patient.Address[0].Line[0],
-			City:       patient.Address[0].City,
-			State:      patient.Address[0].State,
-			PostalCode: patient.Address[0].PostalCode,
-		},
 	}
+	for _, name := range patient.Name {
+		var v3Name HL7V3Name
+		if len(name.Given) > 0 {
+			v3Name.Given = name.Given[0]
+		}
+		if len(name.Family) > 0 {
+			v3Name.Family = name.Family[0]
+		}
+		v3Patient.Name = append(v3Patient.Name, v3Name)
+	}
+	for _, addr := range patient.Address {
+		v3Addr := HL7V3Address{
+			Use:        fhirAddressUses[addr.Use],
+			City:       addr.City,
+			State:      addr.State,
+			PostalCode: addr.PostalCode,
+		}
+		if len(addr.Line) > 0 {
+			v3Addr.Street = addr.Line[0]
+		}
+		v3Patient.Address = append(v3Patient.Address, v3Addr)
+	}
+	for _, point := range patient.Telecom {
+		v3Patient.Telecom = append(v3Patient.Telecom, formatTelecom(point))
+	}
+
+	if p.config.PrettyPrint {
+		out, err := xml.MarshalIndent(v3Patient, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), out...), nil
+	}
+	out, err := xml.Marshal(v3Patient)
+	if err != nil {
+		return nil, err
+	}
+	// compact output stays on a single line
+	return append([]byte(strings.TrimSuffix(xml.Header, "\n")), out...), nil
+}
 
-	return xml.MarshalIndent(v3Patient, "", "  ")
+// marshalJSON encodes v as JSON, indented by JSONIndent if PrettyPrint is
+// set.
+func (p *Processor) marshalJSON(v interface{}) ([]byte, error) {
+	if p.config.PrettyPrint {
+		return json.MarshalIndent(v, "", p.jsonIndent)
+	}
+	return json.Marshal(v)
 }
 
-func (p *Processor) Parameters() map[string]config.Parameter {
-	return map[string]config.Parameter{
-		"inputType": {
-			Default:     "fhir",
-			Description: "Input data type: 'fhir', 'hl7' (v2), or 'hl7v3'",
-			Type:        config.ParameterTypeString,
-			Validations: []config.Validation{
-				config.ValidationRequired{},
-				config.ValidationInclusion{List: []string{"fhir", "hl7", "hl7v3"}},
-			},
-		},
-		"outputType": {
-			Default:     "hl7",
-			Description: "Output data type: 'fhir', 'hl7' (v2), or 'hl7v3'",
-			Type:        config.ParameterTypeString,
-			Validations: []config.Validation{
-				config.ValidationRequired{},
-				config.ValidationInclusion{List: []string{"fhir", "hl7", "hl7v3"}},
-			},
-		},
+// maxJSONIndent is the largest number of spaces accepted as JSONIndent.
+const maxJSONIndent = 8
+
+// parseJSONIndent returns the indent string of a JSONIndent value.
+func parseJSONIndent(v string) (string, error) {
+	if v == "tab" {
+		return "\t", nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > maxJSONIndent {
+		return "", fmt.Errorf("invalid jsonIndent %q: expected a number of spaces from 0 to %d or \"tab\"", v, maxJSONIndent)
+	}
+	return strings.Repeat(" ", n), nil
+}
+
+// fhirToHL7V3Gender maps a FHIR gender to an HL7v3 administrativeGenderCode.
+// Unknown or missing genders are represented with the UNK null flavor.
+func fhirToHL7V3Gender(gender string) HL7V3Gender {
+	var code string
+	switch gender {
+	case "male":
+		code = "M"
+	case "female":
+		code = "F"
+	case "other":
+		code = "UN"
+	default:
+		return HL7V3Gender{NullFlavor: "UNK"}
 	}
+	return HL7V3Gender{Code: code, CodeSystem: administrativeGenderOID}
 }
@@ -1,14 +1,22 @@
 package hl7
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/conduitio/conduit-commons/opencdc"
 	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/conduitio/conduit-processor-sdk/pprocutils"
 	"github.com/matryer/is"
+	"github.com/rs/zerolog"
 )
 
 func TestProcessor_Process(t *testing.T) {
@@ -201,11 +209,11 @@ func TestProcessor_Specification(t *testing.T) {
 
 // Helper function to split HL7 message into segments
 func splitHL7Message(msg string) []string {
-	// In a real HL7 message, segments are separated by \r, but in our implementation we use \n
+	// Segments are terminated by \r, \n or \r\n depending on the configuration
 	segments := make([]string, 0)
 	current := ""
 	for _, char := range msg {
-		if char == '\n' {
+		if char == '\r' || char == '\n' {
 			if current != "" {
 				segments = append(segments, current)
 				current = ""
@@ -226,10 +234,7 @@ func TestConvertFHIRToHL7(t *testing.T) {
 
 	patient := FHIRPatient{
 		ID: "123",
-		Name: []struct {
-			Family []string `json:"family"`
-			Given  []string `json:"given"`
-		}{
+		Name: []HumanName{
 			{
 				Family: []string{"Smith"},
 				Given:  []string{"John"},
@@ -237,13 +242,7 @@ func TestConvertFHIRToHL7(t *testing.T) {
 		},
 		BirthDate: "1990-01-01",
 		Gender:    "male",
-		Address: []struct {
-			Line       []string `json:"line"`
-			City       string   `json:"city"`
-			State      string   `json:"state"`
-			PostalCode string   `json:"postalCode"`
-			Country    string   `json:"country"`
-		}{
+		Address: []Address{
 			{
 				Line:       []string{"123 Main St"},
 				City:       "Springfield",
@@ -264,11 +263,67 @@ func TestConvertFHIRToHL7(t *testing.T) {
 	pidFields := splitHL7Field(segments[1])
 	is.Equal(pidFields[3], "123")                                   // Patient ID
 	is.Equal(pidFields[5], "Smith^John")                            // Name
-	is.Equal(pidFields[7], "1990-01-01")                            // Birth Date
-	is.Equal(pidFields[8], "male")                                  // Gender
+	is.Equal(pidFields[7], "19900101")                              // Birth Date
+	is.Equal(pidFields[8], "M")                                     // Gender
 	is.Equal(pidFields[11], "123 Main St^Springfield^IL^62701^USA") // Address
 }
 
+func TestConvertFHIRToHL7_Clock(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	is.NoErr(p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+		"timezone":   "America/New_York",
+	}))
+	p.clock = func() time.Time { return time.Date(2023, 8, 15, 16, 30, 0, 0, time.UTC) }
+
+	hl7Message, err := p.convertFHIRToHL7(FHIRPatient{
+		ID:        "123",
+		Name:      []HumanName{{Family: []string{"Smith"}, Given: []string{"John"}}},
+		BirthDate: "1990-01-01",
+	})
+	is.NoErr(err)
+	mshFields := splitHL7Field(splitHL7Message(hl7Message)[0])
+	is.Equal(mshFields[6], "20230815123000-0400") // MSH-7
+	is.Equal(mshFields[9], "20230815123000")      // MSH-10
+}
+
+func TestConvertFHIRToHL7_Timezone(t *testing.T) {
+	is := is.New(t)
+	patient := FHIRPatient{
+		ID:        "123",
+		Name:      []HumanName{{Family: []string{"Smith"}, Given: []string{"John"}}},
+		BirthDate: "1990-01-01",
+	}
+
+	for timezone, offset := range map[string]string{
+		"":             "+0000", // UTC by default
+		"Asia/Kolkata": "+0530",
+	} {
+		p := NewProcessor().(*Processor)
+		cfg := map[string]string{"inputType": "fhir", "outputType": "hl7"}
+		if timezone != "" {
+			cfg["timezone"] = timezone
+		}
+		is.NoErr(p.Configure(context.Background(), cfg))
+
+		hl7Message, err := p.convertFHIRToHL7(patient)
+		is.NoErr(err)
+		mshFields := splitHL7Field(splitHL7Message(hl7Message)[0])
+		is.Equal(len(mshFields[6]), 19)                  // YYYYMMDDHHMMSS+ZZZZ
+		is.True(strings.HasSuffix(mshFields[6], offset)) // MSH-7 carries the offset
+		is.Equal(mshFields[9], mshFields[6][:14])        // control ID without offset
+	}
+
+	err := NewProcessor().Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+		"timezone":   "Mars/Olympus_Mons",
+	})
+	is.True(err != nil)
+}
+
 // Helper function to split HL7 field
 func splitHL7Field(segment string) []string {
 	fields := make([]string, 0)
@@ -326,7 +381,7 @@ func TestParseHL7Message(t *testing.T) {
 
 	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male|||123 Main St^Springfield^IL^62701^USA||||||123"
 
-	msg, err := parseHL7Message(hl7String)
+	msg, err := parseHL7Message(hl7String, parseOptions{})
 	is.NoErr(err)
 
 	// Test MSH segment fields
@@ -353,29 +408,20 @@ func TestConvertHL7V3ToFHIR(t *testing.T) {
 
 	v3Patient := HL7V3Patient{
 		ID: "pat-7335",
-		Name: struct {
-			Given  string `xml:"given"`
-			Family string `xml:"family"`
-		}{
-			Given:  "Novella",
-			Family: "Hoeger",
+		Name: []HL7V3Name{
+			{Given: "Novella", Family: "Hoeger"},
 		},
-		Gender: struct {
-			Code string `xml:"code"`
-		}{Code: "M"},
+		Gender: HL7V3Gender{Code: "M"},
 		BirthTime: struct {
 			Value string `xml:"value"`
 		}{Value: "19760320000000"},
-		Address: struct {
-			Street     string `xml:"streetAddressLine"`
-			City       string `xml:"city"`
-			State      string `xml:"state"`
-			PostalCode string `xml:"postalCode"`
-		}{
-			Street:     "6847 Vistaside",
-			City:       "Greensboro",
-			State:      "Vermont",
-			PostalCode: "89755",
+		Address: []HL7V3Address{
+			{
+				Street:     "6847 Vistaside",
+				City:       "Greensboro",
+				State:      "Vermont",
+				PostalCode: "89755",
+			},
 		},
 	}
 
@@ -393,3 +439,1540 @@ func TestConvertHL7V3ToFHIR(t *testing.T) {
 	is.Equal(patient.Address[0].State, "Vermont")
 	is.Equal(patient.Address[0].PostalCode, "89755")
 }
+
+func TestProcess_TrailingSegmentTerminator(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+		"parseMode":  "strict",
+	})
+	is.NoErr(err)
+
+	message := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A01|MSG00001|P|2.5\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M"
+	merge := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A40|MSG00002|P|2.5\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M\rMRG|456^^^^MR\r" +
+		"PID|2||789^^^^MR||Roe^Jane||19810202|F"
+	for _, input := range []string{
+		message + "\r",
+		message + "\n",
+		message + "\r\n",
+		message + "\r\r\n\n",
+		merge + "\r",
+	} {
+		result := p.Process(context.Background(), []opencdc.Record{{
+			Payload: opencdc.Change{After: opencdc.RawData(input)},
+		}})
+		_, ok := result[0].(sdk.SingleRecord)
+		is.True(ok) // empty trailing segments should be skipped
+
+		msg, err := parseHL7Message(input, parseOptions{strict: true})
+		is.NoErr(err)
+		is.Equal(len(msg.Warnings), 0)
+	}
+
+	segments, err := p.(*Processor).Inspect(message + "\r\n")
+	is.NoErr(err)
+	is.Equal(segments, []string{"MSH:12", "PID:8"})
+}
+
+func TestParseHL7Message_FieldMappings(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":     "hl7",
+		"outputType":    "fhir",
+		"fieldMappings": `{"patientId": "PID-2"}`,
+	})
+	is.NoErr(err)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1|A-987|123||Smith^John||1990-01-01|male"
+
+	msg, err := parseHL7Message(hl7String, p.parseOptions())
+	is.NoErr(err)
+	is.Equal(msg.PID.ID, "A-987")       // patient ID read from PID-2
+	is.Equal(msg.PID.LastName, "Smith") // other fields keep their default positions
+}
+
+func TestProcessor_Configure_FieldMappings(t *testing.T) {
+	is := is.New(t)
+	p := &Processor{}
+
+	invalidMappings := []string{
+		`{"patientId": "PID3"}`,
+		`{"patientId": "PID-0"}`,
+		`{"unknownField": "PID-3"}`,
+		`not json`,
+	}
+
+	for _, mappings := range invalidMappings {
+		err := p.Configure(context.Background(), map[string]string{
+			"inputType":     "hl7",
+			"outputType":    "fhir",
+			"fieldMappings": mappings,
+		})
+		is.True(err != nil) // Configure should fail with invalid field mappings
+	}
+}
+
+func TestConvertFHIRToHL7_Defaults(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+		"defaults":   `{"MSH-18": "UNICODE UTF-8", "PID-8": "U", "PID-11": "^^^^USA", "PID-3": "UNUSED"}`,
+	})
+	is.NoErr(err)
+
+	hl7Message, err := p.convertFHIRToHL7(FHIRPatient{ID: "123", Name: []HumanName{{Family: []string{"Smith"}}}})
+	is.NoErr(err)
+	segments := splitHL7Message(hl7Message)
+	is.Equal(splitHL7Field(segments[0])[17], "UNICODE UTF-8") // MSH-18
+	pidFields := splitHL7Field(segments[1])
+	is.Equal(pidFields[8], "U")        // missing gender
+	is.Equal(pidFields[11], "^^^^USA") // missing address
+	is.Equal(pidFields[3], "123")      // set fields are kept
+	is.Equal(pidFields[5], "Smith^")   // others are untouched
+	is.Equal(strings.Count(segments[1], "|"), pidFieldCount)
+
+	invalidDefaults := []string{
+		`{"PID-8.1": "U"}`,
+		`{"MSH-2": "^~\\&"}`,
+		`{"ZZZ-1": "x"}`,
+		`{"PID-31": "x"}`,
+		`not json`,
+	}
+	for _, defaults := range invalidDefaults {
+		err := p.Configure(context.Background(), map[string]string{
+			"inputType":  "fhir",
+			"outputType": "hl7",
+			"defaults":   defaults,
+		})
+		is.True(err != nil) // Configure should fail with invalid defaults
+	}
+}
+
+func TestProcessor_Process_UnsupportedResource(t *testing.T) {
+	is := is.New(t)
+	input := `{"resourceType": "Observation", "id": "obs-1", "status": "final", "code": {"text": "Heart rate"}}`
+
+	for _, outputType := range []string{"hl7", "hl7v3"} {
+		p := NewProcessor()
+		err := p.Configure(context.Background(), map[string]string{
+			"inputType":  "fhir",
+			"outputType": outputType,
+		})
+		is.NoErr(err)
+		result := p.Process(context.Background(), []opencdc.Record{{
+			Payload: opencdc.Change{After: opencdc.RawData(input)},
+		}})
+		errRecord, ok := result[0].(sdk.ErrorRecord)
+		is.True(ok) // non-Patient resources are rejected by default
+		is.True(strings.Contains(errRecord.Error.Error(), "unsupported FHIR resource type Observation"))
+
+		err = p.Configure(context.Background(), map[string]string{
+			"inputType":             "fhir",
+			"outputType":            outputType,
+			"onUnsupportedResource": "pass",
+		})
+		is.NoErr(err)
+		result = p.Process(context.Background(), []opencdc.Record{{
+			Payload: opencdc.Change{After: opencdc.RawData(input)},
+		}})
+		rec, ok := result[0].(sdk.SingleRecord)
+		is.True(ok) // the record should pass through
+		is.Equal(string(rec.Payload.After.Bytes()), input)
+	}
+}
+
+func TestConvertFHIRToHL7_ManagingOrganization(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	patient := FHIRPatient{ID: "123", Name: []HumanName{{Family: []string{"Smith"}}}}
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[0])[3], "FACILITY") // default MSH-4
+
+	patient.ManagingOrganization = &Reference{Reference: "Organization/1", Display: "General & Co"}
+	hl7Message, err = p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[0])[3], "General \\T\\ Co")
+
+	patient.ManagingOrganization = &Reference{Reference: "Organization/1"}
+	hl7Message, err = p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[0])[3], "FACILITY") // no display
+}
+
+func TestConvertHL7ToFHIR_PatientIdentifierComponents(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123456^^^HOSP^MR||Smith^John||1990-01-01|male"
+
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+	is.Equal(msg.PID.ID, "123456")
+	is.Equal(msg.PID.Identifiers[0].AssigningAuthority, "HOSP")
+	is.Equal(msg.PID.Identifiers[0].IdentifierType, "MR")
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.ID, "123456")
+	is.Equal(len(patient.Identifier), 1)
+	is.Equal(patient.Identifier[0].Value, "123456")
+	is.Equal(patient.Identifier[0].System, "HOSP")
+	is.Equal(patient.Identifier[0].Type.Coding[0].Code, "MR")
+	is.Equal(patient.Identifier[0].Type.Coding[0].System, identifierTypeSystem)
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[3], "123456^^^HOSP^MR") // identifier components are emitted back
+}
+
+func TestProcessor_Inspect(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male|||123 Main St^Springfield^IL^62701^USA||||||123"
+
+	segments, err := p.Inspect(hl7String)
+	is.NoErr(err)
+	is.Equal(segments, []string{"MSH:13", "PID:17"})
+
+	_, err = p.Inspect("INVALID|HL7|MESSAGE")
+	is.True(err != nil) // Inspect should fail without an MSH segment
+}
+
+func TestConvertHL7ToFHIR_PatientIdentifierRepetitions(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||999-99-9999^^^SSA^SS~12345^^^HOSP^MR||Smith^John||1990-01-01|male"
+
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+	is.Equal(msg.PID.ID, "12345") // the MRN is the patient ID even when not first
+	is.Equal(len(msg.PID.Identifiers), 2)
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.ID, "12345")
+	is.Equal(len(patient.Identifier), 2)
+	is.Equal(patient.Identifier[0].Value, "999-99-9999")
+	is.Equal(patient.Identifier[0].System, "SSA")
+	is.Equal(patient.Identifier[0].Type.Coding[0].Code, "SS")
+	is.Equal(patient.Identifier[1].Value, "12345")
+	is.Equal(patient.Identifier[1].System, "HOSP")
+	is.Equal(patient.Identifier[1].Type.Coding[0].Code, "MR")
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[3], "12345^^^HOSP^MR~999-99-9999^^^SSA^SS") // repetitions are emitted back, MRN first
+}
+
+func TestConvertHL7ToFHIR_ExpiredIdentifier(t *testing.T) {
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||12345^^^HOSP^MR~OLD42^^^HOSP^PI^^20100101^20151231~NEW7^^^HOSP^PI^^20160101^29991231||Smith^John||1990-01-01|male"
+
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("marked", func(t *testing.T) {
+		is := is.New(t)
+		p := NewProcessor().(*Processor)
+
+		patient, err := p.convertHL7ToFHIR(msg)
+		is.NoErr(err)
+		is.Equal(len(patient.Identifier), 3)
+		is.Equal(patient.Identifier[0].Period, nil)
+		is.Equal(patient.Identifier[1].Value, "OLD42")
+		is.Equal(patient.Identifier[1].Use, "old")
+		is.Equal(*patient.Identifier[1].Period, Period{Start: "2010-01-01", End: "2015-12-31"})
+		is.Equal(patient.Identifier[2].Value, "NEW7")
+		is.Equal(patient.Identifier[2].Use, "") // not expired yet
+
+		hl7Message, err := p.convertFHIRToHL7(patient)
+		is.NoErr(err)
+		pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+		is.Equal(pidFields[3], "12345^^^HOSP^MR~OLD42^^^HOSP^PI^^20100101^20151231~NEW7^^^HOSP^PI^^20160101^29991231")
+	})
+
+	t.Run("excluded", func(t *testing.T) {
+		is := is.New(t)
+		p := NewProcessor().(*Processor)
+		err := p.Configure(context.Background(), map[string]string{
+			"inputType":                 "hl7",
+			"outputType":                "fhir",
+			"excludeExpiredIdentifiers": "true",
+		})
+		is.NoErr(err)
+
+		patient, err := p.convertHL7ToFHIR(msg)
+		is.NoErr(err)
+		is.Equal(len(patient.Identifier), 2)
+		is.Equal(patient.Identifier[0].Value, "12345")
+		is.Equal(patient.Identifier[1].Value, "NEW7")
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		is := is.New(t)
+		p := NewProcessor().(*Processor)
+		p.clock = func() time.Time { return time.Date(2012, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+		patient, err := p.convertHL7ToFHIR(msg)
+		is.NoErr(err)
+		is.Equal(patient.Identifier[1].Value, "OLD42")
+		is.Equal(patient.Identifier[1].Use, "") // still valid in 2012
+	})
+}
+
+func TestConvertHL7ToFHIR_DefaultIdentifierSystem(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":               "hl7",
+		"outputType":              "fhir",
+		"defaultIdentifierSystem": "urn:oid:2.16.840.1.113883.19.5",
+	})
+	is.NoErr(err)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||12345^^^^MR~987^^^HOSP^PI||Smith^John||19900101|M"
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(len(patient.Identifier), 2)
+	is.Equal(patient.Identifier[0].System, "urn:oid:2.16.840.1.113883.19.5") // no assigning authority
+	is.Equal(patient.Identifier[1].System, "HOSP")
+
+	// the system must be an absolute URI
+	err = p.Configure(context.Background(), map[string]string{
+		"inputType":               "hl7",
+		"outputType":              "fhir",
+		"defaultIdentifierSystem": "hospital-mrn",
+	})
+	is.True(err != nil)
+}
+
+func TestProcessor_Process_ParseMode(t *testing.T) {
+	// PID-7 (birth date) is empty
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John|||male"
+
+	t.Run("strict", func(t *testing.T) {
+		is := is.New(t)
+		p := NewProcessor()
+		err := p.Configure(context.Background(), map[string]string{
+			"inputType":  "hl7",
+			"outputType": "fhir",
+			"parseMode":  "strict",
+		})
+		is.NoErr(err)
+
+		result := p.Process(context.Background(), []opencdc.Record{{
+			Payload: opencdc.Change{After: opencdc.RawData(input)},
+		}})
+		is.Equal(len(result), 1)
+		errRecord, ok := result[0].(sdk.ErrorRecord)
+		is.True(ok) // should be an error record
+		is.True(strings.Contains(errRecord.Error.Error(), "birthDate"))
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		is := is.New(t)
+		p := NewProcessor()
+		err := p.Configure(context.Background(), map[string]string{
+			"inputType":  "hl7",
+			"outputType": "fhir",
+		})
+		is.NoErr(err)
+
+		result := p.Process(context.Background(), []opencdc.Record{{
+			Payload: opencdc.Change{After: opencdc.RawData(input)},
+		}})
+		is.Equal(len(result), 1)
+		processed, ok := result[0].(sdk.SingleRecord)
+		is.True(ok) // should be a single record
+
+		var patient FHIRPatient
+		err = json.Unmarshal(processed.Payload.After.Bytes(), &patient)
+		is.NoErr(err)
+		is.Equal(patient.ID, "123")
+		is.Equal(patient.BirthDate, "")
+
+		var warnings []ParseWarning
+		err = json.Unmarshal([]byte(processed.Metadata[metadataWarnings]), &warnings)
+		is.NoErr(err)
+		is.Equal(warnings, []ParseWarning{{
+			Segment: "PID",
+			Field:   "PID-7",
+			Message: "missing expected field birthDate",
+		}})
+	})
+}
+
+func TestProcess_LogDroppedFields(t *testing.T) {
+	is := is.New(t)
+	var logs bytes.Buffer
+	logger := pprocutils.Logger
+	pprocutils.Logger = zerolog.New(&logs).Level(zerolog.DebugLevel)
+	t.Cleanup(func() { pprocutils.Logger = logger })
+
+	input := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A01|MSG00001|P|2.5\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M|||||||||||||||||||||31/12/2020|Y"
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	})
+	is.NoErr(err)
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	processed, ok := result[0].(sdk.SingleRecord)
+	is.True(ok) // lenient parsing should drop the bad date
+
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(processed.Payload.After.Bytes(), &patient))
+	is.Equal(patient.DeceasedDateTime, "")
+	is.Equal(*patient.DeceasedBoolean, true) // the death indicator is kept
+
+	var dropped []map[string]string
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]string
+		if json.Unmarshal([]byte(line), &entry) == nil && entry["field"] != "" {
+			dropped = append(dropped, entry)
+		}
+	}
+	is.Equal(len(dropped), 1)
+	is.Equal(dropped[0]["level"], "debug")
+	is.Equal(dropped[0]["segment"], "PID")
+	is.Equal(dropped[0]["field"], "PID-29")
+	is.True(strings.HasPrefix(dropped[0]["reason"], "unparseable deathDateTime dropped"))
+
+	// the drops are not logged at info level
+	logs.Reset()
+	pprocutils.Logger = zerolog.New(&logs).Level(zerolog.InfoLevel)
+	p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	is.True(!strings.Contains(logs.String(), "PID-29"))
+}
+
+func TestProcess_NullFields(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor()
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+		"parseMode":  "strict",
+	})
+	is.NoErr(err)
+
+	process := func(pid string) (map[string]json.RawMessage, opencdc.Metadata) {
+		input := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A01|MSG00001|P|2.5\r" + pid
+		result := p.Process(context.Background(), []opencdc.Record{{
+			Payload: opencdc.Change{After: opencdc.RawData(input)},
+		}})
+		processed, ok := result[0].(sdk.SingleRecord)
+		is.True(ok) // should be a single record
+		var patient map[string]json.RawMessage
+		is.NoErr(json.Unmarshal(processed.Payload.After.Bytes(), &patient))
+		return patient, processed.Metadata
+	}
+
+	// "" deletes the name
+	patient, metadata := process(`PID|1||123||""||19800101|M|||""`)
+	is.Equal(string(patient["name"]), "[]")
+	is.Equal(patient["address"], json.RawMessage(nil)) // absent, FHIR JSON has no null
+	is.Equal(metadata[metadataNullFields], `["PID-5","PID-11"]`)
+
+	// an empty field leaves the value absent
+	patient, metadata = process("PID|1||123||Smith^John||19800101|M")
+	is.Equal(string(patient["name"]), `[{"family":["Smith"],"given":["John"]}]`)
+	_, ok := metadata[metadataNullFields]
+	is.True(!ok)
+}
+
+func TestProcess_PreserveUnknownSegments(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	input := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A01|MSG00001|P|2.5\r" +
+		"EVN|A01|20230815\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M\r" +
+		"IN1|1|PLAN01|INS01|Acme Insurance\r" +
+		"ZPD|1|VIP^Very important patient\r" +
+		"ZPD|2|NOPUBLICITY"
+
+	toFHIR := NewProcessor()
+	err := toFHIR.Configure(ctx, map[string]string{
+		"inputType":               "hl7",
+		"outputType":              "fhir",
+		"parseMode":               "strict",
+		"preserveUnknownSegments": "true",
+	})
+	is.NoErr(err)
+	result := toFHIR.Process(ctx, []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	fhirRecord, ok := result[0].(sdk.SingleRecord)
+	is.True(ok) // should be a single record
+	_, warned := fhirRecord.Metadata[metadataWarnings]
+	is.True(!warned) // preserved segments should not be reported as ignored
+
+	toHL7 := NewProcessor()
+	err = toHL7.Configure(ctx, map[string]string{
+		"inputType":               "fhir",
+		"outputType":              "hl7",
+		"preserveUnknownSegments": "true",
+	})
+	is.NoErr(err)
+	result = toHL7.Process(ctx, []opencdc.Record{opencdc.Record(fhirRecord)})
+	hl7Record, ok := result[0].(sdk.SingleRecord)
+	is.True(ok) // should be a single record
+
+	segments := splitHL7Message(hl7Record.Payload.After.(opencdc.StructuredData)["hl7"].(string))
+	is.Equal(len(segments), 6)
+	is.Equal(segments[1], "EVN|A01|20230815")
+	is.True(strings.HasPrefix(segments[2], "PID|"))
+	is.Equal(segments[3], "IN1|1|PLAN01|INS01|Acme Insurance") // modeled, but not regenerated
+	is.Equal(segments[4], "ZPD|1|VIP^Very important patient")
+	is.Equal(segments[5], "ZPD|2|NOPUBLICITY")
+}
+
+func TestConvertFHIRToHL7_PrimaryIdentifierType(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+	})
+	is.NoErr(err)
+
+	patient := FHIRPatient{
+		ID: "123",
+		Identifier: []Identifier{
+			{
+				Type:   &CodeableConcept{Coding: []Coding{{System: identifierTypeSystem, Code: "SS"}}},
+				System: "SSA",
+				Value:  "999-99-9999",
+			},
+			{
+				Type:   &CodeableConcept{Coding: []Coding{{System: identifierTypeSystem, Code: "MR"}}},
+				System: "HOSP",
+				Value:  "12345",
+			},
+		},
+	}
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[3], "12345^^^HOSP^MR~999-99-9999^^^SSA^SS") // MRN is the primary identifier
+
+	err = p.Configure(context.Background(), map[string]string{
+		"inputType":             "fhir",
+		"outputType":            "hl7",
+		"primaryIdentifierType": "SS",
+	})
+	is.NoErr(err)
+
+	hl7Message, err = p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields = splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[3], "999-99-9999^^^SSA^SS~12345^^^HOSP^MR")
+}
+
+func TestConvertFHIRToHL7_HistoricalName(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+	})
+	is.NoErr(err)
+
+	patient := FHIRPatient{
+		ID: "123",
+		Name: []HumanName{
+			{
+				Family: []string{"Jones"},
+				Given:  []string{"Mary"},
+				Period: &Period{Start: "1990-01-01", End: "2015-06-20"},
+			},
+			{
+				Family: []string{"Smith"},
+				Given:  []string{"Mary"},
+				Period: &Period{Start: "2015-06-20"},
+			},
+		},
+	}
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[5], "Smith^Mary~Jones^Mary^^^^^NOUSE^^^^^19900101^20150620") // current name first, historical name marked
+}
+
+func TestConvertHL7V3ToFHIR_GenderNullFlavor(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	input := `<Patient xmlns="urn:hl7-org:v3">
+		<id>pat-1</id>
+		<name><given>Alex</given><family>Doe</family></name>
+		<administrativeGenderCode nullFlavor="UNK" codeSystem="2.16.840.1.113883.5.1"/>
+	</Patient>`
+
+	var v3Patient HL7V3Patient
+	err := xml.Unmarshal([]byte(input), &v3Patient)
+	is.NoErr(err)
+	is.Equal(v3Patient.Gender.NullFlavor, "UNK")
+
+	patient, err := p.convertHL7V3ToFHIR(v3Patient)
+	is.NoErr(err)
+	is.Equal(patient.Gender, "unknown")
+
+	// unknown gender is emitted back as a null flavor
+	out, err := p.convertFHIRToHL7V3(patient)
+	is.NoErr(err)
+	is.True(strings.Contains(string(out), `<administrativeGenderCode nullFlavor="UNK"></administrativeGenderCode>`))
+
+	// a code from another code system is rejected
+	v3Patient.Gender = HL7V3Gender{Code: "M", CodeSystem: "1.2.3"}
+	_, err = p.convertHL7V3ToFHIR(v3Patient)
+	is.True(err != nil)
+}
+
+func TestConfigure_GenderMap(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	v3Patient := HL7V3Patient{
+		ID:     "pat-1",
+		Name:   []HL7V3Name{{Given: "Alex", Family: "Doe"}},
+		Gender: HL7V3Gender{Code: "O"},
+	}
+
+	// O is not a standard HL7v3 gender code
+	p := NewProcessor().(*Processor)
+	patient, err := p.convertHL7V3ToFHIR(v3Patient)
+	is.NoErr(err)
+	is.Equal(patient.Gender, "")
+
+	err = p.Configure(ctx, map[string]string{
+		"inputType":  "hl7v3",
+		"outputType": "fhir",
+		"genderMap":  `{"O": "other", "x": "unknown"}`,
+	})
+	is.NoErr(err)
+	patient, err = p.convertHL7V3ToFHIR(v3Patient)
+	is.NoErr(err)
+	is.Equal(patient.Gender, "other")
+
+	// the map applies to HL7 v2 input as well, codes are case insensitive
+	msg, err := parseHL7Message("MSH|^~\\&|APP|FAC|||20230815||ADT^A01|1|P|2.5\r"+
+		"PID|1||123^^^^MR||Doe^Alex||19800101|X\r"+
+		"NK1|1|Doe^Sam|BRO^Brother||||||||||||O", parseOptions{})
+	is.NoErr(err)
+	patient, err = p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.Gender, "unknown")
+	is.Equal(patient.Contact[0].Gender, "other")
+
+	// targets must be FHIR genders
+	err = p.Configure(ctx, map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+		"genderMap":  `{"X": "nonbinary"}`,
+	})
+	is.True(err != nil)
+}
+
+func TestProcess_PrettyPrint(t *testing.T) {
+	ctx := context.Background()
+	hl7Input := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A01|MSG00001|P|2.5\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M"
+	fhirInput := `{"id":"123","name":[{"family":["Doe"],"given":["John"]}],"birthDate":"1980-01-01","gender":"male"}`
+
+	process := func(is *is.I, inputType, outputType, prettyPrint, input string) []byte {
+		p := NewProcessor()
+		err := p.Configure(ctx, map[string]string{
+			"inputType":   inputType,
+			"outputType":  outputType,
+			"prettyPrint": prettyPrint,
+		})
+		is.NoErr(err)
+		result := p.Process(ctx, []opencdc.Record{{
+			Payload: opencdc.Change{After: opencdc.RawData(input)},
+		}})
+		processed, ok := result[0].(sdk.SingleRecord)
+		is.True(ok) // should be a single record
+		return processed.Payload.After.Bytes()
+	}
+
+	t.Run("fhir", func(t *testing.T) {
+		is := is.New(t)
+		compact := process(is, "hl7", "fhir", "false", hl7Input)
+		pretty := process(is, "hl7", "fhir", "true", hl7Input)
+		is.True(!strings.Contains(string(compact), "\n"))
+		is.True(strings.Contains(string(pretty), "\n  \"id\": \"123\""))
+		is.True(len(pretty) > len(compact))
+
+		var indented bytes.Buffer
+		is.NoErr(json.Indent(&indented, compact, "", "  "))
+		is.Equal(indented.String(), string(pretty))
+	})
+
+	t.Run("hl7v3", func(t *testing.T) {
+		is := is.New(t)
+		compact := process(is, "fhir", "hl7v3", "false", fhirInput)
+		pretty := process(is, "fhir", "hl7v3", "true", fhirInput)
+		is.True(!strings.Contains(string(compact), "\n"))
+		is.True(strings.Contains(string(pretty), "\n  <"))
+		is.True(len(pretty) > len(compact))
+	})
+}
+
+func TestProcess_JSONIndent(t *testing.T) {
+	ctx := context.Background()
+	hl7Input := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A01|MSG00001|P|2.5\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M"
+
+	testCases := []struct {
+		jsonIndent string
+		want       string
+	}{
+		{jsonIndent: "4", want: "{\n    \"resourceType\": \"Patient\",\n    \"id\": \"123\",\n"},
+		{jsonIndent: "tab", want: "{\n\t\"resourceType\": \"Patient\",\n\t\"id\": \"123\",\n"},
+		{jsonIndent: "0", want: "{\n\"resourceType\": \"Patient\",\n\"id\": \"123\",\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.jsonIndent, func(t *testing.T) {
+			is := is.New(t)
+			p := NewProcessor()
+			err := p.Configure(ctx, map[string]string{
+				"inputType":   "hl7",
+				"outputType":  "fhir",
+				"prettyPrint": "true",
+				"jsonIndent":  tc.jsonIndent,
+			})
+			is.NoErr(err)
+			result := p.Process(ctx, []opencdc.Record{{
+				Payload: opencdc.Change{After: opencdc.RawData(hl7Input)},
+			}})
+			processed, ok := result[0].(sdk.SingleRecord)
+			is.True(ok) // should be a single record
+			is.True(strings.HasPrefix(string(processed.Payload.After.Bytes()), tc.want))
+		})
+	}
+
+	is := is.New(t)
+	for _, jsonIndent := range []string{"9", "-1", "tabs"} {
+		err := NewProcessor().Configure(ctx, map[string]string{
+			"inputType":  "hl7",
+			"outputType": "fhir",
+			"jsonIndent": jsonIndent,
+		})
+		is.True(err != nil) // Configure should fail with an invalid jsonIndent
+	}
+}
+
+func TestConvertFHIRToHL7_MultipleBirth(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	// the second of twins
+	order := 2
+	twin := FHIRPatient{ID: "123", Name: []HumanName{{Family: []string{"Smith"}}}, MultipleBirthInteger: &order}
+	hl7Message, err := p.convertFHIRToHL7(twin)
+	is.NoErr(err)
+	pid := splitHL7Message(hl7Message)[1]
+	is.Equal(strings.Count(pid, "|"), pidFieldCount)
+	pidFields := splitHL7Field(pid)
+	is.Equal(pidFields[24], "Y")
+	is.Equal(pidFields[25], "2")
+
+	msg, err := parseHL7Message(hl7Message, parseOptions{})
+	is.NoErr(err)
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(*patient.MultipleBirthInteger, 2)
+	is.Equal(patient.MultipleBirthBoolean, nil)
+
+	// without birth order only the indicator is set
+	multipleBirth := true
+	hl7Message, err = p.convertFHIRToHL7(FHIRPatient{ID: "123", Name: []HumanName{{Family: []string{"Smith"}}}, MultipleBirthBoolean: &multipleBirth})
+	is.NoErr(err)
+	pidFields = splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[24], "Y")
+	is.Equal(pidFields[25], "")
+
+	msg, err = parseHL7Message(hl7Message, parseOptions{})
+	is.NoErr(err)
+	patient, err = p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(*patient.MultipleBirthBoolean, true)
+	is.Equal(patient.MultipleBirthInteger, nil)
+}
+
+func TestConvertHL7ToFHIR_Nationality(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male|||123 Main St^Springfield^IL^62701^USA||||||123|||||||||||CAN^Canada^ISO3166"
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+	is.Equal(msg.PID.Nationality, CodedElement{Code: "CAN", Text: "Canada", System: "ISO3166"})
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(len(patient.Extension), 1)
+	is.Equal(patient.Extension[0].URL, nationalityExtensionURL)
+	is.Equal(patient.Extension[0].Extension[0].URL, "code")
+	is.Equal(patient.Extension[0].Extension[0].ValueCodeableConcept.Coding[0].Code, "CAN")
+	is.Equal(patient.Extension[0].Extension[0].ValueCodeableConcept.Coding[0].System, "urn:iso:std:iso:3166")
+
+	// round trip back to PID-28
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pid := splitHL7Message(hl7Message)[1]
+	is.Equal(strings.Count(pid, "|"), pidFieldCount)
+	is.Equal(splitHL7Field(pid)[28], "CAN^Canada^ISO3166")
+}
+
+func TestConvertFHIRToHL7_OutputCharset(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":     "fhir",
+		"outputType":    "hl7",
+		"outputCharset": "UNICODE UTF-8",
+	})
+	is.NoErr(err)
+
+	hl7Message, err := p.convertFHIRToHL7(FHIRPatient{ID: "123"})
+	is.NoErr(err)
+	mshFields := splitHL7Field(splitHL7Message(hl7Message)[0])
+	is.Equal(len(mshFields), mshFieldCount)
+	is.Equal(mshFields[17], "UNICODE UTF-8") // MSH-18
+
+	// the parser reads the generated message and its character set
+	_, err = parseHL7Message(hl7Message, parseOptions{})
+	is.NoErr(err)
+	charset, ok := declaredCharset([]byte(hl7Message))
+	is.True(ok)
+	is.Equal(charset, charsetUTF8)
+
+	// only the character sets of table 0211 the processor reads are accepted
+	err = p.Configure(context.Background(), map[string]string{
+		"inputType":     "fhir",
+		"outputType":    "hl7",
+		"outputCharset": "UTF|8",
+	})
+	is.True(err != nil)
+}
+
+func TestConvertHL7V3ToFHIR_MultipleAddresses(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	input := `<Patient xmlns="urn:hl7-org:v3">
+		<id>pat-1</id>
+		<name><given>Alex</given><family>Doe</family></name>
+		<name><given>Alex</given><family>Smith</family></name>
+		<addr use="H">
+			<streetAddressLine>1 Home Rd</streetAddressLine>
+			<city>Springfield</city>
+		</addr>
+		<addr use="WP">
+			<streetAddressLine>2 Office Park</streetAddressLine>
+			<city>Shelbyville</city>
+		</addr>
+	</Patient>`
+
+	var v3Patient HL7V3Patient
+	err := xml.Unmarshal([]byte(input), &v3Patient)
+	is.NoErr(err)
+
+	patient, err := p.convertHL7V3ToFHIR(v3Patient)
+	is.NoErr(err)
+	is.Equal(len(patient.Name), 2)
+	is.Equal(patient.Name[1].Family[0], "Smith")
+	is.Equal(len(patient.Address), 2)
+	is.Equal(patient.Address[0].Use, "home")
+	is.Equal(patient.Address[0].Line[0], "1 Home Rd")
+	is.Equal(patient.Address[1].Use, "work")
+	is.Equal(patient.Address[1].City, "Shelbyville")
+
+	// both addresses are emitted back with their use
+	out, err := p.convertFHIRToHL7V3(patient)
+	is.NoErr(err)
+	is.Equal(strings.Count(string(out), "<name>"), 2)
+	is.True(strings.Contains(string(out), `<addr use="H">`))
+	is.True(strings.Contains(string(out), `<addr use="WP">`))
+}
+
+func TestConvertFHIRToHL7V3_BirthDateTime(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	patient := FHIRPatient{ID: "123", BirthDate: "1990-01-01T13:45:00Z"}
+	out, err := p.convertFHIRToHL7V3(patient)
+	is.NoErr(err)
+	var v3Patient HL7V3Patient
+	is.NoErr(xml.Unmarshal(out, &v3Patient))
+	is.Equal(v3Patient.BirthTime.Value, "19900101134500+0000") // the time is preserved
+
+	back, err := p.convertHL7V3ToFHIR(v3Patient)
+	is.NoErr(err)
+	is.Equal(back.BirthDate, "1990-01-01T13:45:00Z")
+
+	// dates keep their precision
+	for _, tt := range []struct{ date, birthTime string }{
+		{"1990-01-01", "19900101"},
+		{"1990-05", "199005"},
+		{"1990", "1990"},
+	} {
+		patient.BirthDate = tt.date
+		out, err = p.convertFHIRToHL7V3(patient)
+		is.NoErr(err)
+		is.NoErr(xml.Unmarshal(out, &v3Patient))
+		is.Equal(v3Patient.BirthTime.Value, tt.birthTime)
+		back, err = p.convertHL7V3ToFHIR(v3Patient)
+		is.NoErr(err)
+		is.Equal(back.BirthDate, tt.date)
+	}
+
+	// midnight times of other senders are still read back as dates
+	v3Patient.BirthTime.Value = "19900101000000"
+	back, err = p.convertHL7V3ToFHIR(v3Patient)
+	is.NoErr(err)
+	is.Equal(back.BirthDate, "1990-01-01")
+
+	patient.BirthDate = "19"
+	_, err = p.convertFHIRToHL7V3(patient)
+	is.True(err != nil) // invalid birth date
+}
+
+func TestProcessor_Process_HL7V3RoundTrip(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	convert := func(inputType, outputType string, input []byte) []byte {
+		p := NewProcessor()
+		is.NoErr(p.Configure(ctx, map[string]string{
+			"inputType":  inputType,
+			"outputType": outputType,
+		}))
+		result := p.Process(ctx, []opencdc.Record{{
+			Payload: opencdc.Change{After: opencdc.RawData(input)},
+		}})
+		rec, ok := result[0].(sdk.SingleRecord)
+		is.True(ok)
+		return rec.Payload.After.Bytes()
+	}
+
+	input := `{"resourceType":"Patient","id":"123","name":[{"family":["Smith"],"given":["John"]}],"gender":"male","birthDate":"1990-01-01","address":[{"use":"home","line":["123 Main St"],"city":"Springfield","state":"IL","postalCode":"62701"}]}`
+	xmlOut := convert("fhir", "hl7v3", []byte(input))
+	is.True(bytes.HasPrefix(xmlOut, []byte(`<?xml version="1.0" encoding="UTF-8"?>`)))
+	is.True(bytes.Contains(xmlOut, []byte(`<Patient xmlns="urn:hl7-org:v3">`)))
+
+	var patient FHIRPatient
+	is.NoErr(json.Unmarshal(convert("hl7v3", "fhir", xmlOut), &patient))
+	is.Equal(patient.ID, "123")
+	is.Equal(patient.Name[0].Family, []string{"Smith"})
+	is.Equal(patient.Name[0].Given, []string{"John"})
+	is.Equal(patient.Gender, "male")
+	is.Equal(patient.BirthDate, "1990-01-01")
+	is.Equal(patient.Address[0].Line, []string{"123 Main St"})
+	is.Equal(patient.Address[0].City, "Springfield")
+}
+
+func BenchmarkParseHL7Message(b *testing.B) {
+	// a 1000-segment batch: one MSH followed by 999 PID segments
+	msh := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\n"
+	pid := "PID|1||12345^^^HOSP^MR~999-99-9999^^^SSA^SS||Smith^John||1990-01-01|male|||123 Main St^Springfield^IL^62701^USA||||||123\n"
+	message := msh + strings.Repeat(pid, 999)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseHL7Message(message, parseOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestConvertHL7ToFHIR_AddressRepetitions(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male|||123 Main St^Springfield^IL^62701^USA^^H~PO Box 42^Springfield^IL^62705^USA^^M"
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(len(patient.Address), 2)
+	is.Equal(patient.Address[0].Use, "home")
+	is.Equal(patient.Address[0].Line[0], "123 Main St")
+	is.Equal(patient.Address[1].Use, "")
+	is.Equal(patient.Address[1].Type, "postal")
+	is.Equal(patient.Address[1].Line[0], "PO Box 42")
+	is.Equal(patient.Address[1].PostalCode, "62705")
+
+	// both repetitions are emitted back with their address type
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[11], "123 Main St^Springfield^IL^62701^USA^^H~PO Box 42^Springfield^IL^62705^USA^^M")
+}
+
+func TestProcessor_Process_IncludeResourceType(t *testing.T) {
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male"
+
+	tests := []struct {
+		name                string
+		includeResourceType string
+		want                bool
+	}{
+		{name: "default", want: true},
+		{name: "enabled", includeResourceType: "true", want: true},
+		{name: "disabled", includeResourceType: "false", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			p := NewProcessor().(*Processor)
+			cfg := map[string]string{
+				"inputType":  "hl7",
+				"outputType": "fhir",
+			}
+			if tt.includeResourceType != "" {
+				cfg["includeResourceType"] = tt.includeResourceType
+			}
+			err := p.Configure(context.Background(), cfg)
+			is.NoErr(err)
+
+			result := p.Process(context.Background(), []opencdc.Record{{
+				Payload: opencdc.Change{After: opencdc.RawData(input)},
+			}})
+			is.Equal(len(result), 1)
+			rec, ok := result[0].(sdk.SingleRecord)
+			is.True(ok)
+
+			var out map[string]interface{}
+			err = json.Unmarshal(rec.Payload.After.Bytes(), &out)
+			is.NoErr(err)
+			resourceType, ok := out["resourceType"]
+			is.Equal(ok, tt.want)
+			if tt.want {
+				is.Equal(resourceType, "Patient")
+			}
+		})
+	}
+}
+
+func TestProcessor_Process_Concurrency(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":   "hl7",
+		"outputType":  "fhir",
+		"concurrency": "4",
+	})
+	is.NoErr(err)
+
+	records := make([]opencdc.Record, 100)
+	for i := range records {
+		input := fmt.Sprintf("MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|%d|P|2.5|\nPID|1||%d||Smith^John||1990-01-01|male", i, i)
+		records[i] = opencdc.Record{Payload: opencdc.Change{After: opencdc.RawData(input)}}
+	}
+
+	result := p.Process(context.Background(), records)
+	is.Equal(len(result), len(records))
+	for i, r := range result {
+		rec, ok := r.(sdk.SingleRecord)
+		is.True(ok)
+		var patient FHIRPatient
+		err := json.Unmarshal(rec.Payload.After.Bytes(), &patient)
+		is.NoErr(err)
+		is.Equal(patient.ID, strconv.Itoa(i)) // output order matches input order
+	}
+}
+
+func TestConvertFHIRToHL7_Deceased(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	patient := FHIRPatient{
+		ID:               "123",
+		Name:             []HumanName{{Family: []string{"Smith"}, Given: []string{"John"}}},
+		BirthDate:        "1940-01-01",
+		DeceasedDateTime: "2020-03-01T10:30:00Z",
+	}
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[29], "20200301103000+0000")
+	is.Equal(pidFields[30], "Y")
+
+	msg, err := parseHL7Message(hl7Message, parseOptions{})
+	is.NoErr(err)
+	roundTrip, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(roundTrip.DeceasedDateTime, "2020-03-01T10:30:00Z")
+	is.Equal(roundTrip.DeceasedBoolean, nil) // deceased[x] holds a single value
+
+	// a death indicator without date/time maps to deceasedBoolean
+	deceased := true
+	patient.DeceasedDateTime = ""
+	patient.DeceasedBoolean = &deceased
+	hl7Message, err = p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	msg, err = parseHL7Message(hl7Message, parseOptions{})
+	is.NoErr(err)
+	is.Equal(msg.PID.DeathIndicator, "Y")
+	roundTrip, err = p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(*roundTrip.DeceasedBoolean, true)
+	is.Equal(roundTrip.DeceasedDateTime, "")
+}
+
+func TestRoundTrip_DeathDateTimePrecision(t *testing.T) {
+	p := NewProcessor().(*Processor)
+
+	tests := []struct {
+		pid29    string
+		deceased string
+		// original is the PID-29 value kept in the originalText extension
+		original bool
+	}{
+		{"20200301103045.1234-0500", "2020-03-01T10:30:45.1234-05:00", false},
+		{"20200301103045+0000", "2020-03-01T10:30:45Z", false},
+		{"202003011030-0500", "2020-03-01T10:30:00-05:00", true},
+		{"2020030110", "2020-03-01T10:00:00Z", true},
+		{"20200301", "2020-03-01", false},
+		{"20200301103045-0000", "2020-03-01T10:30:45Z", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pid29, func(t *testing.T) {
+			is := is.New(t)
+			hl7String := "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A03|123|P|2.5\r" +
+				"PID|1||123^^^^MR||Smith^John||19400101|M|||||||||||||||||||||" + tt.pid29 + "|Y"
+			msg, err := parseHL7Message(hl7String, parseOptions{})
+			is.NoErr(err)
+			patient, err := p.convertHL7ToFHIR(msg)
+			is.NoErr(err)
+			is.Equal(patient.DeceasedDateTime, tt.deceased)
+			is.Equal(patient.DeceasedDateTimeElement != nil, tt.original)
+
+			// through JSON and back to PID-29
+			data, err := json.Marshal(patient)
+			is.NoErr(err)
+			var decoded FHIRPatient
+			is.NoErr(json.Unmarshal(data, &decoded))
+			hl7Message, err := p.convertFHIRToHL7(decoded)
+			is.NoErr(err)
+			is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[29], tt.pid29)
+		})
+	}
+
+	// a changed death date/time no longer uses the original value
+	is := is.New(t)
+	patient := FHIRPatient{
+		ID:                      "123",
+		DeceasedDateTime:        "2021-05-05T08:00:00Z",
+		DeceasedDateTimeElement: &Element{Extension: []Extension{{URL: originalTextURL, ValueString: "202003011030"}}},
+	}
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[29], "20210505080000+0000")
+
+	// a time without offset takes the configured time zone and round-trips
+	err = p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+		"timezone":   "America/New_York",
+	})
+	is.NoErr(err)
+	msg, err := parseHL7Message("MSH|^~\\&|APP|FAC|||20230815120000||ADT^A03|123|P|2.5\r"+
+		"PID|1||123^^^^MR||Smith^John||19400101|M|||||||||||||||||||||2020030110|Y", parseOptions{})
+	is.NoErr(err)
+	patient, err = p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.DeceasedDateTime, "2020-03-01T10:00:00-05:00")
+	hl7Message, err = p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[29], "2020030110")
+}
+
+func TestConvertHL7ToFHIR_MaritalStatus(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male||||||||M"
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.MaritalStatus.Coding[0], Coding{System: maritalStatusSystem, Code: "M", Display: "Married"})
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[16], "M")
+
+	// legally separated uses a different code in HL7 v2
+	patient.MaritalStatus = &CodeableConcept{Coding: []Coding{{System: maritalStatusSystem, Code: "L"}}}
+	hl7Message, err = p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields = splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[16], "A")
+}
+
+func TestProcessor_Process_RaceAndEthnicity(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male||2106-3^White^CDCREC~2028-9^Asian^CDCREC|123 Main St^Springfield^IL^62701^USA|||||||||||2186-5^Not Hispanic or Latino^CDCREC"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	out := string(rec.Payload.After.Bytes())
+	is.True(strings.Contains(out, `"url":"http://hl7.org/fhir/us/core/StructureDefinition/us-core-race"`))
+	is.True(strings.Contains(out, `"url":"http://hl7.org/fhir/us/core/StructureDefinition/us-core-ethnicity"`))
+	is.True(strings.Contains(out, `"system":"urn:oid:2.16.840.1.113883.6.238"`))
+
+	// and back to PID-10/PID-22
+	var patient FHIRPatient
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &patient)
+	is.NoErr(err)
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[10], "2106-3^White^CDCREC~2028-9^Asian^CDCREC")
+	is.Equal(pidFields[22], "2186-5^Not Hispanic or Latino^CDCREC")
+}
+
+func TestProcessor_Process_MultipleEthnicGroups(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||19900101|M||||||||||||||2148-5^Mexican^CDCREC~2155-0^Central American^CDCREC"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var patient FHIRPatient
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &patient)
+	is.NoErr(err)
+	is.Equal(len(patient.Extension), 1)
+	ext := patient.Extension[0]
+	is.Equal(ext.URL, usCoreEthnicityURL)
+	is.Equal(ext.Extension, []Extension{
+		{URL: "detailed", ValueCoding: &Coding{System: cdcRaceEthnicitySystem, Code: "2148-5", Display: "Mexican"}},
+		{URL: "detailed", ValueCoding: &Coding{System: cdcRaceEthnicitySystem, Code: "2155-0", Display: "Central American"}},
+		{URL: "text", ValueString: "Mexican, Central American"},
+	})
+
+	// both groups are written back as PID-22 repetitions
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[22], "2148-5^Mexican^CDCREC~2155-0^Central American^CDCREC")
+}
+
+func TestParseHL7Message_AgeInBirthDate(t *testing.T) {
+	is := is.New(t)
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Smith^John||45|male"
+
+	// rejected by default
+	_, err := parseHL7Message(hl7String, parseOptions{})
+	is.True(err != nil)
+	var fieldErr *FieldError
+	is.True(errors.As(err, &fieldErr))
+	is.Equal(fieldErr.Field, "PID-7")
+
+	// or turned into the approximate birth year
+	clock := func() time.Time { return time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC) }
+	msg, err := parseHL7Message(hl7String, parseOptions{estimateAge: true, clock: clock})
+	is.NoErr(err)
+	is.Equal(msg.PID.BirthDate, "1978")
+	is.Equal(len(msg.Warnings), 1)
+	is.Equal(msg.Warnings[0].Field, "PID-7")
+}
+
+func TestConvertHL7ToFHIR_Language(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\nPID|1||123||Garcia^Maria||1990-01-01|female|||||||es^Spanish^ISO639"
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(len(patient.Communication), 1)
+	is.Equal(patient.Communication[0].Language.Coding[0], Coding{System: languageSystem, Code: "es", Display: "Spanish"})
+	is.True(patient.Communication[0].Preferred)
+
+	// the preferred language is emitted back into PID-15
+	patient.Communication = append([]Communication{{
+		Language: CodeableConcept{Coding: []Coding{{System: languageSystem, Code: "en", Display: "English"}}},
+	}}, patient.Communication...)
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[15], "es^Spanish")
+}
+
+func TestConvertFHIRToHL7_FieldCount(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	// a minimal patient still produces complete segments
+	hl7Message, err := p.convertFHIRToHL7(FHIRPatient{ID: "123"})
+	is.NoErr(err)
+	segments := splitHL7Message(hl7Message)
+	is.Equal(strings.Count(segments[0], "|"), mshFieldCount-1) // MSH-1 is the separator
+	is.Equal(strings.Count(segments[1], "|"), pidFieldCount)
+
+	// a value containing the field separator is rejected
+	_, err = p.convertFHIRToHL7(FHIRPatient{ID: "123", BirthDate: "1990|01|01"})
+	is.True(err != nil)
+	var fieldErr *FieldError
+	is.True(errors.As(err, &fieldErr))
+	is.Equal(fieldErr.Segment, "PID")
+}
+
+func TestConvertHL7ToFHIR_Active(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":     "hl7",
+		"outputType":    "fhir",
+		"includeActive": "true",
+	})
+	is.NoErr(err)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A23|123|P|2.5|\nPID|1||123||Smith^John||1990-01-01|male"
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(*patient.Active, false) // deleted record
+
+	msg.MSH.MessageType = "ADT^A08"
+	patient, err = p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(*patient.Active, true)
+
+	// not emitted unless configured
+	p.config.IncludeActive = false
+	patient, err = p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.Active, nil)
+}
+
+func TestConvertFHIRToHL7_SegmentTerminator(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "fhir",
+		"outputType": "hl7",
+	})
+	is.NoErr(err)
+
+	hl7Message, err := p.convertFHIRToHL7(FHIRPatient{ID: "123"})
+	is.NoErr(err)
+	is.True(strings.Contains(hl7Message, "|\rPID|")) // \r between MSH and PID by default
+	is.True(!strings.Contains(hl7Message, "\n"))
+
+	err = p.Configure(context.Background(), map[string]string{
+		"inputType":         "fhir",
+		"outputType":        "hl7",
+		"segmentTerminator": `\r\n`,
+	})
+	is.NoErr(err)
+	hl7Message, err = p.convertFHIRToHL7(FHIRPatient{ID: "123"})
+	is.NoErr(err)
+	is.True(strings.Contains(hl7Message, "|\r\nPID|"))
+
+	// the parser accepts any of the terminators
+	msg, err := parseHL7Message(hl7Message, parseOptions{})
+	is.NoErr(err)
+	is.Equal(msg.PID.ID, "123")
+}
+
+func TestConvertFHIRToHL7_DataAbsentGender(t *testing.T) {
+	input := `{"resourceType":"Patient","id":"123","_gender":{"extension":[{"url":"http://hl7.org/fhir/StructureDefinition/data-absent-reason","valueCode":"asked-declined"}]}}`
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"", "U"}, // default
+		{"unknown", "U"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		is := is.New(t)
+		cfg := map[string]string{
+			"inputType":  "fhir",
+			"outputType": "hl7",
+		}
+		if tt.mode != "" {
+			cfg["dataAbsentGender"] = tt.mode
+		}
+		p := NewProcessor().(*Processor)
+		is.NoErr(p.Configure(context.Background(), cfg))
+
+		var patient FHIRPatient
+		is.NoErr(json.Unmarshal([]byte(input), &patient))
+		is.Equal(patient.GenderElement.Extension[0].ValueCode, "asked-declined")
+		hl7Message, err := p.convertFHIRToHL7(patient)
+		is.NoErr(err)
+		is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[8], tt.want) // PID-8 in mode tt.mode
+	}
+
+	// a gender sent along the extension wins
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	patient := FHIRPatient{ID: "123", Gender: "female", GenderElement: &Element{Extension: []Extension{{URL: dataAbsentReasonURL, ValueCode: "unknown"}}}}
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[8], "F")
+}
+
+func TestGenderCodes(t *testing.T) {
+	tests := []struct {
+		code   string
+		gender string
+		// back is the code the gender converts back to, if it differs
+		back string
+	}{
+		{code: "M", gender: "male"},
+		{code: "F", gender: "female"},
+		{code: "O", gender: "other"},
+		{code: "U", gender: "unknown"},
+		{code: "A", gender: "other", back: "O"},
+		{code: "N", gender: "other", back: "O"},
+		{code: "X", gender: "unknown", back: "U"}, // not in table 0001
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			is := is.New(t)
+			p := NewProcessor().(*Processor)
+
+			hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\rPID|1||123||Smith^John||19900101|" + tt.code
+			msg, err := parseHL7Message(hl7String, parseOptions{})
+			is.NoErr(err)
+			patient, err := p.convertHL7ToFHIR(msg)
+			is.NoErr(err)
+			is.Equal(patient.Gender, tt.gender)
+
+			back := tt.back
+			if back == "" {
+				back = tt.code
+			}
+			hl7Message, err := p.convertFHIRToHL7(patient)
+			is.NoErr(err)
+			is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[8], back)
+		})
+	}
+}
+
+func TestFHIRToHL7Gender(t *testing.T) {
+	is := is.New(t)
+	is.Equal(fhirToHL7Gender("female"), "F")
+	is.Equal(fhirToHL7Gender("a"), "A") // table 0001 code kept
+	is.Equal(fhirToHL7Gender("x"), "U") // neither FHIR gender nor code
+	is.Equal(fhirToHL7Gender(""), "")
+}
+
+func TestConvert_MissingBirthDate(t *testing.T) {
+	for _, parseMode := range []string{"lenient", "strict"} {
+		t.Run(parseMode, func(t *testing.T) {
+			is := is.New(t)
+			p := NewProcessor().(*Processor)
+			err := p.Configure(context.Background(), map[string]string{
+				"inputType":  "fhir",
+				"outputType": "hl7",
+				"parseMode":  parseMode,
+			})
+			is.NoErr(err)
+
+			// FHIR -> HL7
+			hl7Message, errToHL7 := p.convertFHIRToHL7(FHIRPatient{ID: "123", Name: []HumanName{{Family: []string{"Smith"}}}})
+
+			// HL7 -> FHIR
+			hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\rPID|1||123||Smith^John|||M"
+			msg, errToFHIR := parseHL7Message(hl7String, p.parseOptions())
+			if errToFHIR == nil {
+				_, errToFHIR = p.convertHL7ToFHIR(msg)
+			}
+
+			// both directions agree
+			is.Equal(errToHL7 != nil, parseMode == "strict")
+			is.Equal(errToFHIR != nil, parseMode == "strict")
+			if parseMode == "lenient" {
+				is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[7], "") // empty PID-7
+			}
+		})
+	}
+}
+
+func TestProcess_NoAddress(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		inputType string
+		input     string
+	}{
+		{"hl7", "MSH|^~\\&|APP|FAC|||20230815120000||ADT^A01|123|P|2.5\rPID|1||123^^^H^MR||Doe^John||19800101|M"},
+		{"hl7v3", `<Patient xmlns="urn:hl7-org:v3"><id>123</id></Patient>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.inputType, func(t *testing.T) {
+			is := is.New(t)
+			p := NewProcessor()
+			is.NoErr(p.Configure(ctx, map[string]string{
+				"inputType":  tt.inputType,
+				"outputType": "fhir",
+			}))
+			result := p.Process(ctx, []opencdc.Record{{Payload: opencdc.Change{After: opencdc.RawData(tt.input)}}})
+			rec, ok := result[0].(sdk.SingleRecord)
+			is.True(ok)
+			var patient map[string]interface{}
+			is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &patient))
+			_, ok = patient["address"]
+			is.True(!ok) // no null address
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package hl7
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FHIRDiagnosticReport represents a FHIR DiagnosticReport resource. The
+// order, specimen and observations it refers to are contained resources.
+type FHIRDiagnosticReport struct {
+	ResourceType      string          `json:"resourceType"`
+	Contained         []interface{}   `json:"contained,omitempty"`
+	Identifier        []Identifier    `json:"identifier,omitempty"`
+	BasedOn           []Reference     `json:"basedOn,omitempty"`
+	Status            string          `json:"status"`
+	Code              CodeableConcept `json:"code"`
+	Subject           Reference       `json:"subject"`
+	EffectiveDateTime string          `json:"effectiveDateTime,omitempty"`
+	Specimen          []Reference     `json:"specimen,omitempty"`
+	Result            []Reference     `json:"result,omitempty"`
+}
+
+// FHIRServiceRequest represents a FHIR ServiceRequest resource.
+type FHIRServiceRequest struct {
+	ResourceType string          `json:"resourceType"`
+	ID           string          `json:"id,omitempty"`
+	Identifier   []Identifier    `json:"identifier,omitempty"`
+	Status       string          `json:"status"`
+	Intent       string          `json:"intent"`
+	Code         CodeableConcept `json:"code"`
+	Subject      Reference       `json:"subject"`
+	Requester    *Reference      `json:"requester,omitempty"`
+}
+
+// FHIRSpecimen represents a FHIR Specimen resource.
+type FHIRSpecimen struct {
+	ResourceType string          `json:"resourceType"`
+	ID           string          `json:"id,omitempty"`
+	Type         CodeableConcept `json:"type"`
+	Subject      Reference       `json:"subject"`
+}
+
+// FHIRObservation represents a FHIR Observation resource.
+type FHIRObservation struct {
+	ResourceType         string            `json:"resourceType"`
+	ID                   string            `json:"id,omitempty"`
+	Status               string            `json:"status"`
+	Code                 CodeableConcept   `json:"code"`
+	Subject              Reference         `json:"subject"`
+	EffectiveDateTime    string            `json:"effectiveDateTime,omitempty"`
+	ValueQuantity        *Quantity         `json:"valueQuantity,omitempty"`
+	ValueCodeableConcept *CodeableConcept  `json:"valueCodeableConcept,omitempty"`
+	ValueString          string            `json:"valueString,omitempty"`
+	Interpretation       []CodeableConcept `json:"interpretation,omitempty"`
+	ReferenceRange       []ReferenceRange  `json:"referenceRange,omitempty"`
+	// Component holds the parts of an observation made of several results,
+	// e.g. the systolic and diastolic blood pressure.
+	Component []ObservationComponent `json:"component,omitempty"`
+}
+
+// ObservationComponent represents a FHIR Observation.component.
+type ObservationComponent struct {
+	Code                 CodeableConcept   `json:"code"`
+	ValueQuantity        *Quantity         `json:"valueQuantity,omitempty"`
+	ValueCodeableConcept *CodeableConcept  `json:"valueCodeableConcept,omitempty"`
+	ValueString          string            `json:"valueString,omitempty"`
+	Interpretation       []CodeableConcept `json:"interpretation,omitempty"`
+	ReferenceRange       []ReferenceRange  `json:"referenceRange,omitempty"`
+}
+
+// Quantity represents a FHIR Quantity.
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+// ReferenceRange represents a FHIR Observation.referenceRange.
+type ReferenceRange struct {
+	Text string `json:"text"`
+}
+
+// ObservationRequest is an OBR segment with the OBX segments following it.
+type ObservationRequest struct {
+	PlacerOrderNumber string
+	FillerOrderNumber string
+	// UniversalServiceID is the ordered test or panel (OBR-4).
+	UniversalServiceID CodedElement
+	// ObservationDateTime is the raw observation date/time (OBR-7).
+	ObservationDateTime string
+	// Specimen is the specimen source (OBR-15).
+	Specimen CodedElement
+	// OrderingProvider is the name of the ordering provider (OBR-16).
+	OrderingProvider string
+	// ResultStatus is the result status (OBR-25, table 0123).
+	ResultStatus string
+	// OrderStatus is the order status (ORC-5, table 0038) of the common order
+	// segment preceding the OBR, if any.
+	OrderStatus  string
+	Observations []ObservationResult
+}
+
+// unitsOfMeasureSystem is the code system of UCUM units.
+const unitsOfMeasureSystem = "http://unitsofmeasure.org"
+
+// observationInterpretationSystem is the code system of HL7 v2 abnormal
+// flags (table 0078) in FHIR.
+const observationInterpretationSystem = "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"
+
+// reportStatuses maps HL7 v2 result statuses (OBR-25, table 0123) to FHIR
+// DiagnosticReport statuses. Other statuses are preliminary.
+var reportStatuses = map[string]string{
+	"F": "final",
+	"C": "corrected",
+	"X": "cancelled",
+	"O": "registered",
+	"I": "registered",
+	"S": "registered",
+	"A": "partial",
+}
+
+// orderStatuses maps HL7 v2 order statuses (ORC-5, table 0038) to FHIR
+// ServiceRequest statuses. Other statuses are unknown; orders without a
+// status are completed, as results have been reported for them.
+var orderStatuses = map[string]string{
+	"A":  "active",
+	"CA": "revoked",
+	"CM": "completed",
+	"DC": "revoked",
+	"ER": "entered-in-error",
+	"HD": "on-hold",
+	"IP": "active",
+	"RP": "revoked",
+	"SC": "active",
+}
+
+// observationStatuses maps HL7 v2 observation result statuses (OBX-11, table
+// 0085) to FHIR Observation statuses. Other statuses are preliminary.
+var observationStatuses = map[string]string{
+	"F": "final",
+	"C": "corrected",
+	"D": "entered-in-error",
+	"W": "entered-in-error",
+	"X": "cancelled",
+	"I": "registered",
+}
+
+// obxStatuses maps FHIR Observation statuses to HL7 v2 observation result
+// statuses (OBX-11, table 0085). Other statuses become P (preliminary).
+var obxStatuses = map[string]string{
+	"final":            "F",
+	"amended":          "C",
+	"corrected":        "C",
+	"entered-in-error": "W",
+	"cancelled":        "X",
+	"registered":       "I",
+}
+
+// obxFieldCount is the number of fields of generated OBX segments, up to the
+// observation date/time (OBX-14).
+const obxFieldCount = 14
+
+// parseObservationRequest parses the fields of an OBR segment.
+func parseObservationRequest(fields []string) ObservationRequest {
+	obr := func(n int) string { return fieldPath{Segment: "OBR", Field: n}.field(fields) }
+	provider, _, _ := nextToken(obr(16), '~')
+	return ObservationRequest{
+		PlacerOrderNumber:   unescapeHL7(component(obr(2), '^', 1)),
+		FillerOrderNumber:   unescapeHL7(component(obr(3), '^', 1)),
+		UniversalServiceID:  parseCodedElement(obr(4)),
+		ObservationDateTime: obr(7),
+		Specimen:            parseCodedElement(obr(15)),
+		OrderingProvider:    personName(provider),
+		ResultStatus:        obr(25),
+	}
+}
+
+// isLabResult reports whether msg is an unsolicited observation result
+// (ORU^R01) carrying orders to convert to DiagnosticReport resources.
+func isLabResult(msg HL7Message) bool {
+	return component(msg.MSH.MessageType, '^', 1) == "ORU" && len(msg.OBR) > 0
+}
+
+// convertObservationRequest converts an OBR segment and its OBX segments to a
+// DiagnosticReport of the patient with the given ID. Times without UTC offset
+// are in location.
+func convertObservationRequest(patientID string, obr ObservationRequest, location *time.Location) (FHIRDiagnosticReport, error) {
+	subject := Reference{Reference: "Patient/" + patientID}
+	status, ok := reportStatuses[obr.ResultStatus]
+	if !ok {
+		status = "preliminary"
+	}
+	report := FHIRDiagnosticReport{
+		ResourceType: "DiagnosticReport",
+		Status:       status,
+		Code:         codeableConcept(obr.UniversalServiceID),
+		Subject:      subject,
+	}
+	if obr.FillerOrderNumber != "" {
+		report.Identifier = []Identifier{{Value: obr.FillerOrderNumber}}
+	}
+	if obr.ObservationDateTime != "" {
+		effective, err := hl7ToFHIRTimestamp(obr.ObservationDateTime, location)
+		if err != nil {
+			return FHIRDiagnosticReport{}, fmt.Errorf("invalid observation date/time: %w", err)
+		}
+		report.EffectiveDateTime = effective
+	}
+
+	order := FHIRServiceRequest{
+		ResourceType: "ServiceRequest",
+		ID:           "order",
+		Status:       serviceRequestStatus(obr.OrderStatus),
+		Intent:       "order",
+		Code:         report.Code,
+		Subject:      subject,
+	}
+	if obr.PlacerOrderNumber != "" {
+		order.Identifier = []Identifier{{Value: obr.PlacerOrderNumber}}
+	}
+	if obr.OrderingProvider != "" {
+		order.Requester = &Reference{Display: obr.OrderingProvider}
+	}
+	report.Contained = append(report.Contained, order)
+	report.BasedOn = []Reference{{Reference: "#order"}}
+
+	if obr.Specimen.Code != "" || obr.Specimen.Text != "" {
+		report.Contained = append(report.Contained, FHIRSpecimen{
+			ResourceType: "Specimen",
+			ID:           "specimen",
+			Type:         codeableConcept(obr.Specimen),
+			Subject:      subject,
+		})
+		report.Specimen = []Reference{{Reference: "#specimen"}}
+	}
+
+	for i, obx := range obr.Observations {
+		observation, err := convertObservationResult(subject, obx, location)
+		if err != nil {
+			return FHIRDiagnosticReport{}, fmt.Errorf("OBX %d: %w", i+1, err)
+		}
+		observation.ID = "obs" + strconv.Itoa(i+1)
+		report.Contained = append(report.Contained, observation)
+		report.Result = append(report.Result, Reference{Reference: "#" + observation.ID})
+	}
+	return report, nil
+}
+
+// serviceRequestStatus converts an HL7 v2 order status (ORC-5).
+func serviceRequestStatus(orderStatus string) string {
+	if orderStatus == "" {
+		return "completed"
+	}
+	if status, ok := orderStatuses[orderStatus]; ok {
+		return status
+	}
+	return "unknown"
+}
+
+// convertObservationResult converts an OBX segment to an Observation. The
+// value type (OBX-2) selects the value: NM becomes a quantity, CE and CWE a
+// codeable concept and other types a string.
+func convertObservationResult(subject Reference, obx ObservationResult, location *time.Location) (FHIRObservation, error) {
+	status, ok := observationStatuses[obx.Status]
+	if !ok {
+		status = "preliminary"
+	}
+	observation := FHIRObservation{
+		ResourceType: "Observation",
+		Status:       status,
+		Code:         codeableConcept(obx.Identifier),
+		Subject:      subject,
+	}
+	if obx.DateTime != "" {
+		effective, err := hl7ToFHIRTimestamp(obx.DateTime, location)
+		if err != nil {
+			return FHIRObservation{}, fmt.Errorf("invalid observation date/time: %w", err)
+		}
+		observation.EffectiveDateTime = effective
+	}
+
+	var value string
+	if len(obx.Values) > 0 {
+		value = obx.Values[0]
+	}
+	switch obx.ValueType {
+	case "NM":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			observation.ValueQuantity = &Quantity{Value: v, Unit: obx.Units.Text}
+			if obx.Units.Code != "" {
+				observation.ValueQuantity.Unit = obx.Units.Code
+				// coded units are only computable in a known system, e.g. UCUM
+				if system := fhirCodeSystem(obx.Units.System); system != "" {
+					observation.ValueQuantity.System = system
+					observation.ValueQuantity.Code = obx.Units.Code
+				}
+			}
+			break
+		}
+		observation.ValueString = unescapeHL7(value)
+	case "CE", "CWE":
+		concept := codeableConcept(parseCodedElement(value))
+		observation.ValueCodeableConcept = &concept
+	default:
+		observation.ValueString = unescapeHL7(value)
+	}
+
+	if obx.ReferenceRange != "" {
+		observation.ReferenceRange = []ReferenceRange{{Text: obx.ReferenceRange}}
+	}
+	if obx.AbnormalFlag != "" {
+		observation.Interpretation = []CodeableConcept{{
+			Coding: []Coding{{System: observationInterpretationSystem, Code: obx.AbnormalFlag}},
+		}}
+	}
+	return observation, nil
+}
+
+// codeableConcept converts a coded element to a CodeableConcept, with the
+// coding system as FHIR code system URI.
+func codeableConcept(ce CodedElement) CodeableConcept {
+	concept := CodeableConcept{Text: ce.Text}
+	if ce.Code != "" {
+		concept.Coding = []Coding{{System: fhirCodeSystem(ce.System), Code: ce.Code, Display: ce.Text}}
+	}
+	return concept
+}
+
+// formatObservations formats observations as OBX segments, numbered in
+// order. An observation with components yields one OBX per component; the
+// OBX segments of one observation share the observation's number as sub-ID
+// (OBX-4).
+func (p *Processor) formatObservations(observations []FHIRObservation) ([]string, error) {
+	var segments []string
+	for i, observation := range observations {
+		results := observation.Component
+		subID := strconv.Itoa(i + 1)
+		if len(results) == 0 {
+			results = []ObservationComponent{{
+				Code:                 observation.Code,
+				ValueQuantity:        observation.ValueQuantity,
+				ValueCodeableConcept: observation.ValueCodeableConcept,
+				ValueString:          observation.ValueString,
+				Interpretation:       observation.Interpretation,
+				ReferenceRange:       observation.ReferenceRange,
+			}}
+			subID = ""
+		}
+		for _, result := range results {
+			segment := p.applyDefaults(formatOBX(len(segments)+1, subID, observation, result))
+			if err := validateFieldCount(segment); err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+		}
+	}
+	return segments, nil
+}
+
+// formatOBX formats a result of an observation as an OBX segment. The value
+// selects the value type (OBX-2): NM for quantities, CE for codeable concepts
+// and ST otherwise.
+func formatOBX(setID int, subID string, observation FHIRObservation, result ObservationComponent) string {
+	obx := newSegment("OBX", obxFieldCount)
+	obx[1] = strconv.Itoa(setID)
+	obx[3] = formatCodedElement(codedElement(result.Code))
+	obx[4] = subID
+	switch {
+	case result.ValueQuantity != nil:
+		q := result.ValueQuantity
+		obx[2] = "NM"
+		obx[5] = strconv.FormatFloat(q.Value, 'f', -1, 64)
+		units := CodedElement{Code: q.Code, Text: q.Unit, System: hl7CodingSystem(q.System)}
+		obx[6] = formatCodedElement(units)
+	case result.ValueCodeableConcept != nil:
+		obx[2] = "CE"
+		obx[5] = formatCodedElement(codedElement(*result.ValueCodeableConcept))
+	default:
+		obx[2] = "ST"
+		obx[5] = escapeHL7(result.ValueString)
+	}
+	if len(result.ReferenceRange) > 0 {
+		obx[7] = escapeHL7(result.ReferenceRange[0].Text)
+	}
+	if len(result.Interpretation) > 0 && len(result.Interpretation[0].Coding) > 0 {
+		obx[8] = escapeHL7(result.Interpretation[0].Coding[0].Code)
+	}
+	obx[11] = obxStatuses[observation.Status]
+	if obx[11] == "" {
+		obx[11] = "P"
+	}
+	obx[14] = fhirToHL7Timestamp(observation.EffectiveDateTime)
+	return strings.Join(obx, "|")
+}
+
+// codedElement converts a CodeableConcept to a coded element, from its first
+// coding, with the code system as HL7 v2 coding system name.
+func codedElement(concept CodeableConcept) CodedElement {
+	ce := CodedElement{Text: concept.Text}
+	if len(concept.Coding) > 0 {
+		coding := concept.Coding[0]
+		ce.Code = coding.Code
+		ce.System = hl7CodingSystem(coding.System)
+		if ce.Text == "" {
+			ce.Text = coding.Display
+		}
+	}
+	return ce
+}
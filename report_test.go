@@ -0,0 +1,151 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_LabResult(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":  "hl7",
+		"outputType": "fhir",
+		"parseMode":  "strict",
+	})
+	is.NoErr(err)
+
+	input := "MSH|^~\\&|LAB|FACILITY|HL7_PARSER|FACILITY|20230815120000||ORU^R01|123|P|2.5|\r" +
+		"PID|1||123||Smith^John||19900101|M\r" +
+		"OBR|1|ORD-1|LAB-9|24331-1^Lipid panel^LN|||20230815083000||||||||BLD^Blood|1234^Quinn^Michaela|||||||||F\r" +
+		"OBX|1|NM|2093-3^Cholesterol^LN||245|mg/dL^mg/dL^UCUM|<200|H|||F|||20230815090000\r" +
+		"OBX|2|CE|11502-2^Lab report^LN||260385009^Negative^SCT||||||F\r" +
+		"OBX|3|ST|8251-1^Comment^LN||Fasting \\T\\ hydrated||||||P"
+	result := p.Process(context.Background(), []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(input)},
+	}})
+	rec, ok := result[0].(sdk.SingleRecord)
+	is.True(ok)
+
+	var bundle struct {
+		Entry []struct {
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	err = json.Unmarshal(rec.Payload.After.Bytes(), &bundle)
+	is.NoErr(err)
+	is.Equal(len(bundle.Entry), 2)
+
+	var report struct {
+		FHIRDiagnosticReport
+		Contained []json.RawMessage `json:"contained"`
+	}
+	is.NoErr(json.Unmarshal(bundle.Entry[1].Resource, &report))
+	is.Equal(report.ResourceType, "DiagnosticReport")
+	is.Equal(report.Status, "final")
+	is.Equal(report.Identifier[0].Value, "LAB-9")
+	is.Equal(report.Code.Coding[0].Code, "24331-1")
+	is.Equal(report.Subject.Reference, "Patient/123")
+	is.Equal(report.EffectiveDateTime, "2023-08-15T08:30:00Z")
+	is.Equal(report.BasedOn[0].Reference, "#order")
+	is.Equal(report.Specimen[0].Reference, "#specimen")
+	is.Equal(report.Result, []Reference{{Reference: "#obs1"}, {Reference: "#obs2"}, {Reference: "#obs3"}})
+	is.Equal(len(report.Contained), 5)
+
+	var order FHIRServiceRequest
+	is.NoErr(json.Unmarshal(report.Contained[0], &order))
+	is.Equal(order.Identifier[0].Value, "ORD-1")
+	is.Equal(order.Requester.Display, "Michaela Quinn")
+
+	var specimen FHIRSpecimen
+	is.NoErr(json.Unmarshal(report.Contained[1], &specimen))
+	is.Equal(specimen.Type.Coding[0].Code, "BLD")
+
+	var cholesterol, labReport, comment FHIRObservation
+	is.NoErr(json.Unmarshal(report.Contained[2], &cholesterol))
+	is.NoErr(json.Unmarshal(report.Contained[3], &labReport))
+	is.NoErr(json.Unmarshal(report.Contained[4], &comment))
+
+	is.Equal(cholesterol.ID, "obs1")
+	is.Equal(cholesterol.Status, "final")
+	is.Equal(*cholesterol.ValueQuantity, Quantity{Value: 245, Unit: "mg/dL", System: "http://unitsofmeasure.org", Code: "mg/dL"})
+	is.Equal(cholesterol.ReferenceRange[0].Text, "<200")
+	is.Equal(cholesterol.Interpretation[0].Coding[0].Code, "H")
+	is.Equal(cholesterol.EffectiveDateTime, "2023-08-15T09:00:00Z")
+
+	is.Equal(cholesterol.Code.Coding[0].System, "http://loinc.org")
+	is.Equal(labReport.ValueCodeableConcept.Coding[0].Code, "260385009")
+	is.Equal(labReport.ValueCodeableConcept.Coding[0].System, "http://snomed.info/sct")
+	is.Equal(labReport.ValueCodeableConcept.Text, "Negative")
+
+	is.Equal(comment.Status, "preliminary")
+	is.Equal(comment.ValueString, "Fasting & hydrated")
+}
+
+func TestConvertObservationRequest_OrderStatus(t *testing.T) {
+	testCases := []struct {
+		orc  string
+		want string
+	}{
+		{orc: "ORC|RE|ORD-1|||SC", want: "active"},
+		{orc: "ORC|RE|ORD-1|||IP", want: "active"},
+		{orc: "ORC|RE|ORD-1|||CA", want: "revoked"},
+		{orc: "ORC|RE|ORD-1|||CM", want: "completed"},
+		{orc: "ORC|RE|ORD-1|||HD", want: "on-hold"},
+		{orc: "ORC|RE|ORD-1|||ER", want: "entered-in-error"},
+		{orc: "ORC|RE|ORD-1|||ZZ", want: "unknown"},
+		{orc: "ORC|RE|ORD-1", want: "completed"}, // no order status
+		{want: "completed"},                      // no ORC
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.orc, func(t *testing.T) {
+			is := is.New(t)
+			input := "MSH|^~\\&|LAB|FACILITY|HL7_PARSER|FACILITY|20230815120000||ORU^R01|123|P|2.5|\r" +
+				"PID|1||123||Smith^John||19900101|M\r"
+			if tc.orc != "" {
+				input += tc.orc + "\r"
+			}
+			input += "OBR|1|ORD-1|LAB-9|24331-1^Lipid panel^LN\r" +
+				"OBR|2|ORD-2|LAB-10|2093-3^Cholesterol^LN"
+			msg, err := parseHL7Message(input, parseOptions{strict: true})
+			is.NoErr(err)
+			is.Equal(len(msg.OBR), 2)
+
+			report, err := convertObservationRequest(msg.PID.ID, msg.OBR[0], time.UTC)
+			is.NoErr(err)
+			is.Equal(report.Contained[0].(FHIRServiceRequest).Status, tc.want)
+
+			// the ORC only applies to the OBR following it
+			report, err = convertObservationRequest(msg.PID.ID, msg.OBR[1], time.UTC)
+			is.NoErr(err)
+			is.Equal(report.Contained[0].(FHIRServiceRequest).Status, "completed")
+		})
+	}
+}
+
+func TestConvertObservationResult_Units(t *testing.T) {
+	tests := []struct {
+		units string
+		want  Quantity
+	}{
+		{"mg/dL^milligrams per deciliter^UCUM", Quantity{Value: 5, Unit: "mg/dL", System: "http://unitsofmeasure.org", Code: "mg/dL"}},
+		{"MGDL^milligrams per deciliter^99LAB", Quantity{Value: 5, Unit: "MGDL"}}, // local units are not UCUM
+		{"^mg/dL", Quantity{Value: 5, Unit: "mg/dL"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.units, func(t *testing.T) {
+			is := is.New(t)
+			obx := parseObservationResult(splitHL7Field("OBX|1|NM|2093-3^Cholesterol^LN||5|" + tt.units))
+			observation, err := convertObservationResult(Reference{Reference: "Patient/123"}, obx, time.UTC)
+			is.NoErr(err)
+			is.Equal(*observation.ValueQuantity, tt.want)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package hl7
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+// roundTrip converts patient to an HL7 v2 message and back, the way a
+// fhir->hl7 and an hl7->fhir processor with the same configuration would.
+func roundTrip(p *Processor, patient FHIRPatient) (FHIRPatient, error) {
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	if err != nil {
+		return FHIRPatient{}, err
+	}
+	msg, err := parseHL7Message(hl7Message, p.parseOptions())
+	if err != nil {
+		return FHIRPatient{}, err
+	}
+	return p.convertHL7ToFHIR(msg)
+}
+
+func TestRoundTrip_FHIRToHL7ToFHIR(t *testing.T) {
+	base := func() FHIRPatient {
+		return FHIRPatient{
+			ID:         "123",
+			Identifier: []Identifier{{Value: "123", Type: &CodeableConcept{Coding: []Coding{{System: identifierTypeSystem, Code: "MR"}}}}},
+			Name:       []HumanName{{Family: []string{"Smith"}, Given: []string{"John"}}},
+			BirthDate:  "1990-01-01",
+			Gender:     "male",
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(*FHIRPatient)
+	}{{
+		name:   "id",
+		modify: func(p *FHIRPatient) { p.ID = "MRN-42"; p.Identifier[0].Value = "MRN-42" },
+	}, {
+		name:   "id with delimiters",
+		modify: func(p *FHIRPatient) { p.ID = "A|B^C"; p.Identifier[0].Value = "A|B^C" },
+	}, {
+		name:   "name",
+		modify: func(p *FHIRPatient) { p.Name = []HumanName{{Family: []string{"O'Brien"}, Given: []string{"Mary"}}} },
+	}, {
+		name:   "birthDate",
+		modify: func(p *FHIRPatient) { p.BirthDate = "2001-12-31" },
+	}, {
+		name:   "birthDate year only",
+		modify: func(p *FHIRPatient) { p.BirthDate = "1975" },
+	}, {
+		name:   "gender female",
+		modify: func(p *FHIRPatient) { p.Gender = "female" },
+	}, {
+		name:   "gender other",
+		modify: func(p *FHIRPatient) { p.Gender = "other" },
+	}, {
+		name:   "gender unknown",
+		modify: func(p *FHIRPatient) { p.Gender = "unknown" },
+	}, {
+		name: "address",
+		modify: func(p *FHIRPatient) {
+			p.Address = []Address{{Line: []string{"123 Main St"}, City: "Springfield", State: "IL", PostalCode: "62701", Country: "USA"}}
+		},
+	}, {
+		name: "addresses with use",
+		modify: func(p *FHIRPatient) {
+			p.Address = []Address{
+				{Use: "home", Line: []string{"1 Home Rd"}, City: "Springfield", State: "IL", PostalCode: "62701", Country: "USA"},
+				{Use: "work", Line: []string{"2 Office Pl"}, City: "Chicago", State: "IL", PostalCode: "60601", Country: "USA"},
+			}
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			p := NewProcessor().(*Processor)
+			err := p.Configure(context.Background(), map[string]string{
+				"inputType":  "fhir",
+				"outputType": "hl7",
+			})
+			is.NoErr(err)
+
+			want := base()
+			tc.modify(&want)
+			got, err := roundTrip(p, want)
+			is.NoErr(err)
+			is.Equal(got, want)
+		})
+	}
+}
+
+func TestRoundTrip_HL7BirthDate(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	hl7String := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\rPID|1||123||Smith^John||19800101|M"
+	msg, err := parseHL7Message(hl7String, parseOptions{})
+	is.NoErr(err)
+	patient, err := p.convertHL7ToFHIR(msg)
+	is.NoErr(err)
+	is.Equal(patient.BirthDate, "1980-01-01") // FHIR dates are dashed
+	is.Equal(len(patient.Address), 0)         // no empty address from an empty PID-11
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err)
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[7], "19800101")
+
+	// invalid birth dates are rejected
+	msg.PID.BirthDate = "1980/01/01"
+	_, err = p.convertHL7ToFHIR(msg)
+	is.True(err != nil)
+}
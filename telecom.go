@@ -0,0 +1,127 @@
+package hl7
+
+import "strings"
+
+// telecomSystems maps the URL schemes of HL7v3 telecom values to FHIR
+// contact point systems.
+var telecomSystems = map[string]string{
+	"tel":    "phone",
+	"fax":    "fax",
+	"mailto": "email",
+	"http":   "url",
+	"https":  "url",
+}
+
+// telecomSchemes maps FHIR contact point systems to the URL schemes of HL7v3
+// telecom values.
+var telecomSchemes = map[string]string{
+	"phone": "tel",
+	"fax":   "fax",
+	"email": "mailto",
+}
+
+// telecomUses maps HL7v3 telecom use codes to FHIR contact point uses.
+var telecomUses = map[string]string{
+	"H":   "home",
+	"HP":  "home",
+	"HV":  "home",
+	"WP":  "work",
+	"MC":  "mobile",
+	"TMP": "temp",
+	"OLD": "old",
+	"BAD": "old",
+}
+
+// fhirTelecomUses maps FHIR contact point uses to HL7v3 telecom use codes.
+var fhirTelecomUses = map[string]string{
+	"home":   "HP",
+	"work":   "WP",
+	"mobile": "MC",
+	"temp":   "TMP",
+	"old":    "OLD",
+}
+
+// convertTelecom converts an HL7v3 telecom, a URL such as tel:+1-555-0100,
+// to a contact point. The scheme selects the system and is removed from the
+// value, except for web addresses.
+func convertTelecom(telecom HL7V3Telecom) ContactPoint {
+	point := ContactPoint{Value: telecom.Value, Use: telecomUses[telecom.Use]}
+	if scheme, rest, ok := strings.Cut(telecom.Value, ":"); ok {
+		if system, known := telecomSystems[strings.ToLower(scheme)]; known {
+			point.System = system
+			if system != "url" {
+				point.Value = rest
+			}
+		}
+	}
+	return point
+}
+
+// formatTelecom formats a contact point as an HL7v3 telecom, prefixing the
+// value with the URL scheme of its system. Values of other systems are kept
+// as they are.
+func formatTelecom(point ContactPoint) HL7V3Telecom {
+	telecom := HL7V3Telecom{Value: point.Value, Use: fhirTelecomUses[point.Use]}
+	if scheme, ok := telecomSchemes[point.System]; ok && !strings.HasPrefix(point.Value, scheme+":") {
+		telecom.Value = scheme + ":" + point.Value
+	}
+	return telecom
+}
+
+// xtnUseCodes maps FHIR contact point uses to HL7 v2 telecommunication use
+// codes (XTN-2, table 0201).
+var xtnUseCodes = map[string]string{
+	"home":   "PRN",
+	"work":   "WPN",
+	"mobile": "PRN",
+	"temp":   "ORN",
+}
+
+// xtnEquipmentTypes maps FHIR contact point systems to HL7 v2
+// telecommunication equipment types (XTN-3, table 0202).
+var xtnEquipmentTypes = map[string]string{
+	"phone": "PH",
+	"fax":   "FX",
+	"email": "Internet",
+	"pager": "BP",
+}
+
+// formatXTN formats a contact point as an HL7 v2 extended telecommunication
+// number. Email addresses go into XTN-4, other values into XTN-1. With
+// withPeriod, the validity period becomes the effective start (XTN-13) and
+// expiration (XTN-14) dates.
+func formatXTN(point ContactPoint, withPeriod bool) string {
+	xtn := make([]string, 14)
+	xtn[1] = xtnUseCodes[point.Use]
+	xtn[2] = xtnEquipmentTypes[point.System]
+	if point.Use == "mobile" && (point.System == "" || point.System == "phone") {
+		xtn[2] = "CP"
+	}
+	if point.System == "email" {
+		xtn[1] = "NET"
+		xtn[3] = escapeHL7(point.Value)
+	} else {
+		xtn[0] = escapeHL7(point.Value)
+	}
+	if withPeriod && point.Period != nil {
+		xtn[12] = fhirToHL7Timestamp(point.Period.Start)
+		xtn[13] = fhirToHL7Timestamp(point.Period.End)
+	}
+	return strings.TrimRight(strings.Join(xtn, "^"), "^")
+}
+
+// formatPhoneNumbers formats the contact points as the repetitions of PID-13
+// (home) and PID-14 (business): work contact points are business numbers,
+// all others home numbers.
+func (p *Processor) formatPhoneNumbers(telecom []ContactPoint) (home, business string) {
+	var homes, businesses []string
+	for _, point := range telecom {
+		xtn := formatXTN(point, p.config.TelecomPeriod)
+		if point.Use == "work" {
+			businesses = append(businesses, xtn)
+		} else {
+			homes = append(homes, xtn)
+		}
+	}
+	return strings.Join(homes, "~"), strings.Join(businesses, "~")
+}
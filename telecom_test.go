@@ -0,0 +1,80 @@
+package hl7
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestConvertHL7V3_Telecom(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	input := `<Patient xmlns="urn:hl7-org:v3">
+  <id>pat-1</id>
+  <name><given>Jane</given><family>Doe</family></name>
+  <telecom value="tel:+1-555-1234" use="MC"/>
+  <telecom value="mailto:jane@example.com" use="WP"/>
+  <telecom value="https://example.com/jane"/>
+</Patient>`
+	var v3Patient HL7V3Patient
+	is.NoErr(xml.Unmarshal([]byte(input), &v3Patient))
+	patient, err := p.convertHL7V3ToFHIR(v3Patient)
+	is.NoErr(err)
+	is.Equal(patient.Telecom, []ContactPoint{
+		{System: "phone", Value: "+1-555-1234", Use: "mobile"},
+		{System: "email", Value: "jane@example.com", Use: "work"},
+		{System: "url", Value: "https://example.com/jane"},
+	})
+
+	output, err := p.convertFHIRToHL7V3(patient)
+	is.NoErr(err)
+	is.True(strings.Contains(string(output), `<telecom value="tel:+1-555-1234" use="MC"></telecom>`))
+	is.True(strings.Contains(string(output), `<telecom value="mailto:jane@example.com" use="WP"></telecom>`))
+	is.True(strings.Contains(string(output), `<telecom value="https://example.com/jane"></telecom>`))
+
+	var roundTripped HL7V3Patient
+	is.NoErr(xml.Unmarshal(output, &roundTripped))
+	is.Equal(roundTripped.Telecom, v3Patient.Telecom)
+}
+
+func TestConvertFHIRToHL7_TelecomPeriod(t *testing.T) {
+	is := is.New(t)
+
+	patient := FHIRPatient{
+		ID:        "123",
+		Name:      []HumanName{{Family: []string{"Smith"}, Given: []string{"John"}}},
+		BirthDate: "1990-01-01",
+		Gender:    "male",
+		Telecom: []ContactPoint{
+			{System: "phone", Value: "555-0100", Use: "home", Period: &Period{Start: "2020-01-01", End: "2024-12-31"}},
+			{System: "phone", Value: "555-0199", Use: "mobile"},
+			{System: "email", Value: "john@example.com", Use: "work", Period: &Period{Start: "2021-06-01"}},
+		},
+	}
+
+	for _, tt := range []struct {
+		telecomPeriod string
+		home          string
+		business      string
+	}{
+		{"false", "555-0100^PRN^PH~555-0199^PRN^CP", "^NET^Internet^john@example.com"},
+		{"true", "555-0100^PRN^PH^^^^^^^^^^20200101^20241231~555-0199^PRN^CP", "^NET^Internet^john@example.com^^^^^^^^^20210601"},
+	} {
+		p := NewProcessor().(*Processor)
+		is.NoErr(p.Configure(context.Background(), map[string]string{
+			"inputType":     "fhir",
+			"outputType":    "hl7",
+			"telecomPeriod": tt.telecomPeriod,
+		}))
+
+		hl7Message, err := p.convertFHIRToHL7(patient)
+		is.NoErr(err)
+		pidFields := strings.Split(splitHL7Message(hl7Message)[1], "|")
+		is.Equal(pidFields[13], tt.home)     // home phone numbers
+		is.Equal(pidFields[14], tt.business) // business phone numbers
+	}
+}
@@ -0,0 +1,194 @@
+package hl7
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// embed the time zone database, the processor may run without one
+	_ "time/tzdata"
+)
+
+// hl7TimestampLayout is the layout of the timestamps of generated HL7 v2
+// messages: second precision with the numeric UTC offset.
+const hl7TimestampLayout = "20060102150405-0700"
+
+// timeLocation returns the configured time zone, UTC when not configured.
+func (p *Processor) timeLocation() *time.Location {
+	if p.location == nil {
+		return time.UTC
+	}
+	return p.location
+}
+
+// now returns the current time of the processor clock.
+func (p *Processor) now() time.Time {
+	if p.clock == nil {
+		return time.Now()
+	}
+	return p.clock()
+}
+
+// messageTimestamp returns the current time as an HL7 timestamp in the
+// configured time zone, UTC when not configured.
+func (p *Processor) messageTimestamp() string {
+	return p.now().In(p.timeLocation()).Format(hl7TimestampLayout)
+}
+
+// fhirTimeLayouts lists the precisions allowed in FHIR date and dateTime
+// values, from most to least precise.
+var fhirTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseFHIRTime parses a FHIR date or dateTime value.
+func parseFHIRTime(v string) (time.Time, error) {
+	for _, layout := range fhirTimeLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid FHIR date/time %q", v)
+}
+
+// fhirToHL7Timestamp converts a FHIR date or dateTime value to an HL7 TS
+// value (YYYY[MM[DD[HHMM[SS[.S]]]]][+/-ZZZZ]), keeping its precision.
+func fhirToHL7Timestamp(v string) string {
+	date, clock, hasTime := strings.Cut(v, "T")
+	ts := strings.ReplaceAll(date, "-", "")
+	if !hasTime {
+		return ts
+	}
+
+	var zone string
+	switch i := strings.LastIndexAny(clock, "+-"); {
+	case strings.HasSuffix(clock, "Z"):
+		clock = strings.TrimSuffix(clock, "Z")
+		zone = "+0000"
+	case i >= 0:
+		zone = strings.ReplaceAll(clock[i:], ":", "")
+		clock = clock[:i]
+	}
+	return ts + strings.ReplaceAll(clock, ":", "") + zone
+}
+
+// hl7ToFHIRTimestamp converts an HL7 TS value
+// (YYYY[MM[DD[HH[MM[SS[.S]]]]]][+/-ZZZZ]) to a FHIR date or dateTime value,
+// keeping its precision. A time without seconds is completed with zero
+// seconds and a time without UTC offset is taken to be in location, as FHIR
+// requires both.
+func hl7ToFHIRTimestamp(v string, location *time.Location) (string, error) {
+	digits, zone := v, ""
+	if i := strings.IndexAny(v, "+-"); i >= 0 {
+		digits, zone = v[:i], v[i:]
+	}
+	clock, fraction, _ := strings.Cut(digits, ".")
+	if len(zone) != 0 && len(zone) != 5 {
+		return "", fmt.Errorf("invalid HL7 timestamp %q", v)
+	}
+	for _, c := range clock + fraction + strings.TrimLeft(zone, "+-") {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("invalid HL7 timestamp %q", v)
+		}
+	}
+
+	switch len(clock) {
+	case 4:
+		return clock, nil
+	case 6:
+		return clock[:4] + "-" + clock[4:6], nil
+	case 8:
+		return clock[:4] + "-" + clock[4:6] + "-" + clock[6:8], nil
+	case 10, 12, 14:
+	default:
+		return "", fmt.Errorf("invalid HL7 timestamp %q", v)
+	}
+
+	clock += "0000"[:14-len(clock)]
+	if zone == "" {
+		t, err := time.ParseInLocation("20060102150405", clock, location)
+		if err != nil {
+			return "", fmt.Errorf("invalid HL7 timestamp %q", v)
+		}
+		zone = t.Format("-0700")
+	}
+	ts := clock[:4] + "-" + clock[4:6] + "-" + clock[6:8] + "T" +
+		clock[8:10] + ":" + clock[10:12] + ":" + clock[12:14]
+	if fraction != "" {
+		ts += "." + fraction
+	}
+	switch zone {
+	case "+0000", "-0000":
+		ts += "Z"
+	default:
+		ts += zone[:3] + ":" + zone[3:]
+	}
+	return ts, nil
+}
+
+// originalTextURL identifies the FHIR originalText extension. It keeps HL7
+// timestamps FHIR can not represent as sent, e.g. times without seconds.
+const originalTextURL = "http://hl7.org/fhir/StructureDefinition/originalText"
+
+// hl7ToFHIRTimestampElement converts an HL7 TS value like hl7ToFHIRTimestamp.
+// If the FHIR value does not convert back to v, e.g. for a time of minute
+// precision or without UTC offset, v is kept in an originalText extension of
+// the returned element.
+func hl7ToFHIRTimestampElement(v string, location *time.Location) (string, *Element, error) {
+	ts, err := hl7ToFHIRTimestamp(v, location)
+	if err != nil || fhirToHL7Timestamp(ts) == v {
+		return ts, nil, err
+	}
+	return ts, &Element{Extension: []Extension{{URL: originalTextURL, ValueString: v}}}, nil
+}
+
+// fhirToHL7TimestampElement converts a FHIR date or dateTime value like
+// fhirToHL7Timestamp. The HL7 timestamp kept in an originalText extension of
+// element is returned instead if it still denotes the same value, read in
+// location.
+func fhirToHL7TimestampElement(v string, element *Element, location *time.Location) string {
+	if element != nil {
+		for _, ext := range element.Extension {
+			if ext.URL != originalTextURL {
+				continue
+			}
+			if ts, err := hl7ToFHIRTimestamp(ext.ValueString, location); err == nil && ts == v {
+				return ext.ValueString
+			}
+		}
+	}
+	return fhirToHL7Timestamp(v)
+}
+
+// hl7ToFHIRPeriod converts a pair of HL7 timestamps to a FHIR period. Either
+// of them may be empty; nil is returned if both are.
+func hl7ToFHIRPeriod(start, end string, location *time.Location) (*Period, error) {
+	if start == "" && end == "" {
+		return nil, nil
+	}
+	var period Period
+	var err error
+	if start != "" {
+		if period.Start, err = hl7ToFHIRTimestamp(start, location); err != nil {
+			return nil, fmt.Errorf("invalid period start: %w", err)
+		}
+	}
+	if end != "" {
+		if period.End, err = hl7ToFHIRTimestamp(end, location); err != nil {
+			return nil, fmt.Errorf("invalid period end: %w", err)
+		}
+	}
+	return &period, nil
+}
+
+// hl7ToFHIRDate converts an HL7 timestamp holding a date, such as PID-7, to a
+// FHIR date or dateTime value. Values already in the FHIR format, as sent by
+// some systems, are kept.
+func hl7ToFHIRDate(v string, location *time.Location) (string, error) {
+	if v == "" || (len(v) > 4 && v[4] == '-') {
+		return v, nil
+	}
+	return hl7ToFHIRTimestamp(v, location)
+}
@@ -0,0 +1,42 @@
+package hl7
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestHL7ToFHIRTimestamp(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		v        string
+		location *time.Location
+		want     string
+	}{
+		{"20200301", time.UTC, "2020-03-01"},
+		{"2020030110", time.UTC, "2020-03-01T10:00:00Z"},
+		{"20200301103045", newYork, "2020-03-01T10:30:45-05:00"},
+		{"20200701103045", newYork, "2020-07-01T10:30:45-04:00"}, // daylight saving time
+		{"20200301103045+0100", newYork, "2020-03-01T10:30:45+01:00"},
+		{"20200301103045.12", time.UTC, "2020-03-01T10:30:45.12Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.v, func(t *testing.T) {
+			is := is.New(t)
+			got, err := hl7ToFHIRTimestamp(tt.v, tt.location)
+			is.NoErr(err)
+			is.Equal(got, tt.want)
+		})
+	}
+
+	is := is.New(t)
+	for _, v := range []string{"2020133010", "20200301T10", "202003011030-05"} {
+		_, err := hl7ToFHIRTimestamp(v, time.UTC)
+		is.True(err != nil) // invalid timestamp
+	}
+}
@@ -0,0 +1,55 @@
+package hl7
+
+// The tokenizer scans HL7 v2 messages byte by byte and returns substrings of
+// the input, so splitting a message does not copy its contents.
+
+// nextSegment returns the first segment of message and the rest of the
+// message following the segment terminator. Segments may be terminated by
+// \r, \n or \r\n; the latter yields an empty segment in between.
+func nextSegment(message string) (segment, rest string) {
+	for i := 0; i < len(message); i++ {
+		if message[i] == '\r' || message[i] == '\n' {
+			return message[:i], message[i+1:]
+		}
+	}
+	return message, ""
+}
+
+// splitFields appends the fields of segment to dst and returns the extended
+// slice. Passing a reused dst[:0] avoids allocating for every segment.
+func splitFields(segment string, dst []string) []string {
+	start := 0
+	for i := 0; i < len(segment); i++ {
+		if segment[i] == '|' {
+			dst = append(dst, segment[start:i])
+			start = i + 1
+		}
+	}
+	return append(dst, segment[start:])
+}
+
+// nextToken returns the part of s before the first sep and the rest of s
+// after it. ok is false if s does not contain sep.
+func nextToken(s string, sep byte) (token, rest string, ok bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == sep {
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// component returns the n-th (1-based) part of s separated by sep, or an
+// empty string if s has fewer parts.
+func component(s string, sep byte, n int) string {
+	for i := 1; ; i++ {
+		token, rest, ok := nextToken(s, sep)
+		if i == n {
+			return token
+		}
+		if !ok {
+			return ""
+		}
+		s = rest
+	}
+}
@@ -0,0 +1,87 @@
+package hl7
+
+import "strings"
+
+// US Core extensions for race and ethnicity.
+const (
+	usCoreRaceURL      = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-race"
+	usCoreEthnicityURL = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-ethnicity"
+)
+
+// cdcRaceEthnicitySystem is the CDC Race & Ethnicity code system, named
+// CDCREC in HL7 v2.
+const cdcRaceEthnicitySystem = "urn:oid:2.16.840.1.113883.6.238"
+
+// cdcCodingSystems lists the HL7 v2 coding system names of CE values using
+// CDC race and ethnicity codes.
+var cdcCodingSystems = map[string]bool{
+	"CDCREC":  true,
+	"HL70005": true,
+	"HL70189": true,
+}
+
+// ombCategories lists the CDC codes of the OMB race and ethnicity
+// categories. Other CDC codes are detailed races or ethnicities.
+var ombCategories = map[string]bool{
+	"1002-5": true, // American Indian or Alaska Native
+	"2028-9": true, // Asian
+	"2054-5": true, // Black or African American
+	"2076-8": true, // Native Hawaiian or Other Pacific Islander
+	"2106-3": true, // White
+	"2135-2": true, // Hispanic or Latino
+	"2186-5": true, // Not Hispanic or Latino
+}
+
+// newUSCoreExtension builds a us-core-race or us-core-ethnicity extension
+// from the repetitions of PID-10 or PID-22. It returns false if there is
+// nothing to map.
+func newUSCoreExtension(url string, values []CodedElement) (Extension, bool) {
+	ext := Extension{URL: url}
+	var texts []string
+	for _, v := range values {
+		if v.Code != "" && (v.System == "" || cdcCodingSystems[v.System]) {
+			sub := "detailed"
+			if ombCategories[v.Code] {
+				sub = "ombCategory"
+			}
+			ext.Extension = append(ext.Extension, Extension{
+				URL:         sub,
+				ValueCoding: &Coding{System: cdcRaceEthnicitySystem, Code: v.Code, Display: v.Text},
+			})
+		}
+		switch {
+		case v.Text != "":
+			texts = append(texts, v.Text)
+		case v.Code != "":
+			texts = append(texts, v.Code)
+		}
+	}
+	if len(texts) == 0 {
+		return Extension{}, false
+	}
+	// text is required by US Core
+	ext.Extension = append(ext.Extension, Extension{URL: "text", ValueString: strings.Join(texts, ", ")})
+	return ext, true
+}
+
+// usCoreCodedElements returns the CDC codes of the us-core-race or
+// us-core-ethnicity extension of a patient as CE repetitions.
+func usCoreCodedElements(patient FHIRPatient, url string) []CodedElement {
+	var values []CodedElement
+	for _, ext := range patient.Extension {
+		if ext.URL != url {
+			continue
+		}
+		for _, sub := range ext.Extension {
+			if (sub.URL != "ombCategory" && sub.URL != "detailed") || sub.ValueCoding == nil {
+				continue
+			}
+			values = append(values, CodedElement{
+				Code:   sub.ValueCoding.Code,
+				Text:   sub.ValueCoding.Display,
+				System: "CDCREC",
+			})
+		}
+	}
+	return values
+}
@@ -0,0 +1,87 @@
+package hl7
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+)
+
+// metadataValidation is the metadata key holding the ValidationSummary of a
+// record processed in validateOnly mode.
+const metadataValidation = "hl7.validation"
+
+// ValidationSummary describes the output a record would have been converted
+// to in validateOnly mode.
+type ValidationSummary struct {
+	OutputType string `json:"outputType"`
+	// Bytes is the size of the output.
+	Bytes int `json:"bytes"`
+	// Resources counts the FHIR resources of the output by resource type.
+	Resources map[string]int `json:"resources,omitempty"`
+	// Segments is the number of segments of HL7 v2 output.
+	Segments int `json:"segments,omitempty"`
+}
+
+// validationRecord restores the original payload of a converted record and
+// describes the discarded output in the record metadata.
+func (p *Processor) validationRecord(record opencdc.Record, original opencdc.Data) sdk.ProcessedRecord {
+	output := record.Payload.After.Bytes()
+	summary := ValidationSummary{
+		OutputType: p.config.OutputType,
+		Bytes:      len(output),
+	}
+	switch p.config.OutputType {
+	case "fhir":
+		summary.Resources = countResources(output)
+	case "hl7":
+		if data, ok := record.Payload.After.(opencdc.StructuredData); ok {
+			message, _ := data["hl7"].(string)
+			summary.Bytes = len(message)
+			for rest := unwrapMLLP(message); rest != ""; {
+				var segment string
+				segment, rest = nextSegment(rest)
+				if segment != "" {
+					summary.Segments++
+				}
+			}
+		}
+	}
+
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return sdk.ErrorRecord{Error: fmt.Errorf("failed to marshal validation summary: %w", err)}
+	}
+	if record.Metadata == nil {
+		record.Metadata = opencdc.Metadata{}
+	}
+	record.Metadata[metadataValidation] = string(encoded)
+	record.Payload.After = original
+	return sdk.SingleRecord(record)
+}
+
+// countResources counts the resources of FHIR JSON by resource type: the
+// entries of a Bundle, or the single resource otherwise.
+func countResources(fhirJSON []byte) map[string]int {
+	var resource struct {
+		ResourceType string `json:"resourceType"`
+		Entry        []struct {
+			Resource struct {
+				ResourceType string `json:"resourceType"`
+			} `json:"resource"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(fhirJSON, &resource); err != nil {
+		return nil
+	}
+	if resource.ResourceType != "Bundle" {
+		// Patients are emitted without resourceType unless includeResourceType is set
+		return map[string]int{"Patient": 1}
+	}
+	counts := make(map[string]int)
+	for _, entry := range resource.Entry {
+		counts[entry.Resource.ResourceType]++
+	}
+	return counts
+}
@@ -0,0 +1,65 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conduitio/conduit-commons/opencdc"
+	sdk "github.com/conduitio/conduit-processor-sdk"
+	"github.com/matryer/is"
+)
+
+func TestProcessor_Process_ValidateOnly(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	p := NewProcessor()
+	err := p.Configure(ctx, map[string]string{
+		"inputType":      "hl7",
+		"outputType":     "fhir",
+		"validateOnly":   "true",
+		"dg1AsCondition": "true",
+	})
+	is.NoErr(err)
+
+	valid := "MSH|^~\\&|SENDING_APP|SENDING_FACILITY|RECEIVING_APP|RECEIVING_FACILITY|20230815||ADT^A01|MSG00001|P|2.5\r" +
+		"PID|1||123^^^^MR||Doe^John||19800101|M\r" +
+		"DG1|1||E11.9^Type 2 diabetes mellitus^I10"
+	invalid := "PID|1||123^^^^MR||Doe^John||19800101|M"
+	result := p.Process(ctx, []opencdc.Record{
+		{Payload: opencdc.Change{After: opencdc.RawData(valid)}},
+		{Payload: opencdc.Change{After: opencdc.RawData(invalid)}},
+	})
+	is.Equal(len(result), 2)
+
+	processed, ok := result[0].(sdk.SingleRecord)
+	is.True(ok) // valid records are returned
+	is.Equal(processed.Payload.After, opencdc.RawData(valid))
+
+	var summary ValidationSummary
+	is.NoErr(json.Unmarshal([]byte(processed.Metadata[metadataValidation]), &summary))
+	is.Equal(summary.OutputType, "fhir")
+	is.Equal(summary.Resources, map[string]int{"Patient": 1, "Condition": 1})
+	is.True(summary.Bytes > 0)
+
+	_, ok = result[1].(sdk.ErrorRecord)
+	is.True(ok) // invalid records fail
+
+	err = p.Configure(ctx, map[string]string{
+		"inputType":    "fhir",
+		"outputType":   "hl7",
+		"validateOnly": "true",
+	})
+	is.NoErr(err)
+	fhirInput := `{"resourceType":"Patient","id":"123","name":[{"family":["Doe"],"given":["John"]}],"birthDate":"1980-01-01","gender":"male"}`
+	result = p.Process(ctx, []opencdc.Record{{
+		Payload: opencdc.Change{After: opencdc.RawData(fhirInput)},
+	}})
+	processed, ok = result[0].(sdk.SingleRecord)
+	is.True(ok)
+	is.Equal(processed.Payload.After, opencdc.RawData(fhirInput))
+	summary = ValidationSummary{}
+	is.NoErr(json.Unmarshal([]byte(processed.Metadata[metadataValidation]), &summary))
+	is.Equal(summary.OutputType, "hl7")
+	is.Equal(summary.Segments, 2)
+}
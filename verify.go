@@ -0,0 +1,93 @@
+package hl7
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// verifyHL7 parses a generated HL7 v2 message and checks that the patient
+// identifiers, primary name and first address read back unchanged.
+func (p *Processor) verifyHL7(message string, patient FHIRPatient) error {
+	msg, err := parseHL7Message(message, parseOptions{estimateAge: true, clock: p.clock})
+	if err != nil {
+		return fmt.Errorf("generated HL7 message does not parse: %w", err)
+	}
+
+	account, identifiers := splitAccountNumber(patient.Identifier)
+	wantIDs := []string{patient.ID}
+	if len(identifiers) > 0 {
+		wantIDs = wantIDs[:0]
+		for _, id := range p.prioritizeIdentifiers(identifiers) {
+			wantIDs = append(wantIDs, id.Value)
+		}
+	}
+	gotIDs := make([]string, len(msg.PID.Identifiers))
+	for i, id := range msg.PID.Identifiers {
+		gotIDs[i] = id.ID
+	}
+	var wantAccount, gotAccount string
+	if account != nil {
+		wantAccount = account.Value
+	}
+	if msg.PID.AccountNumber != nil {
+		gotAccount = msg.PID.AccountNumber.ID
+	}
+
+	var family, given string
+	if name, ok := primaryName(patient.Name, p.now()); ok {
+		family, given = first(name.Family), first(name.Given)
+	}
+
+	var addr Address
+	if len(patient.Address) > 0 {
+		addr = patient.Address[0]
+	}
+
+	checks := []struct {
+		field     string
+		want, got string
+	}{
+		{"PID-3", strings.Join(wantIDs, "~"), strings.Join(gotIDs, "~")},
+		{"PID-18", wantAccount, gotAccount},
+		{"PID-5", family, msg.PID.LastName},
+		{"PID-5", given, msg.PID.FirstName},
+		{"PID-11", first(addr.Line), msg.PID.Address.Street},
+		{"PID-11", addr.City, msg.PID.Address.City},
+		{"PID-11", addr.State, msg.PID.Address.State},
+		{"PID-11", addr.PostalCode, msg.PID.Address.PostalCode},
+		{"PID-11", normalizeCountry(addr.Country, p.config.CountryFormat), msg.PID.Address.Country},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			return &FieldError{
+				Segment: "PID",
+				Field:   c.field,
+				Message: fmt.Sprintf("generated %s does not round-trip: got %q, want %q", c.field, c.got, c.want),
+			}
+		}
+	}
+	return nil
+}
+
+// primaryName returns the name emitted as the first PID-5 repetition: the
+// first current name at now, or the first historical name if there is none.
+func primaryName(names []HumanName, now time.Time) (HumanName, bool) {
+	for _, n := range names {
+		if !isHistoricalName(n, now) {
+			return n, true
+		}
+	}
+	if len(names) > 0 {
+		return names[0], true
+	}
+	return HumanName{}, false
+}
+
+// first returns the first value, or an empty string if there is none.
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,75 @@
+package hl7
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestConvertFHIRToHL7_VerifyOutput(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":    "fhir",
+		"outputType":   "hl7",
+		"verifyOutput": "true",
+	})
+	is.NoErr(err)
+
+	patient := FHIRPatient{
+		ID: "123",
+		Name: []HumanName{{
+			Family: []string{`O'Brien & Sons|Ltd`},
+			Given:  []string{`Mary^Ann~\Jo`},
+		}},
+		Address: []Address{{Line: []string{"1 Main St & 2nd Ave"}, City: "Springfield"}},
+	}
+
+	hl7Message, err := p.convertFHIRToHL7(patient)
+	is.NoErr(err) // the special characters are escaped and read back
+
+	pidFields := splitHL7Field(splitHL7Message(hl7Message)[1])
+	is.Equal(pidFields[5], `O'Brien \T\ Sons\F\Ltd^Mary\S\Ann\R\\E\Jo`)
+
+	msg, err := parseHL7Message(hl7Message, parseOptions{})
+	is.NoErr(err)
+	is.Equal(msg.PID.LastName, `O'Brien & Sons|Ltd`)
+	is.Equal(msg.PID.FirstName, `Mary^Ann~\Jo`)
+	is.Equal(msg.PID.Address.Street, "1 Main St & 2nd Ave")
+}
+
+func TestConvertFHIRToHL7_VerifyOutputCountryFormat(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+	err := p.Configure(context.Background(), map[string]string{
+		"inputType":     "fhir",
+		"outputType":    "hl7",
+		"verifyOutput":  "true",
+		"countryFormat": "alpha3",
+	})
+	is.NoErr(err)
+
+	hl7Message, err := p.convertFHIRToHL7(FHIRPatient{
+		ID:      "123",
+		Address: []Address{{City: "Springfield", Country: "US"}},
+	})
+	is.NoErr(err) // the normalized country is expected
+	is.Equal(splitHL7Field(splitHL7Message(hl7Message)[1])[11], "^Springfield^^^USA")
+}
+
+func TestVerifyHL7_Mismatch(t *testing.T) {
+	is := is.New(t)
+	p := NewProcessor().(*Processor)
+
+	// a message where the family name was cut off at an unescaped &
+	patient := FHIRPatient{ID: "123", Name: []HumanName{{Family: []string{"Smith & Co"}}}}
+	message := "MSH|^~\\&|FHIR_CONVERTER|FACILITY|HL7_PARSER|FACILITY|20230815120000||ADT^A01|123|P|2.5|\rPID|1||123||Smith "
+
+	err := p.verifyHL7(message, patient)
+	is.True(err != nil)
+	var fieldErr *FieldError
+	is.True(errors.As(err, &fieldErr))
+	is.Equal(fieldErr.Field, "PID-5")
+}